@@ -0,0 +1,388 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cruffinoni/xml-generator/file"
+	"github.com/cruffinoni/xml-generator/generator"
+	"github.com/cruffinoni/xml-generator/generator/config"
+	"github.com/cruffinoni/xml-generator/generator/files"
+	"github.com/cruffinoni/xml-generator/generator/jsonschema"
+	"github.com/cruffinoni/xml-generator/generator/paths"
+	"github.com/cruffinoni/xml-generator/generator/xsd"
+	"github.com/cruffinoni/xml-generator/xml"
+	"github.com/cruffinoni/xml-generator/xml/dialect"
+)
+
+var errMissingFile = errors.New("missing input file")
+
+// parseFlags parses args with fs and returns the positional arguments, which
+// are the XML files (or glob patterns) to work on.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return nil, errMissingFile
+	}
+	return fs.Args(), nil
+}
+
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: xmlgen %s [flags] <file.xml|glob>...\n\n%s\n\nFlags:\n", name, synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// dialectFlag registers the flag giving the dialect of the files.
+func dialectFlag(fs *flag.FlagSet) *string {
+	return fs.String("dialect", "", "JSON file describing the list, map and transversal tags of the files (default: li/list items, keys/values maps)")
+}
+
+// loadDialect loads the dialect file given with dialectFlag. An empty name
+// means the default dialect.
+func loadDialect(fileName string) (*dialect.Dialect, error) {
+	if fileName == "" {
+		return dialect.Default, nil
+	}
+	return dialect.Load(fileName)
+}
+
+// loadConfig loads the naming configuration. An empty name means no
+// configuration.
+func loadConfig(fileName string) (*config.Config, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+	return config.Load(fileName)
+}
+
+// namingPolicy returns the deduplication naming policy called name. An empty
+// name disables the deduplication.
+func namingPolicy(name string) (generator.NamingPolicy, error) {
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := generator.NamingPolicies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown deduplication policy %q, expected first, shortest or common", name)
+	}
+	return p, nil
+}
+
+// openRoots opens the files matching the patterns and returns their root
+// elements, in the order of the patterns.
+func openRoots(patterns []string, d *dialect.Dialect) ([]*xml.Element, error) {
+	openings, err := file.OpenGlobWithDialect(d, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	return rootsOf(openings), nil
+}
+
+func rootsOf(openings []*file.Opening) []*xml.Element {
+	roots := make([]*xml.Element, 0, len(openings))
+	for _, o := range openings {
+		roots = append(roots, o.XML.Root)
+	}
+	return roots
+}
+
+// roundTripSamples reads the files of openings as the samples of the
+// round-trip test.
+func roundTripSamples(openings []*file.Opening) ([]files.Sample, error) {
+	samples := make([]files.Sample, 0, len(openings))
+	for _, o := range openings {
+		content, err := os.ReadFile(o.FileName())
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, files.Sample{
+			Name:    filepath.Base(o.FileName()),
+			Root:    o.XML.Root.GetName(),
+			Content: content,
+		})
+	}
+	return samples, nil
+}
+
+// printProblems reports on stderr the problems met by a lenient inference.
+func printProblems(problems generator.InferenceErrors) {
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", p)
+	}
+}
+
+// printUnmatchedOverrides reports on stderr the overrides of the
+// configuration which matched nothing.
+func printUnmatchedOverrides(g *generator.Generator) {
+	for _, o := range g.UnmatchedOverrides() {
+		fmt.Fprintf(os.Stderr, "warning: override %q matched nothing\n", o.Path)
+	}
+}
+
+// printChanges prints the changes made by a merge on stdout.
+func printChanges(changes []files.Change) {
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stdout, "merge: nothing changed")
+		return
+	}
+	for _, c := range changes {
+		fmt.Fprintf(os.Stdout, "merge: %v\n", c)
+	}
+}
+
+// optionalStyle returns the optional style called name.
+func optionalStyle(name string) (files.OptionalStyle, error) {
+	s, ok := files.OptionalStyles[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown optional style %q, expected none, pointer or accessor", name)
+	}
+	return s, nil
+}
+
+// writerBackend returns the backend called name.
+func writerBackend(name string) (files.Backend, error) {
+	b, ok := files.Backends[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown backend %q, expected runtime, plain or proto", name)
+	}
+	return b, nil
+}
+
+// writerLayout returns the layout called name.
+func writerLayout(name string) (files.Layout, error) {
+	l, ok := files.Layouts[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown layout %q, expected struct, single or root", name)
+	}
+	return l, nil
+}
+
+// aliasFlag are the aliases of the runtime packages given by -alias, by
+// path in the runtime module.
+type aliasFlag map[string]string
+
+func (a aliasFlag) String() string {
+	pairs := make([]string, 0, len(a))
+	for pkg, alias := range a {
+		pairs = append(pairs, pkg+"="+alias)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (a aliasFlag) Set(v string) error {
+	pkg, alias, ok := strings.Cut(v, "=")
+	if !ok || pkg == "" || !token.IsIdentifier(alias) {
+		return fmt.Errorf("%q isn't a package path followed by = and an alias", v)
+	}
+	a[pkg] = alias
+	return nil
+}
+
+// writeReport writes the inference report of g as JSON to fileName.
+func writeReport(fileName string, g *generator.Generator) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err = g.Report().WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeSchema writes the XML Schema of the structures inferred by g, from
+// the root s, to fileName.
+func writeSchema(fileName string, s *generator.StructInfo, g *generator.Generator, d *dialect.Dialect) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err = xsd.Write(f, s, g.RegisteredMembers, xsd.WithDialect(d)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeJSONSchema writes the JSON Schema of the structures inferred by g,
+// from the root s, to fileName.
+func writeJSONSchema(fileName string, s *generator.StructInfo, g *generator.Generator) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err = jsonschema.Write(f, s, g.RegisteredMembers); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readSeed reads the XML Schema fileName as the seed of the generation. An
+// empty name means no seed.
+func readSeed(fileName string, opts ...xsd.Option) (generator.Option, error) {
+	if fileName == "" {
+		return generator.WithSeed(nil, nil), nil
+	}
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	root, registered, err := xsd.Read(string(content), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return generator.WithSeed(root, registered), nil
+}
+
+func runGenerate(args []string) error {
+	var (
+		fs           = newFlagSet("generate", "Infer Go types from one or several sample XML files, or from an XSD with -from-xsd, and write them, one file per struct by default.")
+		output       = fs.String("o", "generated", "output directory of the generated files")
+		pkg          = fs.String("pkg", "", "package name of the generated files (default \"generated\")")
+		withMVFix    = fs.Bool("mvfix", true, "reconcile the different versions of a struct found in the samples")
+		deleteFolder = fs.Bool("delete", true, "delete the output directory before writing into it")
+		roundTrip    = fs.Bool("roundtrip", false, "write a test checking that the samples are saved back as they are, with the samples in testdata")
+		merge        = fs.Bool("merge", false, "merge into the files of the output directory: add the new fields and declarations, update the changed types, keep the rest (implies -delete=false)")
+		lenient      = fs.Bool("lenient", false, "type as *xml.Element the members that can't be inferred instead of failing")
+		dialectFile  = dialectFlag(fs)
+		configFile   = fs.String("config", "", "YAML or JSON file overriding the names and types of the generated structs, fields and files")
+		dedup        = fs.String("dedup", "", "merge the structs with the same shape and name them with the first, shortest or common name (default: disabled)")
+		classes      = fs.Bool("classes", false, "generate one struct per Class attribute value of the list items, behind an interface")
+		unions       = fs.Bool("unions", false, "type the fields whose shape varies between the samples as sum types instead of *xml.Element")
+		attrs        = fs.Bool("attributes", false, "type the attributes of the structs as fields tagged \",attr\" instead of leaving them in the Attr map")
+		enums        = fs.Int("enums", 0, "type the text fields taking at most this number of distinct values as enumerations (default: disabled)")
+		backendName  = fs.String("backend", "runtime", "library reading and writing the generated types: runtime (this project), plain (encoding/xml, no dependency) or proto (a Protocol Buffers file instead of Go code)")
+		layoutName   = fs.String("layout", "struct", "layout of the generated types: struct (one file per type), single (one file) or root (one package per child of the root elements, in the module of the output directory)")
+		optional     = fs.String("optional", "none", "mark the fields missing from some elements: none, pointer (primitive fields become pointers) or accessor (Has<Field>() methods)")
+		report       = fs.String("report", "", "JSON file in which the inferred structs, their XML paths, the fixed type mismatches and the untyped members are reported")
+		schema       = fs.String("xsd", "", "XSD file in which the schema of the samples, as inferred, is written")
+		jsonSchema   = fs.String("jsonschema", "", "JSON file in which the JSON Schema of the samples converted to JSON (see inspect -json), as inferred, is written")
+		fromSchema   = fs.String("from-xsd", "", "XSD file whose declarations are the starting point of the inference, refined by the samples if any")
+		runtime      = fs.String("runtime", "", "module path of the runtime imported by the generated code, e.g. a fork (default \""+strings.TrimSuffix(paths.CodePackage, "/")+"\")")
+		aliases      = aliasFlag{}
+	)
+	fs.Var(aliases, "alias", "import a runtime package under an alias, given as path=alias with the path in the runtime module, e.g. xml/types=xmltypes (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	patterns := fs.Args()
+	if len(patterns) == 0 && *fromSchema == "" {
+		fs.Usage()
+		return errMissingFile
+	}
+	d, err := loadDialect(*dialectFile)
+	if err != nil {
+		return err
+	}
+	seed, err := readSeed(*fromSchema, xsd.WithDialect(d), xsd.WithAttributes(*attrs), xsd.WithUnions(*unions))
+	if err != nil {
+		return err
+	}
+	if *roundTrip && d != dialect.Default {
+		return errors.New("-roundtrip can't be used with -dialect: the test reads the samples with the default dialect")
+	}
+	c, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	policy, err := namingPolicy(*dedup)
+	if err != nil {
+		return err
+	}
+	style, err := optionalStyle(*optional)
+	if err != nil {
+		return err
+	}
+	backend, err := writerBackend(*backendName)
+	if err != nil {
+		return err
+	}
+	layout, err := writerLayout(*layoutName)
+	if err != nil {
+		return err
+	}
+	openings, err := file.OpenGlobWithDialect(d, patterns...)
+	if err != nil {
+		return err
+	}
+	roots := rootsOf(openings)
+	g := generator.New(
+		generator.WithMemberVersioningFix(*withMVFix),
+		generator.WithLenientInference(*lenient),
+		generator.WithDialect(d),
+		generator.WithConfig(c),
+		generator.WithDeduplication(policy),
+		generator.WithClassPolymorphism(*classes),
+		generator.WithEnums(*enums),
+		generator.WithUnions(*unions),
+		generator.WithAttributes(*attrs),
+		generator.WithReport(*report != ""),
+		seed,
+	)
+	s, err := g.GenerateGoFilesFromCorpus(roots)
+	if err != nil && !*lenient {
+		return err
+	}
+	printProblems(g.Problems())
+	printUnmatchedOverrides(g)
+	if *report != "" {
+		if err = writeReport(*report, g); err != nil {
+			return err
+		}
+	}
+	if *schema != "" {
+		if err = writeSchema(*schema, s, g, d); err != nil {
+			return err
+		}
+	}
+	if *jsonSchema != "" {
+		if err = writeJSONSchema(*jsonSchema, s, g); err != nil {
+			return err
+		}
+	}
+	writerOpts := []files.WriterOption{
+		files.WithOptionalStyle(style),
+		files.WithMerge(*merge),
+		files.WithBackend(backend),
+		files.WithDialect(d),
+		files.WithLayout(layout),
+	}
+	if *runtime != "" {
+		writerOpts = append(writerOpts, files.WithRuntimeModule(*runtime))
+	}
+	for pkg, alias := range aliases {
+		writerOpts = append(writerOpts, files.WithPackageAlias(pkg, alias))
+	}
+	if *roundTrip {
+		samples, err := roundTripSamples(openings)
+		if err != nil {
+			return err
+		}
+		writerOpts = append(writerOpts, files.WithRoundTripTest(samples...))
+	}
+	gw := files.NewGoWriter(g.RegisteredMembers, *deleteFolder, *pkg, writerOpts...)
+	if err = gw.WriteGoFile(*output, s); err != nil {
+		return err
+	}
+	if *merge {
+		printChanges(gw.Changes())
+	}
+	fmt.Fprintf(os.Stdout, "%d structs inferred from %d files written to %s\n", len(g.RegisteredMembers), len(roots), *output)
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/cruffinoni/xml-generator/file"
+)
+
+func runInspect(args []string) error {
+	var (
+		fs          = newFlagSet("inspect", "Print the parsed XML tree of each file.")
+		paths       = fs.Bool("paths", false, "print every XML path instead of the indented tree")
+		asJSON      = fs.Bool("json", false, "print the tree converted to JSON instead of the indented tree, see generate -jsonschema")
+		dialectFile = dialectFlag(fs)
+	)
+	patterns, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	d, err := loadDialect(*dialectFile)
+	if err != nil {
+		return err
+	}
+	openings, err := file.OpenGlobWithDialect(d, patterns...)
+	if err != nil {
+		return err
+	}
+	for _, o := range openings {
+		if len(openings) > 1 {
+			fmt.Fprintf(os.Stdout, "# %s\n", o.FileName())
+		}
+		if *asJSON {
+			b, err := json.MarshalIndent(o.XML, "", "\t")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stdout, string(b))
+			continue
+		}
+		if *paths {
+			fmt.Fprint(os.Stdout, o.XML.Root.DisplayAllXMLPaths())
+			continue
+		}
+		fmt.Fprintln(os.Stdout, o.XML.Pretty())
+	}
+	return nil
+}
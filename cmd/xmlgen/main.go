@@ -0,0 +1,78 @@
+// Command xmlgen turns sample XML files into Go types backed by the
+// xml-generator runtime.
+//
+// Usage:
+//
+//	xmlgen generate [flags] <file.xml>
+//	xmlgen inspect [flags] <file.xml>
+//	xmlgen validate [flags] <file.xml>
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []*command{
+	{
+		name:  "generate",
+		usage: "infer Go types from a sample XML file and write them to disk",
+		run:   runGenerate,
+	},
+	{
+		name:  "inspect",
+		usage: "print the parsed XML tree of a file",
+		run:   runInspect,
+	},
+	{
+		name:  "validate",
+		usage: "check that a file can be parsed and its types inferred",
+		run:   runValidate,
+	},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: xmlgen <command> [flags] <file.xml>\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'xmlgen <command> -h' for the flags of a command.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		usage()
+		return
+	}
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		err := c.run(os.Args[2:])
+		// The flags of the command were printed on request
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "xmlgen %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "xmlgen: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
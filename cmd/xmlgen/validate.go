@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cruffinoni/xml-generator/generator"
+)
+
+func runValidate(args []string) error {
+	var (
+		fs          = newFlagSet("validate", "Check that the files can be parsed and their types inferred, without writing anything.")
+		withMVFix   = fs.Bool("mvfix", true, "reconcile the different versions of a struct found in the samples")
+		lenient     = fs.Bool("lenient", false, "report every member that can't be inferred instead of stopping at the first one")
+		dialectFile = dialectFlag(fs)
+		configFile  = fs.String("config", "", "YAML or JSON override configuration to check against the files")
+	)
+	patterns, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	d, err := loadDialect(*dialectFile)
+	if err != nil {
+		return err
+	}
+	c, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	roots, err := openRoots(patterns, d)
+	if err != nil {
+		return err
+	}
+	g := generator.New(
+		generator.WithMemberVersioningFix(*withMVFix),
+		generator.WithLenientInference(*lenient),
+		generator.WithDialect(d),
+		generator.WithConfig(c),
+	)
+	_, err = g.GenerateGoFilesFromCorpus(roots)
+	printUnmatchedOverrides(g)
+	if err != nil {
+		printProblems(g.Problems())
+		return fmt.Errorf("type inference failed: %w", err)
+	}
+	fmt.Fprintf(os.Stdout, "ok: %d structs inferred from %d files\n", len(g.RegisteredMembers), len(roots))
+	return nil
+}
@@ -3,26 +3,65 @@ package file
 import (
 	"bytes"
 	_xml "encoding/xml"
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"golang.org/x/net/html/charset"
 
 	"github.com/cruffinoni/rimworld-editor/xml"
+	"github.com/cruffinoni/rimworld-editor/xml/dialect"
 )
 
 type Opening struct {
 	fileName string
+	dialect  *dialect.Dialect
 	XML      *xml.Tree
 }
 
 func Open(fileName string) (*Opening, error) {
-	fileOpening := &Opening{fileName: fileName}
+	return OpenWithDialect(fileName, nil)
+}
+
+// OpenWithDialect works like Open for the files whose collections are not
+// encoded with dialect.Default.
+func OpenWithDialect(fileName string, d *dialect.Dialect) (*Opening, error) {
+	fileOpening := &Opening{fileName: fileName, dialect: d}
 	if err := fileOpening.ReOpen(); err != nil {
 		return nil, err
 	}
 	return fileOpening, nil
 }
 
+// OpenGlob opens every file matching one of the given patterns. The syntax of
+// the patterns is the one of filepath.Match, so a plain file name is also
+// accepted. A pattern matching no file is an error.
+func OpenGlob(patterns ...string) ([]*Opening, error) {
+	return OpenGlobWithDialect(nil, patterns...)
+}
+
+// OpenGlobWithDialect works like OpenGlob and parses the files with d.
+func OpenGlobWithDialect(d *dialect.Dialect, patterns ...string) ([]*Opening, error) {
+	openings := make([]*Opening, 0, len(patterns))
+	for _, p := range patterns {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("file.OpenGlob: no file matches %s", p)
+		}
+		for _, m := range matches {
+			o, err := OpenWithDialect(m, d)
+			if err != nil {
+				return nil, fmt.Errorf("file.OpenGlob: %s: %w", m, err)
+			}
+			openings = append(openings, o)
+		}
+	}
+	return openings, nil
+}
+
 func (o *Opening) ReOpen() error {
 	content, err := os.ReadFile(o.fileName)
 	if err != nil {
@@ -31,18 +70,30 @@ func (o *Opening) ReOpen() error {
 	reader := bytes.NewReader(content)
 	decoder := _xml.NewDecoder(reader)
 	decoder.CharsetReader = charset.NewReaderLabel
-	if err := decoder.Decode(&o.XML); err != nil {
+	o.XML = &xml.Tree{Dialect: o.dialect}
+	if err := decoder.Decode(o.XML); err != nil {
 		return err
 	}
 	return nil
 }
 
+func (o *Opening) FileName() string {
+	return o.fileName
+}
+
 func ReadFromBuffer(buffer string) (*Opening, error) {
-	fileOpening := &Opening{fileName: "localbuffer"}
+	return ReadFromBufferWithDialect(buffer, nil)
+}
+
+// ReadFromBufferWithDialect works like ReadFromBuffer and parses the buffer
+// with d.
+func ReadFromBufferWithDialect(buffer string, d *dialect.Dialect) (*Opening, error) {
+	fileOpening := &Opening{fileName: "localbuffer", dialect: d}
 	reader := bytes.NewReader([]byte(buffer))
 	decoder := _xml.NewDecoder(reader)
 	decoder.CharsetReader = charset.NewReaderLabel
-	if err := decoder.Decode(&fileOpening.XML); err != nil {
+	fileOpening.XML = &xml.Tree{Dialect: d}
+	if err := decoder.Decode(fileOpening.XML); err != nil {
 		return nil, err
 	}
 	return fileOpening, nil
@@ -9,27 +9,32 @@ import (
 type FixedArray struct {
 	Size        int
 	PrimaryType any
+	// ItemTag is the tag of the items, see CustomType.ItemTag.
+	ItemTag string
 }
 
-func createSubtype(e *xml.Element, flag uint, t any) any {
+func (g *Generator) createSubtype(e *xml.Element, flag uint, t any) (any, error) {
 	switch t {
 	case Complex:
-		return e
+		return e, nil
 	case reflect.Invalid:
 		// With an invalid type and no data, we can assume that the slice is empty
 		if e.Data == nil {
-			return createEmptyType()
+			return createEmptyType(), nil
 		} else {
-			return e
+			return e, nil
 		}
 	case reflect.Slice:
-		return createCustomSlice(e.Child, flag)
+		return g.createCustomSlice(e.Child, flag)
 	case reflect.Array:
-		return createFixedArray(e.Child, flag, nil)
+		return g.createFixedArray(e.Child, flag, nil)
 	case reflect.Struct:
-		return createStructure(e, flag|forceFullCheck)
+		if p, ok, err := g.createPolymorphic(e, flag); ok {
+			return p, err
+		}
+		return g.createStructure(e, flag|forceFullCheck)
 	}
-	return t
+	return t, nil
 }
 
 type offset struct {
@@ -37,10 +42,19 @@ type offset struct {
 	size int
 }
 
-func createFixedArray(e *xml.Element, flag uint, o *offset) any {
+func (g *Generator) createFixedArray(e *xml.Element, flag uint, o *offset) (any, error) {
+	kind, err := g.getTypeFromArrayOrSlice(e)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := g.createSubtype(e, flag, kind)
+	if err != nil {
+		return nil, err
+	}
 	f := &FixedArray{
-		PrimaryType: createSubtype(e, flag, getTypeFromArrayOrSlice(e)),
+		PrimaryType: pt,
 		Size:        1, // Minimum size is 1
+		ItemTag:     g.itemTag(e),
 	}
 	if o == nil {
 		o = &offset{el: e.Child}
@@ -52,7 +66,7 @@ func createFixedArray(e *xml.Element, flag uint, o *offset) any {
 		f.Size++
 		k = k.Next
 	}
-	return f
+	return f, nil
 }
 
 func (a *FixedArray) ValidateField(_ string) {
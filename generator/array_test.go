@@ -137,8 +137,9 @@ func Test_createFixedArray(t *testing.T) {
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			root := resetVarsAndReadBuffer(t, tt.args)
-			got := createFixedArray(root.Child, tt.args.flag, tt.args.o)
+			g, root := newGeneratorAndReadBuffer(t, tt.args)
+			got, err := g.createFixedArray(root.Child, tt.args.flag, tt.args.o)
+			require.NoError(t, err)
 			require.IsType(t, got, tt.want)
 			gotCasted := got.(*FixedArray)
 			wanted := got.(*FixedArray)
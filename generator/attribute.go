@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+// Attribute is an XML attribute of the elements of a structure, typed from
+// its values. See WithAttributes.
+type Attribute struct {
+	Name string
+	// T is reflect.Bool, reflect.Int64, reflect.Float64 or reflect.String.
+	T reflect.Kind
+	// Occurrences is the number of elements of the structure having the
+	// attribute. It's set once the generation is done.
+	Occurrences int
+}
+
+// WithAttributes types the attributes of the elements of the structures as
+// fields of the structures instead of leaving them in their attributes map.
+// The attributes which aren't on every element are pointers. It's disabled
+// by default.
+func WithAttributes(enabled bool) Option {
+	return func(g *Generator) {
+		g.typedAttributes = enabled
+	}
+}
+
+// IsOptionalAttribute reports whether a, an attribute of s, is missing from
+// some of the elements of s.
+func (s *StructInfo) IsOptionalAttribute(a *Attribute) bool {
+	return a.Occurrences < s.Instances
+}
+
+// attribute returns the attribute name of s, or nil if there is none.
+func (s *StructInfo) attribute(name string) *Attribute {
+	for _, a := range s.Attributes {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// attributeKind returns the kind of an attribute holding value.
+func attributeKind(value string) reflect.Kind {
+	switch k := xml.CreateDataType(value).Kind(); k {
+	case reflect.Bool, reflect.Int64, reflect.Float64:
+		return k
+	}
+	return reflect.String
+}
+
+// widenAttributeKind returns the kind holding the values of both a and b.
+func widenAttributeKind(a, b reflect.Kind) reflect.Kind {
+	switch {
+	case a == b:
+		return a
+	case a == reflect.Int64 && b == reflect.Float64, a == reflect.Float64 && b == reflect.Int64:
+		return reflect.Float64
+	}
+	return reflect.String
+}
+
+// addAttributes adds the attributes of e, an element s is inferred from, to
+// s.
+func (g *Generator) addAttributes(s *StructInfo, e *xml.Element) {
+	if !g.typedAttributes {
+		return
+	}
+	for name, value := range e.Attr {
+		// The class of the polymorphic items is given by their structure
+		if g.classes && name == ClassAttribute {
+			continue
+		}
+		if g.attrOccurrences[s] == nil {
+			g.attrOccurrences[s] = make(map[string]int)
+		}
+		g.attrOccurrences[s][name]++
+		k := attributeKind(value)
+		if a := s.attribute(name); a != nil {
+			a.T = widenAttributeKind(a.T, k)
+		} else {
+			s.Attributes = append(s.Attributes, &Attribute{Name: name, T: k})
+		}
+	}
+	sortAttributes(s)
+}
+
+// sortAttributes sorts the attributes of s by name. The attributes of an
+// element are unordered, so is the order in which they are found.
+func sortAttributes(s *StructInfo) {
+	sort.Slice(s.Attributes, func(i, j int) bool {
+		return s.Attributes[i].Name < s.Attributes[j].Name
+	})
+}
+
+// mergeAttributes gives the attributes of a and b to both, like FixMembers
+// does with their members. The attributes present in both are widened to the
+// same kind.
+func mergeAttributes(a, b *StructInfo) {
+	for _, ab := range b.Attributes {
+		if aa := a.attribute(ab.Name); aa != nil {
+			aa.T = widenAttributeKind(aa.T, ab.T)
+		} else {
+			a.Attributes = append(a.Attributes, ab)
+		}
+	}
+	sortAttributes(a)
+	b.Attributes = append(b.Attributes[:0:0], a.Attributes...)
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+func TestGenerator_attributes(t *testing.T) {
+	documents := []string{`
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawns>
+		<li id="1" weight="2" Class="Pawn">
+			<label>a</label>
+		</li>
+		<li id="2" weight="2.5" dead="True">
+			<label>b</label>
+		</li>
+	</pawns>
+	<world seed="12">
+		<size>5</size>
+	</world>
+</savegame>
+`, `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<world seed="random">
+		<size>6</size>
+	</world>
+</savegame>
+`}
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		roots = append(roots, readBuffer(t, d))
+	}
+	attributesOf := func(s *StructInfo) map[string]*Attribute {
+		attributes := make(map[string]*Attribute, len(s.Attributes))
+		for _, a := range s.Attributes {
+			attributes[a.Name] = a
+		}
+		return attributes
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		g := New()
+		got, err := g.GenerateGoFilesFromCorpus(roots)
+		require.NoError(t, err)
+		world := got.Members["savegame"].T.(*StructInfo).Members["world"].T.(*StructInfo)
+		assert.Empty(t, world.Attributes)
+	})
+
+	g := New(WithAttributes(true))
+	got, err := g.GenerateGoFilesFromCorpus(roots)
+	require.NoError(t, err)
+	savegame := got.Members["savegame"].T.(*StructInfo)
+
+	pawn := savegame.Members["pawns"].T.(*CustomType).Type1.(*StructInfo)
+	pawn = g.RegisteredMembers[pawn.Name][0]
+	names := make([]string, 0, len(pawn.Attributes))
+	for _, a := range pawn.Attributes {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{"Class", "dead", "id", "weight"}, names)
+	attributes := attributesOf(pawn)
+	assert.Equal(t, reflect.Int64, attributes["id"].T)
+	assert.False(t, pawn.IsOptionalAttribute(attributes["id"]))
+	assert.Equal(t, reflect.Float64, attributes["weight"].T)
+	assert.Equal(t, reflect.Bool, attributes["dead"].T)
+	assert.True(t, pawn.IsOptionalAttribute(attributes["dead"]))
+
+	// The seed is a number in a file and a text in the other
+	world := g.RegisteredMembers["world"][0]
+	attributes = attributesOf(world)
+	require.Len(t, attributes, 1)
+	assert.Equal(t, reflect.String, attributes["seed"].T)
+	assert.False(t, world.IsOptionalAttribute(attributes["seed"]))
+	for _, v := range g.RegisteredMembers["world"] {
+		assert.Equal(t, world.Attributes, v.Attributes)
+	}
+
+	t.Run("classes", func(t *testing.T) {
+		g := New(WithAttributes(true), WithClassPolymorphism(true))
+		_, err := g.GenerateGoFilesFromCorpus(roots)
+		require.NoError(t, err)
+		for _, versions := range g.RegisteredMembers {
+			for _, v := range versions {
+				assert.Nil(t, v.attribute(ClassAttribute), v.Name)
+			}
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config customizes the code generated from the XML files.
+type Config struct {
+	// Overrides are applied to the elements matching their path.
+	Overrides []*Override `json:"overrides" yaml:"overrides"`
+}
+
+// Override replaces the names derived by the generator for the elements
+// matching Path.
+type Override struct {
+	// Path uses the syntax of the xml/path package and starts at the root
+	// element, e.g. "savegame>game>world" or "savegame>pawns>li[...]".
+	Path string `json:"path" yaml:"path"`
+	// StructName is the name of the structure created from the elements.
+	StructName string `json:"struct_name,omitempty" yaml:"struct_name,omitempty"`
+	// FieldName is the name of the Go field holding the elements in their
+	// parent structure.
+	FieldName string `json:"field_name,omitempty" yaml:"field_name,omitempty"`
+	// FileName is the name, without extension, of the file in which the
+	// structure is written.
+	FileName string `json:"file_name,omitempty" yaml:"file_name,omitempty"`
+	// Type pins the type of the member created from the elements. It's
+	// either a primitive kind (string, bool, int64, uint64 or float64),
+	// TypeRaw to keep the elements as *xml.Element, or the name of an
+	// external type when Import is set.
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Import is the import path of the external type. The type must
+	// implement _interface.Assigner and saver.Transformer.
+	Import string `json:"import,omitempty" yaml:"import,omitempty"`
+}
+
+// TypeRaw is the Type of the overrides keeping the elements as *xml.Element.
+const TypeRaw = "raw"
+
+var kinds = map[string]reflect.Kind{
+	reflect.String.String():  reflect.String,
+	reflect.Bool.String():    reflect.Bool,
+	reflect.Int64.String():   reflect.Int64,
+	reflect.Uint64.String():  reflect.Uint64,
+	reflect.Float64.String(): reflect.Float64,
+}
+
+// Kind returns the primitive kind the override pins the elements to.
+func (o *Override) Kind() (reflect.Kind, bool) {
+	if o.Import != "" {
+		return reflect.Invalid, false
+	}
+	k, ok := kinds[o.Type]
+	return k, ok
+}
+
+// IsRaw reports whether the override keeps the elements as *xml.Element.
+func (o *Override) IsRaw() bool {
+	return o.Import == "" && o.Type == TypeRaw
+}
+
+// ExternalType returns the package and the name of the external type of the
+// override. Type can be qualified ("colors.RGB") or not, in which case the
+// package is the last element of Import.
+func (o *Override) ExternalType() (pkg, name string, ok bool) {
+	if o.Import == "" {
+		return "", "", false
+	}
+	if i := strings.LastIndex(o.Type, "."); i >= 0 {
+		return o.Type[:i], o.Type[i+1:], true
+	}
+	return path.Base(o.Import), o.Type, true
+}
+
+var (
+	ErrMissingPath     = errors.New("config: override without path")
+	ErrEmptyOverride   = errors.New("config: override without anything to override")
+	ErrUnsupportedFile = errors.New("config: unsupported file extension, expected .json, .yaml or .yml")
+	ErrUnknownType     = errors.New("config: unknown type, expected string, bool, int64, uint64, float64 or raw")
+	ErrMissingType     = errors.New("config: import without type")
+)
+
+// Validate checks the overrides of c.
+func (c *Config) Validate() error {
+	for i, o := range c.Overrides {
+		if o.Path == "" {
+			return fmt.Errorf("override #%d: %w", i, ErrMissingPath)
+		}
+		if o.Import != "" {
+			if o.Type == "" {
+				return fmt.Errorf("override %s: %w", o.Path, ErrMissingType)
+			}
+			continue
+		}
+		if o.StructName == "" && o.FieldName == "" && o.FileName == "" && o.Type == "" {
+			return fmt.Errorf("override %s: %w", o.Path, ErrEmptyOverride)
+		}
+		if _, ok := o.Kind(); o.Type != "" && !ok && !o.IsRaw() {
+			return fmt.Errorf("override %s: %q: %w", o.Path, o.Type, ErrUnknownType)
+		}
+	}
+	return nil
+}
+
+// Load reads the configuration from a JSON or YAML file, depending on its
+// extension.
+func Load(fileName string) (*Config, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		err = json.Unmarshal(content, c)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, c)
+	default:
+		err = ErrUnsupportedFile
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config.Load: %s: %w", fileName, err)
+	}
+	if err = c.Validate(); err != nil {
+		return nil, fmt.Errorf("config.Load: %s: %w", fileName, err)
+	}
+	return c, nil
+}
@@ -1,11 +1,10 @@
 package generator
 
 import (
-	"log"
+	"fmt"
 	"reflect"
 
 	"github.com/cruffinoni/rimworld-editor/generator/paths"
-	"github.com/cruffinoni/rimworld-editor/helper"
 	"github.com/cruffinoni/rimworld-editor/xml"
 )
 
@@ -17,6 +16,12 @@ type CustomType struct {
 	Type1      any
 	Type2      any
 	ImportPath string
+	// External is set for the types given by the configuration, their
+	// ImportPath is a full import path.
+	External bool
+	// ItemTag is the tag of the items of a slice, when they're not written
+	// with the list tags of the dialect.
+	ItemTag string
 }
 
 func IsEmptyType(c *CustomType) bool {
@@ -42,20 +47,40 @@ func IsSliceType(c *CustomType) bool {
 	return c.Name == "Slice" && c.Pkg == "*types"
 }
 
+// IsEmbeddedType reports whether c is an embedded type, with or without the
+// pointer package prefix.
 func IsEmbeddedType(c *CustomType) bool {
 	if c == nil {
 		return false
 	}
-	return c.Name == "Type" && c.Pkg == "*embedded"
+	return c.Name == "Type" && (c.Pkg == "*embedded" || c.Pkg == "embedded")
 }
 
-func createCustomSlice(e *xml.Element, flag uint) any {
+func (g *Generator) createCustomSlice(e *xml.Element, flag uint) (any, error) {
+	kind, err := g.getTypeFromArrayOrSlice(e)
+	if err != nil {
+		return nil, err
+	}
+	t1, err := g.createSubtype(e, flag, kind)
+	if err != nil {
+		return nil, err
+	}
 	return &CustomType{
 		Name:       "Slice",
 		Pkg:        "*types",
-		Type1:      createSubtype(e, flag, getTypeFromArrayOrSlice(e)),
+		Type1:      t1,
 		ImportPath: paths.CustomTypesPath,
+		ItemTag:    g.itemTag(e),
+	}, nil
+}
+
+// itemTag returns the tag of the items of the list e, empty if they're
+// written with the list tags of the dialect.
+func (g *Generator) itemTag(e *xml.Element) string {
+	if e.Child == nil || g.dialect.IsListTag(e.Child.GetName()) {
+		return ""
 	}
+	return e.Child.GetName()
 }
 
 func IsMultipleType(c *CustomType) bool {
@@ -81,22 +106,25 @@ func createXMLElementType() any {
 // element as a reflect.Kind
 // If the type is invalid, we consider it as a *xml.Element.
 // Is it useful for empty tags.
-func determineTypeFromData(e *xml.Element, flag uint) any {
-	t := any(getTypeFromArrayOrSlice(e))
+func (g *Generator) determineTypeFromData(e *xml.Element, flag uint) (any, error) {
+	kind, err := g.getTypeFromArrayOrSlice(e)
+	if err != nil {
+		return nil, err
+	}
+	t := any(kind)
 	//log.Printf("Type of %v is %v", e.XMLPath(), t)
 	// We need to define this struct with of this all members
 	if t == reflect.Struct || t == reflect.Slice {
 		c := e.Child
 		// If the child is a list, let's create a slice from it
-		if helper.IsListTag(c.Child.GetName()) {
+		if g.dialect.IsListTag(c.Child.GetName()) {
 			// We set the forceChild flag to true to force the function createStructure
 			// to take the children of the list and not the list itself.
-			t = createArrayOrSlice(c, flag|forceChild)
-		} else {
-			// Otherwise, a basic struct is created
-			// We pass 'e' instead of 'c' because createStructure will take the children of 'e'
-			t = createStructure(e, flag)
+			return g.createArrayOrSlice(c, flag|forceChild)
 		}
+		// Otherwise, a basic struct is created
+		// We pass 'e' instead of 'c' because createStructure will take the children of 'e'
+		return g.createStructure(e, flag)
 	} else if t == reflect.Invalid {
 		if e.Data == nil {
 			t = createEmptyType()
@@ -111,23 +139,23 @@ func determineTypeFromData(e *xml.Element, flag uint) any {
 				Pkg:        "embedded",
 				Type1:      t,
 				ImportPath: paths.EmbeddedTypePath,
-			}
+			}, nil
 		}
 	}
-	return t
+	return t, nil
 }
 
-func createCustomTypeForMap(e *xml.Element, flag uint) any {
+func (g *Generator) createCustomTypeForMap(e *xml.Element, flag uint) (any, error) {
 	if e.Child == nil {
-		log.Panic("generate.createCustomTypeForMap: missing child")
+		return nil, newInferenceError(ErrMissingChild, "the map has no keys")
 	}
 
 	//log.Printf("Determining key type from %s", e.Child.XMLPath())
-	var (
-		c = e.Child
-		k = determineTypeFromData(c, flag|forceFullCheck)
-		v any
-	)
+	c := e.Child
+	k, err := g.determineTypeFromData(c, flag|forceFullCheck)
+	if err != nil {
+		return nil, err
+	}
 	if ct, ok := k.(*CustomType); ok {
 		// primary.Empty does not implement comparable
 		// Might be deleted when the types.Map type implements multiple as Key and not comparable anymore
@@ -137,8 +165,14 @@ func createCustomTypeForMap(e *xml.Element, flag uint) any {
 	}
 	//log.Printf("Key type: %T", k)
 	c = c.Next
+	if c == nil {
+		return nil, newInferenceError(ErrMissingChild, "the map has no values")
+	}
 	//log.Printf("Determining value type from '%v'", c.XMLPath())
-	v = determineTypeFromData(c, flag|forceFullCheck)
+	v, err := g.determineTypeFromData(c, flag|forceFullCheck)
+	if err != nil {
+		return nil, err
+	}
 	//log.Printf("Val type: %T", v)
 	// By default, maps are strings to strings
 	if k == reflect.Invalid || v == reflect.Invalid {
@@ -148,7 +182,7 @@ func createCustomTypeForMap(e *xml.Element, flag uint) any {
 			Type1:      reflect.String,
 			Type2:      reflect.String,
 			ImportPath: paths.CustomTypesPath,
-		}
+		}, nil
 	}
 	return &CustomType{
 		Name:       "Map",
@@ -156,7 +190,7 @@ func createCustomTypeForMap(e *xml.Element, flag uint) any {
 		Type1:      k,
 		Type2:      v,
 		ImportPath: paths.CustomTypesPath,
-	}
+	}, nil
 }
 
 func findFirstValidElementWithChild(e *xml.Element) *xml.Element {
@@ -181,13 +215,13 @@ func determineArrayOrSliceKind(e *xml.Element) reflect.Kind {
 // getTypeFromArrayOrSlice returns the type of the element as a reflect.Kind.
 // If the element is not a valid type, it returns reflect.Invalid.
 // flag can be modified by the function to indicates to bypass the first element of the array/slice
-func getTypeFromArrayOrSlice(e *xml.Element) reflect.Kind {
+func (g *Generator) getTypeFromArrayOrSlice(e *xml.Element) (reflect.Kind, error) {
 	// Return Invalid if the element has no child
 	if e.Child == nil {
 		if e.Data == nil {
-			return reflect.Invalid
+			return reflect.Invalid, nil
 		} else {
-			return e.Data.Kind()
+			return e.Data.Kind(), nil
 		}
 	}
 
@@ -203,10 +237,10 @@ func getTypeFromArrayOrSlice(e *xml.Element) reflect.Kind {
 		n := k.Next
 		for n != nil && n.GetName() == k.GetName() {
 			if n.Child != nil {
-				if helper.IsListTag(n.Child.GetName()) {
-					return determineArrayOrSliceKind(n)
+				if g.dialect.IsListTag(n.Child.GetName()) {
+					return determineArrayOrSliceKind(n), nil
 				}
-				return reflect.Struct
+				return reflect.Struct, nil
 			}
 			n = n.Next
 		}
@@ -214,10 +248,10 @@ func getTypeFromArrayOrSlice(e *xml.Element) reflect.Kind {
 	if k.Child != nil {
 		// On the other hand, this part only check the first element
 		// but sometimes the first element has no data
-		if helper.IsListTag(k.Child.GetName()) {
-			return determineArrayOrSliceKind(k)
+		if g.dialect.IsListTag(k.Child.GetName()) {
+			return determineArrayOrSliceKind(k), nil
 		}
-		return reflect.Struct
+		return reflect.Struct, nil
 	}
 
 	for k != nil {
@@ -227,8 +261,8 @@ func getTypeFromArrayOrSlice(e *xml.Element) reflect.Kind {
 				// Float64 and Int64 can be interchangeable
 				!(kdk == reflect.Float64 && kt == reflect.Int64) &&
 				!(kdk == reflect.Int64 && kt == reflect.Float64) {
-				log.Printf("last: '%v' & kind %s", k.Data, k.Data.Kind())
-				log.Panicf("getTypeFromArrayOrSlice: found type %v, expected %v on path %v ('%v')", kdk, kt, k.XMLPath(), k.Data.GetData())
+				return reflect.Invalid, asInferenceError(newInferenceError(ErrUnsolvableMismatch,
+					fmt.Sprintf("found type %v, expected %v ('%v')", kdk, kt, k.Data.GetData()), kt, kdk), k)
 			}
 			// Float64 and Int64 can be interchangeable, but we prefer to keep Float64
 			if !(kt == reflect.Float64 && kdk == reflect.Int64) {
@@ -238,19 +272,22 @@ func getTypeFromArrayOrSlice(e *xml.Element) reflect.Kind {
 		k = k.Next
 	}
 	// The first element may have no data, so we check its sibling (the second one) for a child
-	if kt == reflect.Invalid && e.Next != nil && helper.IsListTag(e.Next.GetName()) && e.Next.GetName() == e.GetName() {
+	if kt == reflect.Invalid && e.Next != nil && g.dialect.IsListTag(e.Next.GetName()) && e.Next.GetName() == e.GetName() {
 		siblingWithChild := e.Next
 		for siblingWithChild != nil && siblingWithChild.Child == nil {
 			siblingWithChild = siblingWithChild.Next
 		}
 		if siblingWithChild == nil || siblingWithChild.Child == nil {
-			return reflect.Invalid
+			return reflect.Invalid, nil
+		}
+		siblingType, err := g.createTypeFromElement(siblingWithChild, flagNone)
+		if err != nil {
+			return reflect.Invalid, err
 		}
-		siblingType := createTypeFromElement(siblingWithChild, flagNone)
-		if !IsSameType(siblingType, reflect.Invalid, 0) {
-			return Complex
+		if !IsSameType(siblingType, reflect.Invalid) {
+			return Complex, nil
 		}
-		return kt
+		return kt, nil
 	}
-	return kt
+	return kt, nil
 }
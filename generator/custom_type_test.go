@@ -383,13 +383,17 @@ func Test_createCustomType(t *testing.T) {
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			root := resetVarsAndReadBuffer(t, tt.args)
-			var res any
+			g, root := newGeneratorAndReadBuffer(t, tt.args)
+			var (
+				res any
+				err error
+			)
 			if strings.Contains(name, "map") {
-				res = createCustomTypeForMap(root.Child, tt.args.flag)
+				res, err = g.createCustomTypeForMap(root.Child, tt.args.flag)
 			} else {
-				res = createCustomSlice(root.Child, tt.args.flag)
+				res, err = g.createCustomSlice(root.Child, tt.args.flag)
 			}
+			require.NoError(t, err)
 			require.IsType(t, res, tt.want)
 			got := res.(*CustomType)
 			wanted := tt.want.(*CustomType)
@@ -0,0 +1,221 @@
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+// NamingPolicy picks the name of the structure shared by structurally
+// identical structures. names are sorted and contain at least 2 names.
+type NamingPolicy func(names []string) string
+
+// FirstName keeps the first name in alphabetical order.
+func FirstName(names []string) string {
+	return names[0]
+}
+
+// ShortestName keeps the shortest name, the first in alphabetical order in
+// case of a tie.
+func ShortestName(names []string) string {
+	shortest := names[0]
+	for _, n := range names[1:] {
+		if len(n) < len(shortest) {
+			shortest = n
+		}
+	}
+	return shortest
+}
+
+// CommonName keeps the name shared by every structure once their numbered
+// suffixes are removed, e.g. "node" for "node0", "node1" and "node12".
+// It falls back to ShortestName when the names differ.
+func CommonName(names []string) string {
+	common := strings.TrimRightFunc(names[0], unicode.IsDigit)
+	for _, n := range names[1:] {
+		if strings.TrimRightFunc(n, unicode.IsDigit) != common {
+			return ShortestName(names)
+		}
+	}
+	if common == "" {
+		return ShortestName(names)
+	}
+	return common
+}
+
+// NamingPolicies are the naming policies by name.
+var NamingPolicies = map[string]NamingPolicy{
+	"first":    FirstName,
+	"shortest": ShortestName,
+	"common":   CommonName,
+}
+
+// WithDeduplication merges the structures having the same shape, which are
+// the same members with the same types, into one structure named by policy.
+// A nil policy disables the deduplication, which is the default.
+func WithDeduplication(policy NamingPolicy) Option {
+	return func(g *Generator) {
+		g.namingPolicy = policy
+	}
+}
+
+// Deduplicated returns the structures merged by the last generation, indexed
+// by the name of the structure they have been merged into.
+func (g *Generator) Deduplicated() map[string][]string {
+	return g.deduplicated
+}
+
+// fingerprinter computes a textual representation of the shape of types.
+// Two structures with the same fingerprint generate the same Go code apart
+// from their name.
+type fingerprinter struct {
+	done map[*StructInfo]string
+	// visiting holds the structures being fingerprinted to stop on
+	// recursive structures.
+	visiting map[*StructInfo]bool
+}
+
+func (f *fingerprinter) fingerprint(t any) string {
+	switch va := t.(type) {
+	case reflect.Kind:
+		return va.String()
+	case *xml.Element:
+		return "xml.Element"
+	case *Enum:
+		return "enum:" + va.Name
+	case *FixedArray:
+		return fmt.Sprintf("[%d]%s", va.Size, f.fingerprint(va.PrimaryType))
+	case *CustomType:
+		var sb strings.Builder
+		sb.WriteString(va.ImportPath + ":" + va.Pkg + "." + va.Name)
+		if va.Type1 != nil {
+			sb.WriteString("[" + f.fingerprint(va.Type1))
+			if va.Type2 != nil {
+				sb.WriteString("," + f.fingerprint(va.Type2))
+			}
+			sb.WriteString("]")
+		}
+		return sb.String()
+	case *StructInfo:
+		return f.structFingerprint(va)
+	case *Polymorphic:
+		var sb strings.Builder
+		sb.WriteString("polymorphic:" + va.Name + "{")
+		for _, c := range va.Classes() {
+			sb.WriteString(c + ":" + f.fingerprint(va.Variants[c]) + ";")
+		}
+		if va.Default != nil {
+			sb.WriteString("default:" + f.fingerprint(va.Default))
+		}
+		sb.WriteString("}")
+		return sb.String()
+	case *Union:
+		var sb strings.Builder
+		sb.WriteString("union{")
+		for _, v := range va.Variants {
+			sb.WriteString(f.fingerprint(v.T) + ";")
+		}
+		sb.WriteString("}")
+		return sb.String()
+	case nil:
+		return "nil"
+	}
+	return fmt.Sprintf("%T", t)
+}
+
+func (f *fingerprinter) structFingerprint(s *StructInfo) string {
+	if fp, ok := f.done[s]; ok {
+		return fp
+	}
+	if f.visiting[s] {
+		// A recursive structure refers to itself by name
+		return "recursive:" + s.Name
+	}
+	f.visiting[s] = true
+	defer delete(f.visiting, s)
+	names := make([]string, 0, len(s.Members))
+	for name := range s.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("struct{")
+	if s.FileName != "" {
+		sb.WriteString("file:" + s.FileName + ";")
+	}
+	for _, name := range names {
+		m := s.Members[name]
+		sb.WriteString(name)
+		if m.FieldName != "" {
+			sb.WriteString("(" + m.FieldName + ")")
+		}
+		sb.WriteString(" " + f.fingerprint(m.T) + ";")
+	}
+	for _, a := range s.Attributes {
+		sb.WriteString("@" + a.Name + " " + a.T.String() + ";")
+	}
+	sb.WriteString("}")
+	f.done[s] = sb.String()
+	return f.done[s]
+}
+
+// deduplicate merges the registered structures sharing the same fingerprint.
+func (g *Generator) deduplicate() {
+	if g.namingPolicy == nil {
+		return
+	}
+	var (
+		mv     = g.RegisteredMembers
+		f      = &fingerprinter{done: make(map[*StructInfo]string), visiting: make(map[*StructInfo]bool)}
+		groups = make(map[string][]string)
+		order  []string
+	)
+	for _, name := range mv.Names() {
+		// The variants of polymorphic items keep their name, which the
+		// class dispatch relies on
+		if len(mv[name]) == 0 || g.variants[name] {
+			continue
+		}
+		fp := f.fingerprint(mv[name][0])
+		if _, ok := groups[fp]; !ok {
+			order = append(order, fp)
+		}
+		groups[fp] = append(groups[fp], name)
+	}
+	for _, fp := range order {
+		names := groups[fp]
+		if len(names) < 2 {
+			continue
+		}
+		kept := g.namingPolicy(names)
+		// The name picked by the policy can't be the one of another shape
+		if _, ok := mv[kept]; ok && !containsString(names, kept) {
+			kept = names[0]
+		}
+		versions := make([]*StructInfo, 0)
+		for _, name := range names {
+			for _, v := range mv[name] {
+				v.Name = kept
+				versions = append(versions, v)
+			}
+			delete(mv, name)
+			if name != kept {
+				g.deduplicated[kept] = append(g.deduplicated[kept], name)
+			}
+		}
+		mv[kept] = versions
+	}
+}
+
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
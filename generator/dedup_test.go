@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamingPolicies(t *testing.T) {
+	tests := map[string]struct {
+		policy NamingPolicy
+		names  []string
+		want   string
+	}{
+		"first":             {FirstName, []string{"destination", "position"}, "destination"},
+		"shortest":          {ShortestName, []string{"destination", "pos", "position"}, "pos"},
+		"shortest_tie":      {ShortestName, []string{"abc", "abd"}, "abc"},
+		"common_numbered":   {CommonName, []string{"node0", "node1", "node12"}, "node"},
+		"common_different":  {CommonName, []string{"destination", "position"}, "position"},
+		"common_only_digit": {CommonName, []string{"1", "2"}, "1"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy(tt.names))
+		})
+	}
+}
+
+func TestGenerator_deduplicate(t *testing.T) {
+	const document = `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<position>
+			<x>1</x>
+			<y>0</y>
+			<z>3</z>
+		</position>
+		<destination>
+			<x>4</x>
+			<y>0</y>
+			<z>2</z>
+		</destination>
+	</pawn>
+	<camera>
+		<x>1</x>
+		<y>0</y>
+		<z>3.5</z>
+	</camera>
+</savegame>
+`
+	t.Run("disabled", func(t *testing.T) {
+		g := New()
+		_, err := g.GenerateGoFiles(readBuffer(t, document))
+		require.NoError(t, err)
+		assert.Contains(t, g.RegisteredMembers, "position")
+		assert.Contains(t, g.RegisteredMembers, "destination")
+		assert.Empty(t, g.Deduplicated())
+	})
+	t.Run("first", func(t *testing.T) {
+		g := New(WithDeduplication(FirstName))
+		got, err := g.GenerateGoFiles(readBuffer(t, document))
+		require.NoError(t, err)
+		assert.Contains(t, g.RegisteredMembers, "destination")
+		assert.NotContains(t, g.RegisteredMembers, "position")
+		// camera has a float64 z, so it's another shape
+		assert.Contains(t, g.RegisteredMembers, "camera")
+		assert.Equal(t, map[string][]string{"destination": {"position"}}, g.Deduplicated())
+
+		savegame := got.Members["savegame"].T.(*StructInfo)
+		pawn := savegame.Members["pawn"].T.(*StructInfo)
+		assert.Equal(t, "destination", pawn.Members["position"].T.(*StructInfo).Name)
+		assert.Equal(t, "destination", pawn.Members["destination"].T.(*StructInfo).Name)
+	})
+}
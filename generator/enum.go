@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Enum is a string type whose values are all known. It replaces the string
+// members taking only a few distinct values, see WithEnums, or comes from a
+// seed, see WithSeed.
+type Enum struct {
+	Name string
+	// Values are the distinct values seen, sorted.
+	Values []string
+}
+
+// WithEnums types the string members taking at most threshold distinct
+// values as an Enum. A member needs to repeat at least one of its values to
+// become an Enum, so a member seen a few times isn't mistaken for one.
+// A threshold of 0 disables the enumerations, which is the default.
+func WithEnums(threshold int) Option {
+	return func(g *Generator) {
+		g.enumThreshold = threshold
+	}
+}
+
+// countValue records the value of an element of the member name of s.
+func (g *Generator) countValue(s *StructInfo, name, value string) {
+	if g.enumThreshold <= 0 {
+		return
+	}
+	if g.values[s] == nil {
+		g.values[s] = make(map[string]map[string]bool)
+	}
+	if g.values[s][name] == nil {
+		g.values[s][name] = make(map[string]bool)
+	}
+	g.values[s][name][value] = true
+}
+
+// enumValues returns the sorted values of the member name of the versions
+// of a structure, or nil if they can't make an Enum.
+func (g *Generator) enumValues(versions []*StructInfo, name string) []string {
+	values := make(map[string]bool)
+	for _, v := range versions {
+		for value := range g.values[v][name] {
+			// Multi-line texts are never enumerations
+			if value == "" || strings.ContainsAny(value, "\r\n") {
+				return nil
+			}
+			values[value] = true
+		}
+	}
+	if len(values) == 0 || len(values) > g.enumThreshold {
+		return nil
+	}
+	sorted := make([]string, 0, len(values))
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// applyEnums replaces the string members of the registered structures by an
+// Enum when they take few enough values. It must run once the occurrences
+// are applied.
+func (g *Generator) applyEnums() {
+	if g.enumThreshold <= 0 {
+		return
+	}
+	for _, name := range g.RegisteredMembers.Names() {
+		versions := g.RegisteredMembers[name]
+		if len(versions) == 0 {
+			continue
+		}
+		for _, mName := range versions[0].memberNames() {
+			m := versions[0].Members[mName]
+			if m.T != reflect.String || m.Pinned {
+				continue
+			}
+			values := g.enumValues(versions, mName)
+			if values == nil || len(values) >= m.Occurrences {
+				continue
+			}
+			e := &Enum{
+				Name:   g.freeTypeName(name + "_" + mName),
+				Values: values,
+			}
+			for _, v := range versions {
+				if vm, ok := v.Members[mName]; ok && vm.T == reflect.String {
+					vm.T = e
+				}
+			}
+		}
+	}
+}
+
+// stableTypeName returns the name given to the types asked for as name,
+// recorded in names: the same one every time, free when it's first given.
+func (g *Generator) stableTypeName(names map[string]string, name string) string {
+	if n, ok := names[name]; ok {
+		return n
+	}
+	n := g.freeTypeName(name)
+	names[name] = n
+	return n
+}
+
+// freeTypeName returns name, suffixed when a structure or another generated
+// type (Enum, Union) already has it.
+func (g *Generator) freeTypeName(name string) string {
+	_, isStruct := g.RegisteredMembers[name]
+	if !isStruct && !g.typeNames[name] {
+		g.typeNames[name] = true
+		return name
+	}
+	for {
+		n := g.addUniqueNumber(name)
+		if _, isStruct = g.RegisteredMembers[n]; !isStruct && !g.typeNames[n] {
+			g.typeNames[n] = true
+			return n
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_enums(t *testing.T) {
+	const document = `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawns>
+		<li>
+			<name>Tynan</name>
+			<gender>Male</gender>
+			<faction>Faction_1</faction>
+		</li>
+		<li>
+			<name>Randy</name>
+			<gender>Male</gender>
+			<faction>Faction_2</faction>
+		</li>
+		<li>
+			<name>Cassandra</name>
+			<gender>Female</gender>
+			<faction>Faction_1</faction>
+		</li>
+		<li>
+			<name>Phoebe</name>
+			<gender>Female</gender>
+			<faction>Faction_3</faction>
+		</li>
+	</pawns>
+</savegame>
+`
+	pawnOf := func(t *testing.T, g *Generator, got *StructInfo) *StructInfo {
+		savegame := got.Members["savegame"].T.(*StructInfo)
+		pawn := savegame.Members["pawns"].T.(*CustomType).Type1.(*StructInfo)
+		return g.RegisteredMembers[pawn.Name][0]
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		g := New()
+		got, err := g.GenerateGoFiles(readBuffer(t, document))
+		require.NoError(t, err)
+		pawn := pawnOf(t, g, got)
+		assert.Equal(t, reflect.String, pawn.Members["gender"].T)
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		g := New(WithEnums(2))
+		got, err := g.GenerateGoFiles(readBuffer(t, document))
+		require.NoError(t, err)
+		pawn := pawnOf(t, g, got)
+		require.IsType(t, &Enum{}, pawn.Members["gender"].T)
+		gender := pawn.Members["gender"].T.(*Enum)
+		assert.Equal(t, pawn.Name+"_gender", gender.Name)
+		assert.Equal(t, []string{"Female", "Male"}, gender.Values)
+		// 3 values, above the threshold
+		assert.Equal(t, reflect.String, pawn.Members["faction"].T)
+		// Every value is unique
+		assert.Equal(t, reflect.String, pawn.Members["name"].T)
+	})
+
+	t.Run("unique_values", func(t *testing.T) {
+		g := New(WithEnums(10))
+		got, err := g.GenerateGoFiles(readBuffer(t, document))
+		require.NoError(t, err)
+		pawn := pawnOf(t, g, got)
+		assert.IsType(t, &Enum{}, pawn.Members["faction"].T)
+		assert.Equal(t, reflect.String, pawn.Members["name"].T)
+	})
+}
@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+// Categories of InferenceError. They can be checked with errors.Is.
+var (
+	ErrUnsolvableMismatch = errors.New("unsolvable mismatch")
+	ErrMissingChild       = errors.New("missing child")
+	ErrUnhandledType      = errors.New("unhandled type")
+)
+
+// InferenceError is returned when the type of an element can't be inferred.
+type InferenceError struct {
+	// Path is the XML path of the offending element. When the error
+	// happens while fixing the registered members, it's the path of the
+	// member in the first element of its structure, and Member is set. It's
+	// empty if the elements of the structure aren't known, e.g. for a seed.
+	Path string
+	// Member is the name of the member whose type couldn't be inferred.
+	Member string
+	// Types are the conflicting types, if any.
+	Types []any
+	// Category is one of the ErrXXX errors of this package.
+	Category error
+	// Detail gives more context about the error.
+	Detail string
+}
+
+func newInferenceError(category error, detail string, types ...any) *InferenceError {
+	return &InferenceError{
+		Types:    types,
+		Category: category,
+		Detail:   detail,
+	}
+}
+
+func (e *InferenceError) Error() string {
+	var sb strings.Builder
+	switch {
+	case e.Path != "":
+		sb.WriteString(e.Path + ": ")
+	case e.Member != "":
+		sb.WriteString("member " + e.Member + ": ")
+	}
+	sb.WriteString(e.Category.Error())
+	if e.Detail != "" {
+		sb.WriteString(": " + e.Detail)
+	}
+	if len(e.Types) > 0 {
+		names := make([]string, len(e.Types))
+		for i, t := range e.Types {
+			names[i] = fmt.Sprintf("%T", t)
+			if n := getTypeName(t); n != "" {
+				names[i] += " (" + n + ")"
+			}
+		}
+		sb.WriteString(" [" + strings.Join(names, " / ") + "]")
+	}
+	return sb.String()
+}
+
+func (e *InferenceError) Unwrap() error {
+	return e.Category
+}
+
+// InferenceErrors is the list of the problems met by a lenient generation.
+type InferenceErrors []*InferenceError
+
+func (l InferenceErrors) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Unwrap returns the problems, so their categories can be checked with
+// errors.Is.
+func (l InferenceErrors) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// asInferenceError converts err to an InferenceError and fills the path from
+// e when it's unknown yet.
+func asInferenceError(err error, e *xml.Element) *InferenceError {
+	var ie *InferenceError
+	if !errors.As(err, &ie) {
+		ie = newInferenceError(ErrUnhandledType, err.Error())
+	}
+	if ie.Path == "" && e != nil {
+		ie.Path = e.XMLPath()
+	}
+	return ie
+}
+
+// memberPath returns the XML path of the member name in the first element
+// the version s of a structure has been created from, or an empty path if
+// there is none.
+func (g *Generator) memberPath(s *StructInfo, name string) string {
+	p, ok := g.paths[s]
+	if !ok {
+		return ""
+	}
+	return p + ">" + name
+}
+
+// fallbackToElement handles err, met while inferring the type of e. In
+// lenient mode, the problem is recorded and the member is typed as an
+// *xml.Element. Otherwise, the error is returned with the path of e.
+func (g *Generator) fallbackToElement(err error, e *xml.Element) (any, error) {
+	ie := asInferenceError(err, e)
+	if !g.lenient {
+		return nil, ie
+	}
+	g.problems = append(g.problems, ie)
+	if e != nil {
+		g.recordFallback(schemaPath(e), ie)
+	}
+	return createXMLElementType(), nil
+}
@@ -2,17 +2,26 @@ package files
 
 import (
 	"sort"
+	"strconv"
 	"strings"
-
-	"github.com/cruffinoni/rimworld-editor/generator/paths"
 )
 
 type buffer struct {
 	writtenHeaders map[string]bool
-	header         strings.Builder
-	imp            []string
-	body           strings.Builder
-	footer         strings.Builder
+	// module is the path of the runtime module, aliases the aliases of its
+	// packages.
+	module  string
+	aliases map[string]string
+	// pkg is the subpackage of the file in the layout, empty for the
+	// output directory.
+	pkg    string
+	header strings.Builder
+	imp    []string
+	body   strings.Builder
+	footer strings.Builder
+	// assertions are written after the footer because they may be added
+	// while the structure is being written.
+	assertions strings.Builder
 }
 
 func (b *buffer) writeImport(imp ...string) {
@@ -21,7 +30,22 @@ func (b *buffer) writeImport(imp ...string) {
 			return
 		}
 		b.writtenHeaders[v] = true
-		b.imp = append(b.imp, `"`+paths.CodePackage+v+`"`+"\n")
+		line := strconv.Quote(b.module + "/" + v)
+		if alias, ok := b.aliases[v]; ok {
+			line = alias + " " + line
+		}
+		b.imp = append(b.imp, line+"\n")
+	}
+}
+
+// writeExternalImport imports the packages outside of the runtime module.
+func (b *buffer) writeExternalImport(imp ...string) {
+	for _, v := range imp {
+		if h, ok := b.writtenHeaders[v]; ok && h {
+			continue
+		}
+		b.writtenHeaders[v] = true
+		b.imp = append(b.imp, `"`+v+`"`+"\n")
 	}
 }
 
@@ -37,6 +61,28 @@ func (b *buffer) writeToFooter(s string) {
 	b.footer.WriteString(s)
 }
 
+func (b *buffer) writeAssertion(s string) {
+	b.assertions.WriteString(s)
+}
+
+// append appends the declarations of o to b, with its imports.
+func (b *buffer) append(o *buffer) {
+	lines := make(map[string]bool, len(b.imp))
+	for _, v := range b.imp {
+		lines[v] = true
+	}
+	for _, v := range o.imp {
+		if !lines[v] {
+			lines[v] = true
+			b.imp = append(b.imp, v)
+		}
+	}
+	for k, v := range o.writtenHeaders {
+		b.writtenHeaders[k] = b.writtenHeaders[k] || v
+	}
+	b.writeToBody("\n" + o.body.String() + o.footer.String() + o.assertions.String())
+}
+
 func (b *buffer) bytes() []byte {
 	builder := strings.Builder{}
 	builder.WriteString(b.header.String())
@@ -52,5 +98,6 @@ func (b *buffer) bytes() []byte {
 	}
 	builder.WriteString(b.body.String())
 	builder.WriteString(b.footer.String())
+	builder.WriteString(b.assertions.String())
 	return []byte(builder.String())
 }
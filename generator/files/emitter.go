@@ -0,0 +1,127 @@
+package files
+
+import (
+	"errors"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+)
+
+// Emitter renders the types of the graph walked by Walk. Each type is
+// emitted once, before the types it refers to. A structure is emitted in its
+// best version, the first one registered by the generator.
+type Emitter interface {
+	EmitStruct(s *generator.StructInfo) error
+	EmitEnum(e *generator.Enum) error
+	EmitUnion(u *generator.Union) error
+	EmitPolymorphic(p *generator.Polymorphic) error
+	// Flush is called once the whole graph is emitted.
+	Flush() error
+}
+
+// SkipReferences is returned by an Emitter which doesn't render the types
+// referred to by the type it was given, so they aren't walked, unless
+// another type refers to them. It's not returned by Walk.
+var SkipReferences = errors.New("files: skip the references")
+
+type walker struct {
+	registeredMember generator.MemberVersioning
+	emitter          Emitter
+	// emitted are the types already emitted, by kind and name.
+	emitted map[string]bool
+}
+
+// Walk walks the types of s, the structure returned by the generator, and of
+// its members recursively, and emits them to e. registeredMember are the
+// structures registered by the generator.
+func Walk(s *generator.StructInfo, registeredMember generator.MemberVersioning, e Emitter) error {
+	w := &walker{
+		registeredMember: registeredMember,
+		emitter:          e,
+		emitted:          make(map[string]bool),
+	}
+	if err := w.walk(s); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// once reports whether the type named name of the given kind is seen for the
+// first time.
+func (w *walker) once(kind, name string) bool {
+	key := kind + ":" + name
+	if w.emitted[key] {
+		return false
+	}
+	w.emitted[key] = true
+	return true
+}
+
+// references returns the types referred to by t, which is emitted if it's
+// seen for the first time. They are nil if t is already emitted or if the
+// emitter skips them.
+func (w *walker) references(t any) ([]any, error) {
+	var (
+		err  error
+		refs []any
+	)
+	switch va := t.(type) {
+	case *generator.StructInfo:
+		best := w.registeredMember.Best(va)
+		if !w.once("struct", best.Name) {
+			return nil, nil
+		}
+		err = w.emitter.EmitStruct(best)
+		for _, m := range best.Order {
+			refs = append(refs, m.T)
+		}
+	case *generator.Enum:
+		if w.once("enum", va.Name) {
+			err = w.emitter.EmitEnum(va)
+		}
+	case *generator.Union:
+		if !w.once("union", va.Name) {
+			return nil, nil
+		}
+		err = w.emitter.EmitUnion(va)
+		for _, v := range va.Variants {
+			refs = append(refs, v.T)
+		}
+	case *generator.Polymorphic:
+		if !w.once("polymorphic", va.Name) {
+			return nil, nil
+		}
+		err = w.emitter.EmitPolymorphic(va)
+		for _, c := range va.Classes() {
+			refs = append(refs, va.Variants[c])
+		}
+		if va.Default != nil {
+			refs = append(refs, va.Default)
+		}
+	case *generator.CustomType:
+		if !va.External {
+			refs = append(refs, va.Type1, va.Type2)
+		}
+	case *generator.FixedArray:
+		refs = append(refs, va.PrimaryType)
+	}
+	if errors.Is(err, SkipReferences) {
+		return nil, nil
+	}
+	return refs, err
+}
+
+func (w *walker) walk(t any) error {
+	refs, err := w.references(t)
+	if err != nil {
+		return err
+	}
+	for _, r := range refs {
+		if r == nil {
+			continue
+		}
+		if err = w.walk(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
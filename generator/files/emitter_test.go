@@ -0,0 +1,76 @@
+package files
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+)
+
+// recorder records the names of the types it's given.
+type recorder struct {
+	emitted []string
+	// skip are the names of the types whose references are skipped.
+	skip    map[string]bool
+	flushed int
+}
+
+func (r *recorder) emit(name string) error {
+	r.emitted = append(r.emitted, name)
+	if r.skip[name] {
+		return SkipReferences
+	}
+	return nil
+}
+
+func (r *recorder) EmitStruct(s *generator.StructInfo) error { return r.emit(s.Name) }
+
+func (r *recorder) EmitEnum(e *generator.Enum) error { return r.emit(e.Name) }
+
+func (r *recorder) EmitUnion(u *generator.Union) error { return r.emit(u.Name) }
+
+func (r *recorder) EmitPolymorphic(p *generator.Polymorphic) error { return r.emit(p.Name) }
+
+func (r *recorder) Flush() error {
+	r.flushed++
+	return nil
+}
+
+func TestWalk(t *testing.T) {
+	s, registered := generate(t, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<name>Tynan</name>
+		<parent><name>Randy</name></parent>
+		<things>
+			<li Class="Apparel"><def>Hat</def></li>
+			<li Class="Weapon"><def>Gun</def></li>
+		</things>
+	</pawn>
+	<pawn>
+		<name>Cassandra</name>
+	</pawn>
+</savegame>`, generator.WithClassPolymorphism(true))
+	r := &recorder{}
+	noError(t, Walk(s, registered, r))
+	assert.Equal(t, 1, r.flushed)
+	assert.Len(t, r.emitted, len(uniqueNames(r.emitted)), "a type is emitted once: %v", r.emitted)
+	assert.Equal(t, s.Name, r.emitted[0])
+	assert.Contains(t, r.emitted, "things_Apparel")
+
+	t.Run("skip references", func(t *testing.T) {
+		r := &recorder{skip: map[string]bool{"things_item": true}}
+		noError(t, Walk(s, registered, r))
+		assert.Contains(t, r.emitted, "things_item")
+		assert.NotContains(t, r.emitted, "things_Apparel")
+	})
+}
+
+func uniqueNames(names []string) map[string]bool {
+	unique := make(map[string]bool, len(names))
+	for _, n := range names {
+		unique[n] = true
+	}
+	return unique
+}
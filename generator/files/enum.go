@@ -0,0 +1,68 @@
+package files
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+)
+
+// enumConstants returns the names of the constants of the values of e, in
+// the order of its values.
+func enumConstants(typeName string, e *generator.Enum) []string {
+	var (
+		names = make([]string, len(e.Values))
+		used  = make(map[string]bool, len(e.Values))
+	)
+	for i, v := range e.Values {
+		// Only the letters and digits of the value are kept to make an
+		// identifier
+		suffix := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return r
+			}
+			return ' '
+		}, v)
+		name := typeName + strcase.ToCamel(suffix)
+		if name == typeName || used[name] {
+			name = typeName + "Value" + strconv.Itoa(i)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// generateEnumToPath writes the type of e, its constants and its Values and
+// Valid methods in their own file.
+func (gw *GoWriter) generateEnumToPath(path string, e *generator.Enum) error {
+	fileName := path + "/" + strcase.ToSnake(e.Name) + ".go"
+	if gw.exists(fileName) {
+		return nil
+	}
+	buf := gw.newBuffer(path)
+	var (
+		typeName   = strcase.ToCamel(e.Name)
+		identifier = strings.ToLower(typeName[:1])
+		constants  = enumConstants(typeName, e)
+	)
+	buf.writeToBody("// " + typeName + " holds the values known at generation time. Unknown values\n" +
+		"// are still accepted, see Valid.\n" +
+		"type " + typeName + " string\n\nconst (\n")
+	for i, v := range e.Values {
+		buf.writeToBody("\t" + constants[i] + " " + typeName + " = " + strconv.Quote(v) + "\n")
+	}
+	buf.writeToBody(")\n")
+	buf.writeToFooter("\n// Values returns the known values of " + typeName + ".\n" +
+		"func (" + typeName + ") Values() []" + typeName + " {\n" +
+		"\treturn []" + typeName + "{" + strings.Join(constants, ", ") + "}\n}\n")
+	buf.writeToFooter("\n// Valid reports whether " + identifier + " is one of the known values.\n" +
+		"func (" + identifier + " " + typeName + ") Valid() bool {\n" +
+		"\tswitch " + identifier + " {\n" +
+		"\tcase " + strings.Join(constants, ", ") + ":\n" +
+		"\t\treturn true\n\t}\n\treturn false\n}\n")
+	return gw.writeFile(fileName, buf)
+}
@@ -0,0 +1,330 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/generator/paths"
+)
+
+// defaultRuntimeModule is the module of the runtime imported by the
+// generated code by default.
+var defaultRuntimeModule = strings.TrimSuffix(paths.CodePackage, "/")
+
+// WithRuntimeModule sets the path of the module of the runtime imported by
+// the generated code, e.g. a fork of this project. It's paths.CodePackage by
+// default.
+func WithRuntimeModule(module string) WriterOption {
+	return func(gw *GoWriter) {
+		gw.runtimeModule = strings.TrimSuffix(module, "/")
+	}
+}
+
+// WithPackageAlias imports the runtime package pkg, given by its path in the
+// runtime module like paths.CustomTypesPath, under the name alias. The
+// generated code refers to it by alias.
+func WithPackageAlias(pkg, alias string) WriterOption {
+	return func(gw *GoWriter) {
+		if gw.packageAliases == nil {
+			gw.packageAliases = make(map[string]string)
+		}
+		gw.packageAliases[strings.Trim(pkg, "/")] = alias
+	}
+}
+
+// newBuffer returns the buffer of a file of the directory dir, with its
+// header, importing the runtime packages from the runtime module of gw under
+// their aliases.
+func (gw *GoWriter) newBuffer(dir string) *buffer {
+	b := &buffer{
+		writtenHeaders: make(map[string]bool),
+		module:         gw.runtimeModule,
+		aliases:        gw.packageAliases,
+		pkg:            gw.subpackage(dir),
+	}
+	gw.writeHeader(b, gw.packageOf(b.pkg))
+	return b
+}
+
+// runtimePackageName returns the name of the runtime package at path pkg in
+// the runtime module, its last element, except for xml/interface whose name
+// is a keyword.
+func runtimePackageName(pkg string) string {
+	if pkg == paths.XmlInterface {
+		return "_interface"
+	}
+	return pathpkg.Base(pkg)
+}
+
+// source returns the formatted code of b. The runtime packages imported
+// under an alias are referred to by their alias, the types of the other
+// packages of the layout by their package.
+func (gw *GoWriter) source(b *buffer) ([]byte, error) {
+	src := b.bytes()
+	formatted, err := gw.qualify(b, src)
+	if err == nil {
+		formatted, err = renamePackages(formatted, gw.packageRenames())
+	}
+	if err == nil {
+		formatted, err = format.Source(formatted)
+	}
+	if err != nil {
+		log.Printf("Err: Format buffer:\n%s", src)
+		return nil, err
+	}
+	return formatted, nil
+}
+
+// packageRenames returns the aliases of the runtime packages by package
+// name.
+func (gw *GoWriter) packageRenames() map[string]string {
+	renames := make(map[string]string, len(gw.packageAliases))
+	for pkg, alias := range gw.packageAliases {
+		renames[runtimePackageName(pkg)] = alias
+	}
+	return renames
+}
+
+// renamePackages renames the packages referred to in src after renames, by
+// package name. The identifiers declared in src aren't renamed.
+func renamePackages(src []byte, renames map[string]string) ([]byte, error) {
+	if len(renames) == 0 {
+		return src, nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		// A package isn't declared in the file, so it's not resolved
+		if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+			if alias, ok := renames[id.Name]; ok {
+				id.Name = alias
+			}
+		}
+		return true
+	})
+	var b bytes.Buffer
+	if err = format.Node(&b, fset, f); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// goModule is the module path, the required modules and the replaced
+// modules of a go.mod file.
+type goModule struct {
+	path     string
+	requires []string
+	replaces []string
+}
+
+// findGoMod returns the go.mod file of the directory dir, which may not
+// exist yet: the first one found in dir or its parents. It returns an empty
+// name if there is none.
+func findGoMod(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		name := filepath.Join(dir, "go.mod")
+		if _, err = os.Stat(name); err == nil {
+			return name, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// readGoMod reads the module path, the required modules and the replaced
+// modules of the go.mod file name. The directives may be factored in blocks
+// and the comments are ignored.
+func readGoMod(name string) (*goModule, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var (
+		m       = &goModule{}
+		scanner = bufio.NewScanner(f)
+		// The verb of the block the line is in, if any
+		block string
+	)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		verb, args := block, fields
+		if block == "" {
+			verb, args = fields[0], fields[1:]
+			if len(args) == 1 && args[0] == "(" {
+				block = verb
+				continue
+			}
+		} else if fields[0] == ")" {
+			block = ""
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		switch verb {
+		case "module":
+			m.path = unquote(args[0])
+		case "require":
+			m.requires = append(m.requires, unquote(args[0]))
+		case "replace":
+			m.replaces = append(m.replaces, unquote(args[0]))
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if m.path == "" {
+		return nil, fmt.Errorf("%s: no module directive", name)
+	}
+	return m, nil
+}
+
+func unquote(s string) string {
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// provides reports whether the package imp is in the module or in one of
+// the modules it requires or replaces. The standard library is always
+// provided.
+func (m *goModule) provides(imp string) bool {
+	first, _, _ := strings.Cut(imp, "/")
+	if !strings.Contains(first, ".") {
+		return true
+	}
+	modules := append([]string{m.path}, m.requires...)
+	for _, p := range append(modules, m.replaces...) {
+		if imp == p || strings.HasPrefix(imp, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// imports returns the packages outside of the standard library imported by
+// the generated code of s: the runtime module and the packages of the
+// external types.
+func (gw *GoWriter) imports(s *generator.StructInfo) ([]string, error) {
+	if gw.backend == BackendProto {
+		return nil, nil
+	}
+	c := &importCollector{imports: make(map[string]bool)}
+	if gw.backend == BackendRuntime {
+		c.imports[gw.runtimeModule] = true
+	}
+	if err := Walk(s, gw.registeredMember, c); err != nil {
+		return nil, err
+	}
+	imports := make([]string, 0, len(c.imports))
+	for imp := range c.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	return imports, nil
+}
+
+// checkImports returns an error if a package imported by the generated code
+// of s isn't provided by the go.mod of path. There's nothing to check if
+// path isn't in a module.
+func (gw *GoWriter) checkImports(path string, s *generator.StructInfo) error {
+	name, err := findGoMod(path)
+	if err != nil || name == "" {
+		return err
+	}
+	m, err := readGoMod(name)
+	if err != nil {
+		return err
+	}
+	imports, err := gw.imports(s)
+	if err != nil {
+		return err
+	}
+	for _, imp := range imports {
+		if !m.provides(imp) {
+			return fmt.Errorf("files.GoWriter: %s is imported by the generated code but isn't required by %s", imp, name)
+		}
+	}
+	return nil
+}
+
+// importCollector collects the packages of the external types.
+type importCollector struct {
+	imports map[string]bool
+}
+
+func (c *importCollector) add(t any) {
+	switch va := t.(type) {
+	case *generator.CustomType:
+		if va.External {
+			c.imports[va.ImportPath] = true
+			return
+		}
+		c.add(va.Type1)
+		c.add(va.Type2)
+	case *generator.FixedArray:
+		c.add(va.PrimaryType)
+	}
+}
+
+func (c *importCollector) EmitStruct(s *generator.StructInfo) error {
+	for _, m := range s.Order {
+		c.add(m.T)
+	}
+	return nil
+}
+
+func (c *importCollector) EmitEnum(*generator.Enum) error {
+	return nil
+}
+
+func (c *importCollector) EmitUnion(u *generator.Union) error {
+	for _, v := range u.Variants {
+		c.add(v.T)
+	}
+	return nil
+}
+
+func (c *importCollector) EmitPolymorphic(*generator.Polymorphic) error {
+	return nil
+}
+
+func (c *importCollector) Flush() error {
+	return nil
+}
@@ -0,0 +1,128 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cruffinoni/rimworld-editor/generator/paths"
+)
+
+func TestGoWriter_imports(t *testing.T) {
+	s, registered := generate(t, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<name>Tynan</name>
+		<skills><li>1</li><li>2</li></skills>
+	</pawn>
+</savegame>`)
+	write := func(t *testing.T, goMod string) (string, error) {
+		dir := filepath.Join(tempModule(t, goMod), "internal", "saves")
+		gw := NewGoWriter(registered, true, "",
+			WithRuntimeModule("example.com/fork/"),
+			WithPackageAlias(paths.CustomTypesPath, "xmltypes"),
+		)
+		return dir, gw.WriteGoFile(dir, s)
+	}
+
+	dir, err := write(t, `module example.com/app
+
+go 1.21
+
+require (
+	example.com/fork v1.2.0 // indirect
+	example.com/other v0.1.0
+)
+`)
+	noError(t, err)
+	pawn := readFile(t, dir, "pawn.go")
+	assert.Contains(t, pawn, `xmltypes "example.com/fork/xml/types"`)
+	assert.Contains(t, pawn, `"example.com/fork/xml/attributes"`)
+	assert.Contains(t, pawn, "Skills *xmltypes.Slice[int64] `xml:\"skills\"`")
+	assert.NotContains(t, pawn, paths.CodePackage)
+
+	t.Run("unresolved", func(t *testing.T) {
+		dir, err := write(t, "module example.com/app\n\nrequire example.com/other v0.1.0\n")
+		assert.ErrorContains(t, err, "example.com/fork")
+		_, err = os.Stat(dir)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("replaced runtime module", func(t *testing.T) {
+		_, err := write(t, `module example.com/app
+
+replace (
+	// A local copy of the fork
+	example.com/fork v1.2.0 => ../fork
+)
+`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("runtime module", func(t *testing.T) {
+		_, err := write(t, "module example.com/fork\n")
+		assert.NoError(t, err)
+	})
+
+	t.Run("default runtime module", func(t *testing.T) {
+		m, err := readGoMod(filepath.Join("..", "..", "go.mod"))
+		noError(t, err)
+		assert.Equal(t, m.path, defaultRuntimeModule)
+		// The generated code imports this module, so it builds with it
+		dir := moduleDir(t)
+		gw := NewGoWriter(registered, true, "", WithPackageAlias(paths.CustomTypesPath, "xmltypes"))
+		noError(t, gw.WriteGoFile(dir, s))
+		assert.Contains(t, readFile(t, dir, "pawn.go"), `xmltypes "`+paths.CodePackage+paths.CustomTypesPath+`"`)
+		output(t, goCommand(t, dir, "vet", "./..."))
+	})
+}
+
+func Test_goModule_provides(t *testing.T) {
+	m := &goModule{
+		path:     "example.com/app",
+		requires: []string{"example.com/fork"},
+		replaces: []string{"example.com/local"},
+	}
+	for imp, want := range map[string]bool{
+		"encoding/xml":               true,
+		"example.com/app/types":      true,
+		"example.com/fork":           true,
+		"example.com/fork/xml/types": true,
+		"example.com/forked/xml":     false,
+		"example.com/local/xml":      true,
+		"github.com/other/pkg":       false,
+	} {
+		assert.Equal(t, want, m.provides(imp), imp)
+	}
+}
+
+func Test_readGoMod(t *testing.T) {
+	dir := tempModule(t, `module "example.com/app" // the application
+
+go 1.21
+
+require (
+	// The runtime
+	example.com/fork v1.2.0 // indirect
+
+	example.com/other v0.1.0
+)
+
+require example.com/single v0.2.0
+
+exclude example.com/other v0.0.1
+
+replace example.com/local => ../local
+
+replace (
+	example.com/other v0.1.0 => example.com/other v0.1.1
+)
+`)
+	m, err := readGoMod(filepath.Join(dir, "go.mod"))
+	noError(t, err)
+	assert.Equal(t, "example.com/app", m.path)
+	assert.Equal(t, []string{"example.com/fork", "example.com/other", "example.com/single"}, m.requires)
+	assert.Equal(t, []string{"example.com/local", "example.com/other"}, m.replaces)
+}
@@ -0,0 +1,409 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/generator/paths"
+)
+
+// Layout is how the generated types are laid out in files and packages.
+type Layout int
+
+const (
+	// LayoutPerStruct writes each type in its own file, in a single
+	// package.
+	LayoutPerStruct Layout = iota
+	// LayoutSingleFile writes all the types in a single file named after
+	// the package.
+	LayoutSingleFile
+	// LayoutPerRoot writes the types of each child of the root elements in
+	// its own package, a subdirectory named after the child. The types of
+	// several children are in the shared package, the root elements in the
+	// package of the output directory. Each type is in its own file.
+	LayoutPerRoot
+)
+
+// Layouts are the layouts by name.
+var Layouts = map[string]Layout{
+	"struct": LayoutPerStruct,
+	"single": LayoutSingleFile,
+	"root":   LayoutPerRoot,
+}
+
+// sharedPackage is the name of the package of the types of several children
+// of the root elements with LayoutPerRoot.
+const sharedPackage = "shared"
+
+// WithLayout sets how the types are laid out in files and packages. It's
+// LayoutPerStruct by default.
+//
+// With LayoutPerRoot, the packages import each other by their path in the
+// module of the output directory, which must be in a module. A root element
+// can't be a type of one of the children, the packages would import each
+// other. The layouts other than LayoutPerStruct need a Go backend.
+func WithLayout(l Layout) WriterOption {
+	return func(gw *GoWriter) {
+		gw.layout = l
+	}
+}
+
+// exists reports whether the file fileName is already written. With
+// LayoutSingleFile, the files are only pending, they're not on the disk.
+func (gw *GoWriter) exists(fileName string) bool {
+	if gw.written[fileName] {
+		return true
+	}
+	if gw.layout == LayoutSingleFile {
+		return false
+	}
+	_, err := os.Stat(fileName)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// writeFile writes the code of b in the file fileName. With
+// LayoutSingleFile, it's kept until the emitter is flushed.
+func (gw *GoWriter) writeFile(fileName string, b *buffer) error {
+	if gw.written == nil {
+		gw.written = make(map[string]bool)
+	}
+	gw.written[fileName] = true
+	if gw.layout == LayoutSingleFile {
+		gw.pending = append(gw.pending, b)
+		return nil
+	}
+	src, err := gw.source(b)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, src, 0644)
+}
+
+// writeSingleFile writes the pending files in the file named after the
+// package in path.
+func (gw *GoWriter) writeSingleFile(path string) error {
+	buf := gw.newBuffer(path)
+	for _, b := range gw.pending {
+		buf.append(b)
+	}
+	gw.pending = nil
+	src, err := gw.source(buf)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, gw.packageName()+".go"), src, 0644)
+}
+
+// subpackage returns the path of the package of the directory dir relative
+// to the output directory, empty for the output directory itself.
+func (gw *GoWriter) subpackage(dir string) string {
+	if gw.layout != LayoutPerRoot {
+		return ""
+	}
+	rel, err := filepath.Rel(gw.outputDir, dir)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// packageOf returns the package name of the subpackage pkg.
+func (gw *GoWriter) packageOf(pkg string) string {
+	if pkg == "" {
+		return gw.packageName()
+	}
+	return pathpkg.Base(pkg)
+}
+
+// typeDir returns the directory of the type named name in path, created if
+// needed.
+func (gw *GoWriter) typeDir(path, name string) (string, error) {
+	pkg := gw.typePackages[strcase.ToCamel(name)]
+	if pkg == "" {
+		return path, nil
+	}
+	dir := filepath.Join(path, pkg)
+	return dir, os.MkdirAll(dir, os.ModePerm)
+}
+
+// packageImportPath returns the import path of the package of the directory
+// dir, which may not exist yet, from the go.mod of its module.
+func packageImportPath(dir string) (string, error) {
+	name, err := findGoMod(dir)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("files.GoWriter: %s isn't in a module, its packages can't import each other", dir)
+	}
+	m, err := readGoMod(name)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(filepath.Dir(name), abs)
+	if err != nil {
+		return "", err
+	}
+	return pathpkg.Join(m.path, filepath.ToSlash(rel)), nil
+}
+
+// reservedPackageName reports whether name can't be the name of a package
+// of the layout: a keyword, a predeclared identifier or a package imported
+// by the generated code.
+func (gw *GoWriter) reservedPackageName(name string) bool {
+	if token.IsKeyword(name) || types.Universe.Lookup(name) != nil {
+		return true
+	}
+	switch name {
+	case "xml", "embed", "testing":
+		return true
+	}
+	for _, p := range []string{
+		paths.EmbeddedTypePath, paths.CustomTypesPath, paths.MultipleTypesPath, paths.PrimaryTypesPath,
+		paths.XmlAttributes, paths.XmlInterface, paths.XmlSaver, paths.XmlFileSaver, paths.XmlUnmarshal, paths.FilePackage,
+	} {
+		if runtimePackageName(p) == name {
+			return true
+		}
+	}
+	for _, alias := range gw.packageAliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+// layoutPackageName returns the name of the package of the child element.
+// Only its letters and digits are kept, in lower case.
+func (gw *GoWriter) layoutPackageName(element string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, removeInnerKeyword(element))
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "p" + name
+	}
+	if gw.reservedPackageName(name) {
+		name += "pkg"
+	}
+	return name
+}
+
+// layoutPackages returns the subpackages of the types of root, the structure
+// returned by the generator, by type name. A type of a single child of the
+// root elements is in the package of the child, a type of several children
+// in the shared package. The other types are in the output directory. A
+// polymorphic type is in the package of its variants, which it declares
+// methods on.
+func (gw *GoWriter) layoutPackages(root *generator.StructInfo) (map[string]string, error) {
+	r := &typeRecorder{
+		backend: gw.backend,
+		refs:    make(map[string][]string),
+	}
+	if err := Walk(root, gw.registeredMember, r); err != nil {
+		return nil, err
+	}
+	var (
+		rootTypes = map[string]bool{strcase.ToCamel(root.Name): true}
+		// reached are the packages of the children reaching each type
+		reached = make(map[string]map[string]bool)
+		used    = make(map[string]bool)
+	)
+	mark := func(name, pkg string) {
+		if reached[name] == nil {
+			reached[name] = make(map[string]bool)
+		}
+		reached[name][pkg] = true
+	}
+	for _, m := range root.Members {
+		s, ok := m.T.(*generator.StructInfo)
+		if !ok {
+			continue
+		}
+		rootTypes[strcase.ToCamel(s.Name)] = true
+		best := gw.registeredMember[s.Name][0]
+		elements := elementNames(best)
+		for _, c := range best.Order {
+			pkg := gw.layoutPackageName(elements[c])
+			used[pkg] = true
+			namedTypes(c.T, func(name string) {
+				mark(name, pkg)
+			})
+		}
+	}
+	// The types referred to are reached by the same children
+	for changed := true; changed; {
+		changed = false
+		for name, pkgs := range reached {
+			for _, ref := range r.refs[name] {
+				for pkg := range pkgs {
+					if !reached[ref][pkg] {
+						mark(ref, pkg)
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	shared := uniqueName(sharedPackage, used)
+	packages := make(map[string]string, len(reached))
+	for name, pkgs := range reached {
+		children := make([]string, 0, len(pkgs))
+		for pkg := range pkgs {
+			children = append(children, pkg)
+		}
+		sort.Strings(children)
+		if rootTypes[name] {
+			return nil, fmt.Errorf("files.GoWriter: the root type %s is a type of %s, their packages would import each other", name, strings.Join(children, ", "))
+		}
+		if len(children) == 1 {
+			packages[name] = children[0]
+		} else {
+			packages[name] = shared
+		}
+	}
+	return packages, nil
+}
+
+// namedTypes calls add with the names of the declared types of t, through
+// its lists, maps and fixed arrays.
+func namedTypes(t any, add func(name string)) {
+	switch va := t.(type) {
+	case *generator.StructInfo:
+		add(strcase.ToCamel(va.Name))
+	case *generator.Enum:
+		add(strcase.ToCamel(va.Name))
+	case *generator.Union:
+		add(strcase.ToCamel(va.Name))
+	case *generator.Polymorphic:
+		add(strcase.ToCamel(va.Name))
+	case *generator.CustomType:
+		if !va.External {
+			namedTypes(va.Type1, add)
+			namedTypes(va.Type2, add)
+		}
+	case *generator.FixedArray:
+		namedTypes(va.PrimaryType, add)
+	}
+}
+
+// typeRecorder records the types referred to by each generated type. A
+// polymorphic type and its variants refer to each other, so they're in the
+// same package.
+type typeRecorder struct {
+	backend Backend
+	refs    map[string][]string
+}
+
+func (r *typeRecorder) refer(from string, t any) {
+	namedTypes(t, func(name string) {
+		r.refs[from] = append(r.refs[from], name)
+	})
+}
+
+func (r *typeRecorder) EmitStruct(s *generator.StructInfo) error {
+	name := strcase.ToCamel(s.Name)
+	for _, m := range s.Order {
+		r.refer(name, m.T)
+	}
+	return nil
+}
+
+func (r *typeRecorder) EmitEnum(*generator.Enum) error {
+	return nil
+}
+
+func (r *typeRecorder) EmitUnion(u *generator.Union) error {
+	if r.backend == BackendPlain {
+		return SkipReferences
+	}
+	name := strcase.ToCamel(u.Name)
+	for _, v := range u.Variants {
+		r.refer(name, v.T)
+	}
+	return nil
+}
+
+func (r *typeRecorder) EmitPolymorphic(p *generator.Polymorphic) error {
+	if r.backend == BackendPlain {
+		return SkipReferences
+	}
+	name := strcase.ToCamel(p.Name)
+	variants := make([]*generator.StructInfo, 0, len(p.Variants)+1)
+	for _, c := range p.Classes() {
+		variants = append(variants, p.Variants[c])
+	}
+	if p.Default != nil {
+		variants = append(variants, p.Default)
+	}
+	for _, v := range variants {
+		r.refer(name, v)
+		r.refs[strcase.ToCamel(v.Name)] = append(r.refs[strcase.ToCamel(v.Name)], name)
+	}
+	return nil
+}
+
+func (r *typeRecorder) Flush() error {
+	return nil
+}
+
+// qualify qualifies the types of src, the code of b, declared in another
+// package of the layout by the name of their package, and imports it.
+func (gw *GoWriter) qualify(b *buffer, src []byte) ([]byte, error) {
+	if len(gw.typePackages) == 0 {
+		return src, nil
+	}
+	var (
+		fset    = token.NewFileSet()
+		f, err  = parser.ParseFile(fset, "", src, parser.ParseComments)
+		foreign bool
+	)
+	if err != nil {
+		return nil, err
+	}
+	// The types of another package aren't declared in the file
+	for _, id := range f.Unresolved {
+		if pkg, ok := gw.typePackages[id.Name]; ok && pkg != b.pkg {
+			b.writeExternalImport(gw.importBase + "/" + pkg)
+			foreign = true
+		}
+	}
+	if !foreign {
+		return src, nil
+	}
+	src = b.bytes()
+	if f, err = parser.ParseFile(fset, "", src, parser.ParseComments); err != nil {
+		return nil, err
+	}
+	for _, id := range f.Unresolved {
+		if pkg, ok := gw.typePackages[id.Name]; ok && pkg != b.pkg {
+			id.Name = gw.packageOf(pkg) + "." + id.Name
+		}
+	}
+	var out bytes.Buffer
+	if err = format.Node(&out, fset, f); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoWriter_layout(t *testing.T) {
+	const document = `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<game>
+		<pawn><name>Tynan</name></pawn>
+		<tick>12</tick>
+	</game>
+	<world>
+		<pawn><name>Randy</name></pawn>
+		<type>tundra</type>
+	</world>
+</savegame>`
+
+	t.Run("single", func(t *testing.T) {
+		dir := moduleDir(t)
+		write(t, dir, document, WithLayout(LayoutSingleFile))
+		entries, err := os.ReadDir(dir)
+		noError(t, err)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, "generated.go", entries[0].Name())
+		}
+		generated := readFile(t, dir, "generated.go")
+		for _, decl := range []string{"type Savegame struct", "type Game struct", "type World struct", "type Pawn struct"} {
+			assert.Contains(t, generated, decl)
+		}
+		output(t, goCommand(t, dir, "vet", "./..."))
+	})
+
+	t.Run("root", func(t *testing.T) {
+		dir := moduleDir(t)
+		write(t, dir, document, WithLayout(LayoutPerRoot))
+		for _, name := range []string{"savegame.go", "game/game.go", "world/world.go", "shared/pawn.go"} {
+			_, err := os.Stat(filepath.Join(dir, name))
+			assert.NoError(t, err, name)
+		}
+		base, err := packageImportPath(dir)
+		noError(t, err)
+		savegame := readFile(t, dir, "savegame.go")
+		assert.Contains(t, savegame, `"`+base+`/game"`)
+		assert.Contains(t, savegame, "Game  *game.Game   `xml:\"game\"`")
+		game := readFile(t, dir, filepath.Join("game", "game.go"))
+		assert.Contains(t, game, "package game")
+		assert.Contains(t, game, `"`+base+`/shared"`)
+		assert.Contains(t, game, "*shared.Pawn")
+		output(t, goCommand(t, dir, "vet", "./..."))
+	})
+
+	t.Run("root outside of a module", func(t *testing.T) {
+		s, registered := generate(t, document)
+		gw := NewGoWriter(registered, true, "", WithLayout(LayoutPerRoot))
+		assert.ErrorContains(t, gw.WriteGoFile(t.TempDir(), s), "isn't in a module")
+	})
+
+	t.Run("proto", func(t *testing.T) {
+		s, registered := generate(t, document)
+		gw := NewGoWriter(registered, true, "", WithBackend(BackendProto), WithLayout(LayoutSingleFile))
+		assert.Error(t, gw.WriteGoFile(t.TempDir(), s))
+	})
+}
@@ -0,0 +1,539 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind is the kind of change made by a merge.
+type ChangeKind string
+
+const (
+	// ChangeFileAdded is a file written because it didn't exist.
+	ChangeFileAdded ChangeKind = "file_added"
+	// ChangeFileUpdated is a data file replaced because it changed, e.g. a
+	// sample of the round-trip test.
+	ChangeFileUpdated ChangeKind = "file_updated"
+	// ChangeFileSkipped is a file left untouched because it's not
+	// generated.
+	ChangeFileSkipped ChangeKind = "file_skipped"
+	// ChangeImportAdded is an import added to an existing file.
+	ChangeImportAdded ChangeKind = "import_added"
+	// ChangeDeclAdded is a declaration added to an existing file.
+	ChangeDeclAdded ChangeKind = "declaration_added"
+	// ChangeDeclKept is a declaration different from the generated one and
+	// kept as is.
+	ChangeDeclKept ChangeKind = "declaration_kept"
+	// ChangeFieldAdded is a field added to an existing structure.
+	ChangeFieldAdded ChangeKind = "field_added"
+	// ChangeFieldType is a field of an existing structure whose type has
+	// been updated.
+	ChangeFieldType ChangeKind = "field_type_changed"
+	// ChangeFieldTag is a field of an existing structure whose tag has been
+	// updated, e.g. after a renamed element or a new option.
+	ChangeFieldTag ChangeKind = "field_tag_changed"
+)
+
+// Change is a change made to the existing files by a merge, see WithMerge.
+type Change struct {
+	Kind ChangeKind
+	File string
+	// Decl is the name of the declaration changed, if any.
+	Decl string
+	// Field is the name of the field changed, if any.
+	Field string
+	// From and To are the types of a field whose type changed, or its tags
+	// if its tag changed, To the type of a field added.
+	From, To string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeFileAdded:
+		return c.File + ": added"
+	case ChangeFileUpdated:
+		return c.File + ": updated"
+	case ChangeFileSkipped:
+		return c.File + ": not generated, skipped"
+	case ChangeImportAdded:
+		return c.File + ": import " + c.Decl + " added"
+	case ChangeDeclAdded:
+		return c.File + ": " + c.Decl + " added"
+	case ChangeDeclKept:
+		return c.File + ": " + c.Decl + " differs from the generated one, kept"
+	case ChangeFieldAdded:
+		return c.File + ": field " + c.Decl + "." + c.Field + " " + c.To + " added"
+	case ChangeFieldType:
+		return c.File + ": field " + c.Decl + "." + c.Field + " changed from " + c.From + " to " + c.To
+	case ChangeFieldTag:
+		return c.File + ": tag of the field " + c.Decl + "." + c.Field + " changed from " + c.From + " to " + c.To
+	}
+	return c.File + ": " + string(c.Kind)
+}
+
+// WithMerge merges the generated code into the files already in the output
+// directory instead of skipping them. The new fields are added to the
+// existing structures and the types and tags of their fields are updated,
+// the missing declarations are added. Everything else is left as is: the
+// fields and the declarations which aren't generated anymore, the comments
+// and the files without the header of the generated files. The folder is
+// never deleted in this mode. See Changes for what has been changed.
+func WithMerge(enabled bool) WriterOption {
+	return func(gw *GoWriter) {
+		gw.merge = enabled
+	}
+}
+
+// Changes returns the changes made by the last merge.
+func (gw *GoWriter) Changes() []Change {
+	return gw.changes
+}
+
+// mergeInto calls write with a temporary directory and merges each file
+// written there into path.
+func (gw *GoWriter) mergeInto(path string, write func(dir string) error) error {
+	gw.changes = nil
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "xmlgen-merge")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err = write(tmp); err != nil {
+		return err
+	}
+	return gw.mergeDir(tmp, path, "")
+}
+
+// mergeDir merges the files of the directory src/dir into dst/dir. The Go
+// files are merged, the other ones are replaced.
+func (gw *GoWriter) mergeDir(src, dst, dir string) error {
+	if err := os.MkdirAll(filepath.Join(dst, dir), os.ModePerm); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(filepath.Join(src, dir))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := filepath.Join(dir, e.Name())
+		if e.IsDir() {
+			if err = gw.mergeDir(src, dst, name); err != nil {
+				return err
+			}
+			continue
+		}
+		generated, err := os.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			return err
+		}
+		fileName := filepath.Join(dst, name)
+		existing, err := os.ReadFile(fileName)
+		if errors.Is(err, os.ErrNotExist) {
+			if err = os.WriteFile(fileName, generated, 0644); err != nil {
+				return err
+			}
+			gw.changes = append(gw.changes, Change{Kind: ChangeFileAdded, File: name})
+			continue
+		} else if err != nil {
+			return err
+		}
+		if filepath.Ext(name) != ".go" {
+			if !bytes.Equal(existing, generated) {
+				if err = os.WriteFile(fileName, generated, 0644); err != nil {
+					return err
+				}
+				gw.changes = append(gw.changes, Change{Kind: ChangeFileUpdated, File: name})
+			}
+			continue
+		}
+		merged, changes, err := mergeSource(name, existing, generated)
+		if err != nil {
+			return fmt.Errorf("files.GoWriter: merge %s: %w", fileName, err)
+		}
+		gw.changes = append(gw.changes, changes...)
+		if !bytes.Equal(merged, existing) {
+			if err = os.WriteFile(fileName, merged, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// edit replaces the bytes [off, end) of a source by text.
+type edit struct {
+	off, end int
+	text     string
+}
+
+// sourceFile is a parsed Go file with its source.
+type sourceFile struct {
+	src  []byte
+	fset *token.FileSet
+	f    *ast.File
+}
+
+func parseSource(name string, src []byte) (*sourceFile, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceFile{src: src, fset: fset, f: f}, nil
+}
+
+func (sf *sourceFile) offset(p token.Pos) int {
+	return sf.fset.Position(p).Offset
+}
+
+// text returns the source of n, with its doc comment if it's given.
+func (sf *sourceFile) text(n ast.Node, doc *ast.CommentGroup) string {
+	start := n.Pos()
+	if doc != nil {
+		start = doc.Pos()
+	}
+	return string(sf.src[sf.offset(start):sf.offset(n.End())])
+}
+
+// mergeSource merges the generated source into the existing one of the file
+// name and returns the result. The existing source is returned as is if it's
+// not generated.
+func mergeSource(name string, existing, generated []byte) ([]byte, []Change, error) {
+	old, err := parseSource(name, existing)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ast.IsGenerated(old.f) {
+		return existing, []Change{{Kind: ChangeFileSkipped, File: name}}, nil
+	}
+	gen, err := parseSource(name, generated)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := &merger{name: name, old: old, gen: gen}
+	m.mergeImports()
+	m.mergeDecls()
+	if len(m.edits) == 0 {
+		return existing, m.changes, nil
+	}
+	// The edits are applied from the end so their offsets stay valid. The
+	// edits at the same offset are applied from the last one, so they end up
+	// in the order they have been made.
+	for i, j := 0, len(m.edits)-1; i < j; i, j = i+1, j-1 {
+		m.edits[i], m.edits[j] = m.edits[j], m.edits[i]
+	}
+	sort.SliceStable(m.edits, func(i, j int) bool {
+		return m.edits[i].off > m.edits[j].off
+	})
+	src := existing
+	for _, e := range m.edits {
+		src = append(src[:e.off:e.off], append([]byte(e.text), src[e.end:]...)...)
+	}
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	return formatted, m.changes, nil
+}
+
+type merger struct {
+	name     string
+	old, gen *sourceFile
+	edits    []edit
+	changes  []Change
+}
+
+func (m *merger) insert(off int, text string) {
+	m.edits = append(m.edits, edit{off: off, end: off, text: text})
+}
+
+// mergeImports adds the imports of the generated file missing from the
+// existing one.
+func (m *merger) mergeImports() {
+	paths := make(map[string]bool)
+	for _, imp := range m.old.f.Imports {
+		paths[imp.Path.Value] = true
+	}
+	var block *ast.GenDecl
+	for _, d := range m.old.f.Decls {
+		if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == token.IMPORT && gd.Lparen.IsValid() {
+			block = gd
+			break
+		}
+	}
+	for _, imp := range m.gen.f.Imports {
+		if paths[imp.Path.Value] {
+			continue
+		}
+		spec := m.gen.text(imp, nil)
+		if block != nil {
+			m.insert(m.old.offset(block.Rparen), "\t"+spec+"\n")
+		} else {
+			m.insert(m.old.offset(m.old.f.Name.End()), "\n\nimport "+spec)
+		}
+		m.changes = append(m.changes, Change{Kind: ChangeImportAdded, File: m.name, Decl: imp.Path.Value})
+	}
+}
+
+// declKey returns the keys identifying the declarations of d in a file.
+func declKey(d ast.Decl) []string {
+	switch va := d.(type) {
+	case *ast.FuncDecl:
+		if va.Recv == nil || len(va.Recv.List) == 0 {
+			return []string{"func " + va.Name.Name}
+		}
+		t := va.Recv.List[0].Type
+		if star, ok := t.(*ast.StarExpr); ok {
+			t = star.X
+		}
+		if ident, ok := t.(*ast.Ident); ok {
+			return []string{"func (" + ident.Name + ") " + va.Name.Name}
+		}
+		return []string{"func " + va.Name.Name}
+	case *ast.GenDecl:
+		var keys []string
+		for _, s := range va.Specs {
+			switch spec := s.(type) {
+			case *ast.TypeSpec:
+				keys = append(keys, "type "+spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range spec.Names {
+					keys = append(keys, va.Tok.String()+" "+n.Name)
+				}
+			}
+		}
+		return keys
+	}
+	return nil
+}
+
+// mergeDecls adds the declarations of the generated file missing from the
+// existing one and merges the structures declared in both.
+func (m *merger) mergeDecls() {
+	existing := make(map[string]ast.Decl)
+	for _, d := range m.old.f.Decls {
+		for _, k := range declKey(d) {
+			existing[k] = d
+		}
+	}
+	end := len(m.old.src)
+	for _, d := range m.gen.f.Decls {
+		switch va := d.(type) {
+		case *ast.FuncDecl:
+			key := declKey(va)[0]
+			o, ok := existing[key]
+			// The init functions can't be told apart, an existing one is
+			// enough
+			if !ok {
+				m.insert(end, "\n"+m.gen.text(va, va.Doc)+"\n")
+				m.changes = append(m.changes, Change{Kind: ChangeDeclAdded, File: m.name, Decl: key})
+			} else if va.Name.Name != "init" && m.old.text(o, nil) != m.gen.text(va, nil) {
+				m.changes = append(m.changes, Change{Kind: ChangeDeclKept, File: m.name, Decl: key})
+			}
+		case *ast.GenDecl:
+			if va.Tok == token.IMPORT {
+				continue
+			}
+			for _, s := range va.Specs {
+				m.mergeSpec(va, s, existing, end)
+			}
+		}
+	}
+}
+
+// mergeSpec adds the spec s of the generated declaration d if it's missing
+// from the existing file, or merges it with the existing one if it's a
+// structure.
+func (m *merger) mergeSpec(d *ast.GenDecl, s ast.Spec, existing map[string]ast.Decl, end int) {
+	switch spec := s.(type) {
+	case *ast.TypeSpec:
+		key := "type " + spec.Name.Name
+		o, ok := existing[key]
+		if !ok {
+			m.insert(end, "\n"+m.specText(d, spec, spec.Doc)+"\n")
+			m.changes = append(m.changes, Change{Kind: ChangeDeclAdded, File: m.name, Decl: key})
+			return
+		}
+		oldSpec := findTypeSpec(o, spec.Name.Name)
+		oldStruct, okOld := oldSpec.Type.(*ast.StructType)
+		genStruct, okGen := spec.Type.(*ast.StructType)
+		if okOld && okGen {
+			m.mergeStruct(spec.Name.Name, oldStruct, genStruct)
+		} else if m.old.text(oldSpec, nil) != m.gen.text(spec, nil) {
+			m.changes = append(m.changes, Change{Kind: ChangeDeclKept, File: m.name, Decl: key})
+		}
+	case *ast.ValueSpec:
+		for _, n := range spec.Names {
+			if _, ok := existing[d.Tok.String()+" "+n.Name]; ok {
+				continue
+			}
+			m.insert(end, "\n"+m.specText(d, spec, spec.Doc)+"\n")
+			m.changes = append(m.changes, Change{Kind: ChangeDeclAdded, File: m.name, Decl: d.Tok.String() + " " + n.Name})
+			break
+		}
+	}
+}
+
+// specText returns the source of spec as a declaration on its own.
+func (m *merger) specText(d *ast.GenDecl, spec ast.Spec, doc *ast.CommentGroup) string {
+	if doc == nil && len(d.Specs) == 1 {
+		doc = d.Doc
+	}
+	var sb strings.Builder
+	if doc != nil {
+		sb.WriteString(m.gen.text(doc, nil) + "\n")
+	}
+	sb.WriteString(d.Tok.String() + " " + m.gen.text(spec, nil))
+	return sb.String()
+}
+
+func findTypeSpec(d ast.Decl, name string) *ast.TypeSpec {
+	for _, s := range d.(*ast.GenDecl).Specs {
+		if spec, ok := s.(*ast.TypeSpec); ok && spec.Name.Name == name {
+			return spec
+		}
+	}
+	return nil
+}
+
+// mergeStruct adds the named fields of gen missing from old at the end of
+// old, and updates the type and the tag of the fields of old which differ.
+// Only the keys of the generated tag are updated, the other keys written by
+// hand are kept. A tag is kept if the generated field has none.
+func (m *merger) mergeStruct(name string, old, gen *ast.StructType) {
+	fields := make(map[string]*ast.Field)
+	for _, f := range old.Fields.List {
+		for _, n := range f.Names {
+			fields[n.Name] = f
+		}
+	}
+	for _, f := range gen.Fields.List {
+		if len(f.Names) == 0 {
+			continue
+		}
+		to := m.gen.text(f.Type, nil)
+		o, ok := fields[f.Names[0].Name]
+		if !ok {
+			m.insert(m.old.offset(old.Fields.Closing), "\t"+m.gen.text(f, nil)+"\n")
+			m.changes = append(m.changes, Change{Kind: ChangeFieldAdded, File: m.name, Decl: name, Field: f.Names[0].Name, To: to})
+			continue
+		}
+		if from := m.old.text(o.Type, nil); from != to {
+			m.edits = append(m.edits, edit{off: m.old.offset(o.Type.Pos()), end: m.old.offset(o.Type.End()), text: to})
+			m.changes = append(m.changes, Change{Kind: ChangeFieldType, File: m.name, Decl: name, Field: f.Names[0].Name, From: from, To: to})
+		}
+		if f.Tag == nil {
+			continue
+		}
+		if o.Tag == nil {
+			m.insert(m.old.offset(o.Type.End()), " "+f.Tag.Value)
+			m.changes = append(m.changes, Change{Kind: ChangeFieldTag, File: m.name, Decl: name, Field: f.Names[0].Name, To: f.Tag.Value})
+			continue
+		}
+		if tag, changed := mergeTag(o.Tag.Value, f.Tag.Value); changed {
+			m.edits = append(m.edits, edit{off: m.old.offset(o.Tag.Pos()), end: m.old.offset(o.Tag.End()), text: tag})
+			m.changes = append(m.changes, Change{Kind: ChangeFieldTag, File: m.name, Decl: name, Field: f.Names[0].Name, From: o.Tag.Value, To: tag})
+		}
+	}
+}
+
+// tagPair is a key and its quoted value in a struct tag.
+type tagPair struct {
+	key, value string
+}
+
+// parseTag returns the pairs of the conventional struct tag whose literal in
+// the source is literal, in their order. ok is false if the tag isn't
+// conventional.
+func parseTag(literal string) (pairs []tagPair, ok bool) {
+	tag, err := strconv.Unquote(literal)
+	if err != nil {
+		return nil, false
+	}
+	// The same scanning as reflect.StructTag.Lookup
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		if tag = tag[i:]; tag == "" {
+			break
+		}
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return nil, false
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, false
+		}
+		pairs = append(pairs, tagPair{key: key, value: tag[:i+1]})
+		tag = tag[i+1:]
+	}
+	return pairs, true
+}
+
+// mergeTag returns the literal of the tag old whose keys are given the
+// values they have in gen, the keys of gen it hasn't being added at the end,
+// and whether it changed. The other keys of old are kept in their order. gen
+// replaces old if one of them isn't conventional.
+func mergeTag(old, gen string) (string, bool) {
+	oldPairs, okOld := parseTag(old)
+	genPairs, okGen := parseTag(gen)
+	if !okOld || !okGen {
+		return gen, old != gen
+	}
+	changed := false
+	for _, g := range genPairs {
+		found := false
+		for i, o := range oldPairs {
+			if o.key == g.key {
+				found = true
+				if o.value != g.value {
+					oldPairs[i].value = g.value
+					changed = true
+				}
+			}
+		}
+		if !found {
+			oldPairs = append(oldPairs, g)
+			changed = true
+		}
+	}
+	if !changed {
+		return old, false
+	}
+	pairs := make([]string, 0, len(oldPairs))
+	for _, p := range oldPairs {
+		pairs = append(pairs, p.key+":"+p.value)
+	}
+	tag := strings.Join(pairs, " ")
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag), true
+	}
+	return "`" + tag + "`", true
+}
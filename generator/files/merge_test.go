@@ -0,0 +1,148 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mergeSource(t *testing.T) {
+	const existing = `// Code generated by rimworld-editor. DO NOT EDIT.
+
+package generated
+
+import (
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+type Pawn struct {
+	Attr map[string]string
+
+	// Age is hand-written documentation
+	Age  int64  ` + "`xml:\"age\"`" + `
+	Name string ` + "`xml:\"name\" json:\"name\"`" + `
+	Title string
+	Legacy bool
+}
+
+func (p *Pawn) Assign(*xml.Element) error {
+	return nil
+}
+
+// Greet is a hand-written method
+func (p *Pawn) Greet() string {
+	return "Hello " + p.Name
+}
+`
+	const generated = `// Code generated by rimworld-editor. DO NOT EDIT.
+
+package generated
+
+import (
+	"github.com/cruffinoni/rimworld-editor/xml"
+	"github.com/cruffinoni/rimworld-editor/xml/types"
+)
+
+type Pawn struct {
+	Attr map[string]string
+
+	Age    float64                ` + "`xml:\"age\"`" + `
+	Name   string                 ` + "`xml:\"name,omitempty\"`" + `
+	Title  string                 ` + "`xml:\"title\"`" + `
+	Skills *types.Slice[*Skill]   ` + "`xml:\"skills\"`" + `
+	Traits *types.Slice[string]   ` + "`xml:\"traits\"`" + `
+}
+
+// Skill is generated
+type Skill struct {
+	Level int64 ` + "`xml:\"level\"`" + `
+}
+
+func (p *Pawn) Assign(e *xml.Element) error {
+	return nil
+}
+`
+	merged, changes, err := mergeSource("pawn.go", []byte(existing), []byte(generated))
+	noError(t, err)
+	got := string(merged)
+	assert.Contains(t, got, "// Age is hand-written documentation\n\tAge    float64")
+	// The keys written by hand are kept
+	assert.Contains(t, got, "Name   string  `xml:\"name,omitempty\" json:\"name\"`")
+	assert.Contains(t, got, "Title  string  `xml:\"title\"`")
+	assert.Contains(t, got, "Legacy bool")
+	assert.Regexp(t, `(?s)Skills \*types\.Slice\[\*Skill\].*Traits \*types\.Slice\[string\]`, got)
+	assert.Contains(t, got, "\"github.com/cruffinoni/rimworld-editor/xml/types\"")
+	assert.Contains(t, got, "// Skill is generated\ntype Skill struct")
+	assert.Contains(t, got, "// Greet is a hand-written method")
+	assert.Contains(t, got, "func (p *Pawn) Assign(*xml.Element) error")
+	assert.Equal(t, []Change{
+		{Kind: ChangeImportAdded, File: "pawn.go", Decl: `"github.com/cruffinoni/rimworld-editor/xml/types"`},
+		{Kind: ChangeFieldType, File: "pawn.go", Decl: "Pawn", Field: "Age", From: "int64", To: "float64"},
+		{Kind: ChangeFieldTag, File: "pawn.go", Decl: "Pawn", Field: "Name", From: "`xml:\"name\" json:\"name\"`", To: "`xml:\"name,omitempty\" json:\"name\"`"},
+		{Kind: ChangeFieldTag, File: "pawn.go", Decl: "Pawn", Field: "Title", To: "`xml:\"title\"`"},
+		{Kind: ChangeFieldAdded, File: "pawn.go", Decl: "Pawn", Field: "Skills", To: "*types.Slice[*Skill]"},
+		{Kind: ChangeFieldAdded, File: "pawn.go", Decl: "Pawn", Field: "Traits", To: "*types.Slice[string]"},
+		{Kind: ChangeDeclAdded, File: "pawn.go", Decl: "type Skill"},
+		{Kind: ChangeDeclKept, File: "pawn.go", Decl: "func (Pawn) Assign"},
+	}, changes)
+
+	// Merging again changes nothing
+	again, changes, err := mergeSource("pawn.go", merged, []byte(generated))
+	noError(t, err)
+	assert.Equal(t, got, string(again))
+	assert.Equal(t, []Change{{Kind: ChangeDeclKept, File: "pawn.go", Decl: "func (Pawn) Assign"}}, changes)
+
+	userOwned := "package generated\n\ntype Pawn struct{}\n"
+	kept, changes, err := mergeSource("pawn.go", []byte(userOwned), []byte(generated))
+	noError(t, err)
+	assert.Equal(t, userOwned, string(kept))
+	assert.Equal(t, []Change{{Kind: ChangeFileSkipped, File: "pawn.go"}}, changes)
+}
+
+func Test_mergeTag(t *testing.T) {
+	for _, c := range []struct {
+		old, gen, want string
+		changed        bool
+	}{
+		{"`xml:\"age\" json:\"age\"`", "`xml:\"age\"`", "`xml:\"age\" json:\"age\"`", false},
+		{"`json:\"age\"  xml:\"age\"`", "`xml:\"age,omitempty\"`", "`json:\"age\" xml:\"age,omitempty\"`", true},
+		{"`json:\"age\"`", "`xml:\"age\"`", "`json:\"age\" xml:\"age\"`", true},
+		{"`not a tag`", "`xml:\"age\"`", "`xml:\"age\"`", true},
+	} {
+		got, changed := mergeTag(c.old, c.gen)
+		assert.Equal(t, c.want, got, c.old)
+		assert.Equal(t, c.changed, changed, c.old)
+	}
+}
+
+func TestGoWriter_merge(t *testing.T) {
+	dir := moduleDir(t)
+	write(t, dir, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<name>Tynan</name>
+	</pawn>
+</savegame>`)
+	handWritten := "\nfunc (p *Pawn) Greet() string {\n\treturn \"Hello \" + p.Name\n}\n"
+	noError(t, os.WriteFile(filepath.Join(dir, "pawn.go"), []byte(readFile(t, dir, "pawn.go")+handWritten), 0644))
+	userFile := filepath.Join(dir, "helpers.go")
+	noError(t, os.WriteFile(userFile, []byte("package generated\n"), 0644))
+
+	gw := write(t, dir, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<name>Randy</name>
+		<age>3</age>
+	</pawn>
+</savegame>`, WithMerge(true))
+	pawn := readFile(t, dir, "pawn.go")
+	assert.Contains(t, pawn, "Age  int64  `xml:\"age\"`")
+	assert.Contains(t, pawn, handWritten)
+	_, err := os.Stat(userFile)
+	assert.NoError(t, err)
+	assert.Contains(t, gw.Changes(), Change{Kind: ChangeFieldAdded, File: "pawn.go", Decl: "Pawn", Field: "Age", To: "int64"})
+	// The merged code still builds
+	output(t, goCommand(t, dir, "vet", "./..."))
+}
@@ -0,0 +1,359 @@
+package files
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/xml/dialect"
+)
+
+// Backend is the library reading and writing the generated types.
+type Backend int
+
+const (
+	// BackendRuntime generates the types read by the unmarshal package and
+	// saved by the saver of this project.
+	BackendRuntime Backend = iota
+	// BackendPlain generates ordinary structures read and written by
+	// encoding/xml, which don't depend on this project.
+	BackendPlain
+	// BackendProto generates the Protocol Buffers messages of the types
+	// instead of Go code.
+	BackendProto
+)
+
+// Backends are the backends by name.
+var Backends = map[string]Backend{
+	"runtime": BackendRuntime,
+	"plain":   BackendPlain,
+	"proto":   BackendProto,
+}
+
+// WithBackend sets the library reading and writing the generated types.
+// It's BackendRuntime by default.
+//
+// With BackendPlain, the lists are slices of their items, tagged with the
+// path to the items, and the structures are pointers. The optional lists are
+// pointers to an XMLList, nil when the element is missing. The lists whose
+// items aren't written with the list tag have their own XMLList type, named
+// after the tag of their items. The untyped
+// attributes are kept in an Attr field tagged ",any,attr". The maps, the
+// elements holding data and attributes and the empty elements are generic
+// structures of the xml_types.go file. The types which can't be chosen by
+// encoding/xml, the unions and the polymorphic items, are kept as raw XML
+// in an XMLAny. The fixed arrays are slices, their empty items are zero
+// values. The optional members are omitted when they're zero, or nil with
+// OptionalPointer. The structure of a root element names its element, so it's
+// unmarshalled and marshalled on its own.
+//
+// BackendPlain can't generate the round-trip test nor the presence
+// accessors, which need the runtime.
+//
+// BackendProto writes the messages in a single proto3 file named after the
+// package. The map of the untyped attributes is the field 1 of every
+// message, the other fields are numbered from 2 in the order of the members.
+// The numbers aren't kept across runs: a member found by a new sample
+// renumbers the fields following it, which breaks the compatibility with the
+// messages encoded with the previous file. The lists
+// are repeated fields, a list held by a list or a map is wrapped in a
+// message. The maps whose keys are scalars are map<> fields, the other ones
+// are repeated entry messages holding a key and a value. The elements
+// holding data and attributes are XMLText sub-messages, the unions and the
+// polymorphic items hold one of their variants. The optional scalars are
+// optional fields, unless the optional members aren't marked. It can't
+// generate the round-trip test nor merge the files.
+func WithBackend(b Backend) WriterOption {
+	return func(gw *GoWriter) {
+		gw.backend = b
+	}
+}
+
+// WithDialect sets the dialect of the documents, which gives the tags of the
+// list items and of the keys and values of the maps in the tags written by
+// BackendPlain. It's dialect.Default by default.
+func WithDialect(d *dialect.Dialect) WriterOption {
+	return func(gw *GoWriter) {
+		gw.dialect = dialect.Or(d)
+	}
+}
+
+// plainTypesFile is the name of the file of the generic structures used by
+// BackendPlain.
+const plainTypesFile = "xml_types.go"
+
+// checkBackend returns an error if an option of gw isn't supported by its
+// backend.
+func (gw *GoWriter) checkBackend() error {
+	switch {
+	case gw.backend == BackendRuntime:
+		return nil
+	case gw.backend == BackendPlain && gw.optionalStyle == OptionalAccessor:
+		return errors.New("files.GoWriter: the presence accessors need the runtime backend")
+	case len(gw.roundTripSamples) > 0:
+		return errors.New("files.GoWriter: the round-trip test needs the runtime backend")
+	case gw.backend == BackendProto && gw.merge:
+		return errors.New("files.GoWriter: the merge needs a Go backend")
+	case gw.backend == BackendProto && gw.layout != LayoutPerStruct:
+		return errors.New("files.GoWriter: the layouts need a Go backend")
+	}
+	return nil
+}
+
+// rootElements returns the element names of the structures of the root
+// elements, by structure name. root is the structure returned by the
+// generator.
+func (gw *GoWriter) rootElements(root *generator.StructInfo) map[string]string {
+	elements := make(map[string]string, len(root.Members))
+	for name, m := range root.Members {
+		if s, ok := m.T.(*generator.StructInfo); ok {
+			elements[s.Name] = removeInnerKeyword(name)
+		}
+	}
+	return elements
+}
+
+// writePlainTypes writes the generic structures used by BackendPlain in
+// path, once.
+func (gw *GoWriter) writePlainTypes(path string) error {
+	fileName := path + "/" + plainTypesFile
+	if gw.exists(fileName) {
+		return nil
+	}
+	buf := gw.newBuffer(path)
+	buf.writeExternalImport("encoding/xml")
+	var (
+		item   = gw.dialect.ListTag()
+		keys   = gw.dialect.MapKeys + ">" + item
+		values = gw.dialect.MapValues + ">" + item
+	)
+	var lists strings.Builder
+	tags := make([]string, 0, len(gw.plainLists))
+	for tag := range gw.plainLists {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		name := gw.plainLists[tag]
+		lists.WriteString("\n// " + name + " is a list whose items are written with the tag " + tag + ".\n" +
+			"type " + name + "[T any] struct {\n" +
+			"\tItems []T `xml:\"" + tag + "\"`\n}\n")
+	}
+	buf.writeToBody("// XMLText is an element holding data of type T and attributes.\n" +
+		"type XMLText[T any] struct {\n" +
+		"\tValue T `xml:\",chardata\"`\n" +
+		"\tAttr []xml.Attr `xml:\",any,attr\"`\n}\n\n" +
+		"// XMLEmpty is an element without data nor children, which may have\n" +
+		"// attributes.\n" +
+		"type XMLEmpty struct {\n" +
+		"\tAttr []xml.Attr `xml:\",any,attr\"`\n}\n\n" +
+		"// XMLAny is an element whose type can't be chosen by encoding/xml, kept as\n" +
+		"// is.\n" +
+		"type XMLAny struct {\n" +
+		"\tXMLName xml.Name\n" +
+		"\tAttr []xml.Attr `xml:\",any,attr\"`\n" +
+		"\tContent []byte `xml:\",innerxml\"`\n}\n\n" +
+		"// XMLList is an optional list, or a list held by a list or a map.\n" +
+		"type XMLList[T any] struct {\n" +
+		"\tItems []T `xml:\"" + item + "\"`\n}\n\n" +
+		"// XMLMap is a map, its keys and values are paired by position.\n" +
+		"type XMLMap[K, V any] struct {\n" +
+		"\tKeys []K `xml:\"" + keys + "\"`\n" +
+		"\tValues []V `xml:\"" + values + "\"`\n}\n" +
+		lists.String())
+	return gw.writeFile(fileName, buf)
+}
+
+// plainKind returns the Go type of the data of kind k. The hexadecimal
+// numbers are kept as strings.
+func plainKind(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool, reflect.Int64, reflect.Float64:
+		return k.String()
+	}
+	return "string"
+}
+
+// isList reports whether the elements of type t hold list items.
+func isList(t any) bool {
+	switch va := t.(type) {
+	case *generator.FixedArray:
+		return true
+	case *generator.CustomType:
+		return generator.IsSliceType(va)
+	}
+	return false
+}
+
+// itemTag returns the tag of the items of the list t.
+func (gw *GoWriter) itemTag(t any) string {
+	var tag string
+	switch va := t.(type) {
+	case *generator.FixedArray:
+		tag = va.ItemTag
+	case *generator.CustomType:
+		tag = va.ItemTag
+	}
+	if tag == "" {
+		return gw.dialect.ListTag()
+	}
+	return tag
+}
+
+// plainListTypes returns the names of the XMLList types of the lists whose
+// items aren't written with the list tag, by item tag.
+func (gw *GoWriter) plainListTypes() map[string]string {
+	var (
+		lists = make(map[string]string)
+		names = map[string]bool{"XMLList": true}
+		seen  = make(map[any]bool)
+		visit func(t any)
+	)
+	visit = func(t any) {
+		switch va := t.(type) {
+		case *generator.FixedArray:
+			if va.ItemTag != "" {
+				lists[va.ItemTag] = ""
+			}
+			visit(va.PrimaryType)
+		case *generator.CustomType:
+			if va.External {
+				return
+			}
+			if va.ItemTag != "" {
+				lists[va.ItemTag] = ""
+			}
+			visit(va.Type1)
+			visit(va.Type2)
+		case *generator.StructInfo:
+			if !seen[va] {
+				seen[va] = true
+				for _, m := range va.Order {
+					visit(m.T)
+				}
+			}
+		}
+	}
+	for _, name := range gw.registeredMember.Names() {
+		visit(gw.registeredMember[name][0])
+	}
+	tags := make([]string, 0, len(lists))
+	for tag := range lists {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		lists[tag] = uniqueName("XMLList"+strcase.ToCamel(tag), names)
+	}
+	return lists
+}
+
+// listType returns the name of the XMLList type of the list t.
+func (gw *GoWriter) listType(t any) string {
+	if name, ok := gw.plainLists[gw.itemTag(t)]; ok {
+		return name
+	}
+	return "XMLList"
+}
+
+// plainType returns the Go type of a member of type t for BackendPlain. The
+// lists are the slices of their items.
+func (gw *GoWriter) plainType(t any, b *buffer) string {
+	switch va := t.(type) {
+	case reflect.Kind:
+		return plainKind(va)
+	case *generator.StructInfo:
+		return "*" + strcase.ToCamel(va.Name)
+	case *generator.Enum:
+		return strcase.ToCamel(va.Name)
+	case *generator.FixedArray:
+		return "[]" + gw.plainItemType(va.PrimaryType, b)
+	case *generator.CustomType:
+		switch {
+		case va.External:
+			b.writeExternalImport(va.ImportPath)
+			return va.Pkg + "." + va.Name
+		case generator.IsEmptyType(va):
+			return "*XMLEmpty"
+		case generator.IsEmbeddedType(va):
+			return "*XMLText[" + plainKind(kindOf(va.Type1)) + "]"
+		case generator.IsSliceType(va):
+			return "[]" + gw.plainItemType(va.Type1, b)
+		case va.Name == "Map":
+			return "*XMLMap[" + gw.plainItemType(va.Type1, b) + ", " + gw.plainItemType(va.Type2, b) + "]"
+		}
+	}
+	// The unions, the polymorphic items and the *xml.Element
+	return "*XMLAny"
+}
+
+// plainItemType returns the Go type of the items of type t of a list or a
+// map. A list held by a list is an XMLList.
+func (gw *GoWriter) plainItemType(t any, b *buffer) string {
+	s := gw.plainType(t, b)
+	if isList(t) {
+		s = gw.listType(t) + "[" + s[len("[]"):] + "]"
+	}
+	return s
+}
+
+// kindOf returns the kind of t, reflect.String if it's not a kind.
+func kindOf(t any) reflect.Kind {
+	if k, ok := t.(reflect.Kind); ok {
+		return k
+	}
+	return reflect.String
+}
+
+// generatePlainStructToPath writes s as an ordinary structure for
+// BackendPlain in its own file, see WithBackend.
+func (gw *GoWriter) generatePlainStructToPath(path string, s *generator.StructInfo) error {
+	fileName := path + "/" + gw.fileName(s) + ".go"
+	if gw.exists(fileName) {
+		return nil
+	}
+	if err := gw.writePlainTypes(path); err != nil {
+		return err
+	}
+	buf := gw.newBuffer(path)
+	structName := strcase.ToCamel(s.Name)
+	best := gw.registeredMember[s.Name][0]
+	buf.writeExternalImport("encoding/xml")
+	buf.writeToBody("type " + structName + " struct {\n")
+	if element, ok := gw.rootElementNames[s.Name]; ok {
+		buf.writeToBody("XMLName xml.Name `xml:\"" + element + "\"`\n")
+	}
+	buf.writeToBody("Attr []xml.Attr `xml:\",any,attr\"`\n\n")
+	namesRegistered := map[string]bool{"XMLName": true}
+	collisions := make(map[string]int)
+	for _, m := range best.Order {
+		element := nameField(m, namesRegistered, collisions)
+		t := gw.plainType(m.T, buf)
+		optional := best.IsOptional(m)
+		if _, ok := m.T.(reflect.Kind); ok && optional && gw.optionalStyle == OptionalPointer {
+			t = "*" + t
+		}
+		tag := removeInnerKeyword(element)
+		if isList(m.T) {
+			// The parent of a path isn't omitted, so the optional lists
+			// are wrapped
+			if optional {
+				t = "*" + gw.plainItemType(m.T, buf)
+			} else {
+				tag += ">" + gw.itemTag(m.T)
+			}
+		}
+		// The optional members missing from a document aren't written back
+		if optional {
+			tag += ",omitempty"
+		}
+		buf.writeToBody("\t" + m.Name + " " + t + " `xml:\"" + tag + "\"`\n")
+	}
+	writeAttributeFields(buf, best, namesRegistered)
+	buf.writeToFooter("}\n")
+	return gw.writeFile(fileName, buf)
+}
@@ -0,0 +1,144 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+)
+
+const plainDocument = `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<game>
+		<tickCount>1500</tickCount>
+		<pawns>
+			<li>
+				<name>Tynan</name>
+				<gender>Male</gender>
+				<label lang="en">hello</label>
+				<skills><li>1</li><li>2</li></skills>
+				<weights>
+					<keys><li>a</li></keys>
+					<values><li>1.5</li></values>
+				</weights>
+				<dead />
+				<traits><li>Tough</li></traits>
+				<apparel>
+					<li Class="Hat"><def>Cap</def></li>
+					<li Class="Vest"><def>Kevlar</def><armor>2</armor></li>
+				</apparel>
+			</li>
+			<li>
+				<name>Randy</name>
+				<gender>Male</gender>
+				<age>3</age>
+				<label>bye</label>
+				<skills><li>3</li></skills>
+				<weights>
+					<keys><li>b</li></keys>
+					<values><li>2</li></values>
+				</weights>
+				<dead />
+			</li>
+		</pawns>
+		<things>
+			<thing><def>Hat</def></thing>
+			<thing><def>Gun</def></thing>
+		</things>
+	</game>
+</savegame>`
+
+// plainProgram decodes the document given on its standard input with the
+// types of the saves package, encodes it back and checks that decoding the
+// result gives the same values.
+const plainProgram = `package main
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"reflect"
+
+	"example.com/plain/saves"
+)
+
+func main() {
+	document, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		panic(err)
+	}
+	var decoded, again saves.Savegame
+	if err = xml.Unmarshal(document, &decoded); err != nil {
+		panic(err)
+	}
+	encoded, err := xml.Marshal(&decoded)
+	if err != nil {
+		panic(err)
+	}
+	if err = xml.Unmarshal(encoded, &again); err != nil {
+		panic(err)
+	}
+	if !reflect.DeepEqual(decoded, again) {
+		panic("the values changed once encoded")
+	}
+	os.Stdout.Write(encoded)
+}
+`
+
+func TestGoWriter_plain(t *testing.T) {
+	for name, opts := range map[string][]generator.Option{
+		"untyped": nil,
+		"typed": {
+			generator.WithAttributes(true),
+			generator.WithEnums(2),
+			generator.WithClassPolymorphism(true),
+			generator.WithUnions(true),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, registered := generate(t, plainDocument, opts...)
+			root := tempModule(t, "module example.com/plain\n\ngo 1.21\n")
+			noError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte(plainProgram), 0644))
+			dir := filepath.Join(root, "saves")
+			gw := NewGoWriter(registered, true, "saves", WithBackend(BackendPlain), WithOptionalStyle(OptionalPointer))
+			noError(t, gw.WriteGoFile(dir, s))
+			assert.NotContains(t, readFile(t, dir, "savegame.go"), "github.com")
+
+			cmd := goCommand(t, root, "run", ".")
+			cmd.Stdin = strings.NewReader(plainDocument)
+			encoded := output(t, cmd)
+			for _, want := range []string{
+				`<savegame><game><tickCount>1500</tickCount>`,
+				`<gender>Male</gender>`,
+				`<label lang="en">hello</label>`,
+				`<skills><li>1</li><li>2</li></skills>`,
+				`<weights><keys><li>a</li></keys><values><li>1.5</li></values></weights>`,
+				`<age>3</age>`,
+				`<traits><li>Tough</li></traits>`,
+				`<apparel><li Class="Hat"><def>Cap</def></li><li Class="Vest"><def>Kevlar</def><armor>2</armor></li></apparel>`,
+				`<things><thing><def>Hat</def></thing><thing><def>Gun</def></thing></things>`,
+			} {
+				assert.Contains(t, encoded, want)
+			}
+			// The optional members missing from the document aren't written
+			assert.Equal(t, 1, strings.Count(encoded, "<age>"))
+			assert.Equal(t, 1, strings.Count(encoded, "<traits>"))
+			assert.Equal(t, 1, strings.Count(encoded, "<apparel>"))
+		})
+	}
+
+	t.Run("runtime options", func(t *testing.T) {
+		s, registered := generate(t, plainDocument)
+		for _, opt := range []WriterOption{
+			WithOptionalStyle(OptionalAccessor),
+			WithRoundTripTest(Sample{Name: "sample.xml", Root: "savegame", Content: []byte(plainDocument)}),
+		} {
+			gw := NewGoWriter(registered, true, "", WithBackend(BackendPlain), opt)
+			assert.Error(t, gw.WriteGoFile(t.TempDir(), s))
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package files
+
+import (
+	"strconv"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/generator/paths"
+)
+
+// generatePolymorphicToPath writes the interface of p, the Class method of
+// its variants and the factory choosing the variant from the Class attribute
+// during the unmarshalling.
+func (gw *GoWriter) generatePolymorphicToPath(path string, p *generator.Polymorphic) error {
+	fileName := path + "/" + strcase.ToSnake(p.Name) + ".go"
+	if gw.exists(fileName) {
+		return nil
+	}
+	buf := gw.newBuffer(path)
+	buf.writeImport(paths.XmlInterface)
+	buf.writeImport(paths.XmlUnmarshal)
+	interfaceName := strcase.ToCamel(p.Name)
+	buf.writeToBody("// " + interfaceName + " is implemented by the structures of the items, chosen by\n" +
+		"// their Class attribute.\n" +
+		"type " + interfaceName + " interface {\n" +
+		"\t_interface.Assigner\n" +
+		"\t_interface.Classifier\n}\n")
+
+	classes := p.Classes()
+	buf.writeToBody("\nfunc init() {\n" +
+		"\tunmarshal.RegisterClasses[" + interfaceName + "](func(class string) _interface.Assigner {\n" +
+		"\t\tswitch class {\n")
+	for _, c := range classes {
+		v := p.Variants[c]
+		buf.writeToBody("\t\tcase " + strconv.Quote(c) + ":\n" +
+			"\t\t\treturn &" + strcase.ToCamel(v.Name) + "{}\n")
+		buf.writeToFooter("\n// Class returns the class of " + strcase.ToCamel(v.Name) + ".\n" +
+			"func (*" + strcase.ToCamel(v.Name) + ") Class() string {\n" +
+			"\treturn " + strconv.Quote(c) + "\n}\n")
+	}
+	if p.Default != nil {
+		buf.writeToBody("\t\tcase \"\":\n" +
+			"\t\t\treturn &" + strcase.ToCamel(p.Default.Name) + "{}\n")
+		buf.writeToFooter("\n// Class returns an empty class, " + strcase.ToCamel(p.Default.Name) + " holds the items without class.\n" +
+			"func (*" + strcase.ToCamel(p.Default.Name) + ") Class() string {\n" +
+			"\treturn \"\"\n}\n")
+	}
+	buf.writeToBody("\t\t}\n\t\treturn nil\n\t})\n}\n")
+	return gw.writeFile(fileName, buf)
+}
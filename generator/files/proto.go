@@ -0,0 +1,310 @@
+package files
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+)
+
+// protoEmitter writes the types in a single .proto file, named after the
+// package, see BackendProto.
+type protoEmitter struct {
+	gw   *GoWriter
+	path string
+	// definitions are the messages and the enumerations, in the order they
+	// are emitted. The helper messages, written on demand, follow them.
+	definitions []string
+	helpers     []string
+	helperNames map[string]bool
+}
+
+func newProtoEmitter(gw *GoWriter, path string) *protoEmitter {
+	return &protoEmitter{
+		gw:          gw,
+		path:        path,
+		helperNames: make(map[string]bool),
+	}
+}
+
+// protoKind returns the type of the data of kind k. The hexadecimal numbers
+// are kept as strings.
+func protoKind(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool, reflect.Int64:
+		return k.String()
+	case reflect.Float64:
+		return "double"
+	}
+	return "string"
+}
+
+// protoKeyType returns the type of the keys of type t of a map, if they can
+// be the keys of a map<>.
+func protoKeyType(t any) (string, bool) {
+	switch va := t.(type) {
+	case reflect.Kind:
+		if va != reflect.Float64 {
+			return protoKind(va), true
+		}
+	case *generator.Enum:
+		// An enumeration can't be a key, its values are
+		return "string", true
+	}
+	return "", false
+}
+
+// protoFieldName returns the field name, in snake case, of the element or
+// attribute name. The characters which can't be in a name are underscores.
+func protoFieldName(name string) string {
+	var (
+		b     strings.Builder
+		runes = []rune(removeInnerKeyword(name))
+	)
+	for i, r := range runes {
+		if r >= unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			b.WriteByte('_')
+			continue
+		}
+		// A word starts at an upper case letter following a lower case
+		// letter or a digit, or ending an acronym
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+			unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	name = strings.Join(strings.FieldsFunc(b.String(), func(r rune) bool {
+		return r == '_'
+	}), "_")
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		name = "field_" + name
+	}
+	return name
+}
+
+// uniqueName returns name, suffixed by a number if it's already in names.
+func uniqueName(name string, names map[string]bool) string {
+	unique := name
+	for i := 2; names[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	names[unique] = true
+	return unique
+}
+
+// helper adds the helper message name, whose fields are fields, unless it's
+// already added, and returns its name.
+func (e *protoEmitter) helper(name string, fields ...string) string {
+	if e.helperNames[name] {
+		return name
+	}
+	e.helperNames[name] = true
+	e.helpers = append(e.helpers, message(name, fields))
+	return name
+}
+
+func message(name string, fields []string) string {
+	var b strings.Builder
+	b.WriteString("message " + name + " {\n")
+	for i, f := range fields {
+		b.WriteString("  " + f + " = " + strconv.Itoa(i+1) + ";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// protoType returns the type of a field of type t, and its label: repeated
+// for the lists and the maps whose keys are messages, which are lists of
+// entries. The maps whose keys are scalars are map<>, without label.
+func (e *protoEmitter) protoType(t any) (label, typ string) {
+	switch va := t.(type) {
+	case reflect.Kind:
+		return "", protoKind(va)
+	case *generator.StructInfo:
+		return "", strcase.ToCamel(va.Name)
+	case *generator.Enum:
+		return "", strcase.ToCamel(va.Name)
+	case *generator.Union:
+		return "", strcase.ToCamel(va.Name)
+	case *generator.Polymorphic:
+		return "", strcase.ToCamel(va.Name)
+	case *generator.FixedArray:
+		return "repeated", e.itemType(va.PrimaryType)
+	case *generator.CustomType:
+		switch {
+		case va.External:
+		case generator.IsEmptyType(va):
+			return "", e.helper("XMLEmpty", "map<string, string> attr")
+		case generator.IsEmbeddedType(va):
+			kind := protoKind(kindOf(va.Type1))
+			return "", e.helper("XMLText"+strcase.ToCamel(kind), kind+" value", "map<string, string> attr")
+		case generator.IsSliceType(va):
+			return "repeated", e.itemType(va.Type1)
+		case va.Name == "Map":
+			return e.mapType(va)
+		}
+	}
+	// The unknown types and the *xml.Element are kept as raw XML
+	return "", e.helper("XMLAny", "string name", "map<string, string> attr", "bytes content")
+}
+
+// itemType returns the type of the items t of a list, of the values of a map
+// or of the cases of a oneof, which can't be lists nor maps. Those are
+// wrapped in a message.
+func (e *protoEmitter) itemType(t any) string {
+	label, typ := e.protoType(t)
+	switch {
+	case label == "repeated":
+		return e.helper(strcase.ToCamel(typ)+"List", "repeated "+typ+" items")
+	case strings.HasPrefix(typ, "map<"):
+		key, value, _ := strings.Cut(typ[len("map<"):len(typ)-1], ", ")
+		return e.helper(strcase.ToCamel(key)+strcase.ToCamel(value)+"Map", typ+" entries")
+	}
+	return typ
+}
+
+// mapType returns the type of the map c: a map<> if its keys are scalars,
+// otherwise a list of entries holding a key and a value.
+func (e *protoEmitter) mapType(c *generator.CustomType) (label, typ string) {
+	value := e.itemType(c.Type2)
+	if key, ok := protoKeyType(c.Type1); ok {
+		return "", "map<" + key + ", " + value + ">"
+	}
+	key := e.itemType(c.Type1)
+	return "repeated", e.helper(strcase.ToCamel(key)+strcase.ToCamel(value)+"Entry", key+" key", value+" value")
+}
+
+// elementNames returns the element names of the members of s. The Go
+// writer renames the members once it has written them, so they're taken
+// from the keys of s.Members.
+func elementNames(s *generator.StructInfo) map[*generator.Member]string {
+	names := make(map[*generator.Member]string, len(s.Members))
+	for name, m := range s.Members {
+		names[m] = name
+	}
+	return names
+}
+
+// EmitStruct writes s as a message. The map of the untyped attributes is
+// always the field 1, the members follow in their order, then the typed
+// attributes.
+func (e *protoEmitter) EmitStruct(s *generator.StructInfo) error {
+	var (
+		fields   = []string{"map<string, string> attr"}
+		names    = map[string]bool{"attr": true}
+		elements = elementNames(s)
+	)
+	for _, m := range s.Order {
+		element, ok := elements[m]
+		if !ok {
+			element = m.Name
+		}
+		label, typ := e.protoType(m.T)
+		switch m.T.(type) {
+		case reflect.Kind, *generator.Enum:
+			if e.gw.optionalStyle != OptionalUnmarked && s.IsOptional(m) {
+				label = "optional"
+			}
+		}
+		if label != "" {
+			typ = label + " " + typ
+		}
+		fields = append(fields, typ+" "+uniqueName(protoFieldName(element), names))
+	}
+	for _, a := range s.Attributes {
+		name := protoFieldName(a.Name)
+		if names[name] {
+			name += "_attr"
+		}
+		typ := protoKind(a.T)
+		if s.IsOptionalAttribute(a) {
+			typ = "optional " + typ
+		}
+		fields = append(fields, typ+" "+uniqueName(name, names))
+	}
+	e.definitions = append(e.definitions, message(strcase.ToCamel(s.Name), fields))
+	return nil
+}
+
+// EmitEnum writes en as an enumeration. Its first constant is the zero
+// value, unspecified, the other ones are commented with their value.
+func (e *protoEmitter) EmitEnum(en *generator.Enum) error {
+	var (
+		b           strings.Builder
+		typeName    = strcase.ToCamel(en.Name)
+		unspecified = strcase.ToScreamingSnake(typeName) + "_UNSPECIFIED"
+		names       = map[string]bool{unspecified: true}
+	)
+	b.WriteString("enum " + typeName + " {\n  " + unspecified + " = 0;\n")
+	for i, c := range enumConstants(typeName, en) {
+		name := uniqueName(strcase.ToScreamingSnake(c), names)
+		b.WriteString("  " + name + " = " + strconv.Itoa(i+1) + "; // " + strconv.Quote(en.Values[i]) + "\n")
+	}
+	b.WriteString("}\n")
+	e.definitions = append(e.definitions, b.String())
+	return nil
+}
+
+// oneofName is the name of the oneof of the unions and the polymorphic
+// items, which their fields can't be named.
+const oneofName = "value"
+
+// oneof returns the message name holding one of the fields.
+func oneof(name string, fields []string) string {
+	var b strings.Builder
+	b.WriteString("message " + name + " {\n  oneof " + oneofName + " {\n")
+	for i, f := range fields {
+		b.WriteString("    " + f + " = " + strconv.Itoa(i+1) + ";\n")
+	}
+	b.WriteString("  }\n}\n")
+	return b.String()
+}
+
+// EmitUnion writes u as a message holding one of its variants.
+func (e *protoEmitter) EmitUnion(u *generator.Union) error {
+	var (
+		fields = make([]string, 0, len(u.Variants))
+		names  = map[string]bool{oneofName: true}
+	)
+	for _, v := range u.Variants {
+		fields = append(fields, e.itemType(v.T)+" "+uniqueName(protoFieldName(u.VariantName(v)), names))
+	}
+	e.definitions = append(e.definitions, oneof(strcase.ToCamel(u.Name), fields))
+	return nil
+}
+
+// EmitPolymorphic writes p as a message holding one of its variants, named
+// after their class. The variant without class is no_class.
+func (e *protoEmitter) EmitPolymorphic(p *generator.Polymorphic) error {
+	var (
+		fields = make([]string, 0, len(p.Variants)+1)
+		names  = map[string]bool{oneofName: true, "no_class": true}
+	)
+	for _, c := range p.Classes() {
+		fields = append(fields, strcase.ToCamel(p.Variants[c].Name)+" "+uniqueName(protoFieldName(c), names))
+	}
+	if p.Default != nil {
+		fields = append(fields, strcase.ToCamel(p.Default.Name)+" no_class")
+	}
+	e.definitions = append(e.definitions, oneof(strcase.ToCamel(p.Name), fields))
+	return nil
+}
+
+// Flush writes the file.
+func (e *protoEmitter) Flush() error {
+	var b strings.Builder
+	pkgName := e.gw.packageName()
+	b.WriteString("// Code generated by rimworld-editor. DO NOT EDIT.\n\n" +
+		"syntax = \"proto3\";\n\n" +
+		"package " + pkgName + ";\n")
+	for _, d := range append(e.definitions, e.helpers...) {
+		b.WriteString("\n" + d)
+	}
+	return os.WriteFile(e.path+"/"+pkgName+".proto", []byte(b.String()), 0644)
+}
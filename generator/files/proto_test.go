@@ -0,0 +1,97 @@
+package files
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+)
+
+func TestGoWriter_proto(t *testing.T) {
+	s, registered := generate(t, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<game>
+		<tickCount>1500</tickCount>
+		<label lang="en">hello</label>
+		<nested><li><li>1</li></li><li><li>2</li></li></nested>
+		<weights>
+			<keys><li>a</li><li>b</li></keys>
+			<values><li>1.5</li><li>2</li></values>
+		</weights>
+		<cells>
+			<keys><li><x>1</x><z>2</z></li></keys>
+			<values><li>Sand</li></values>
+		</cells>
+		<pawns>
+			<li id="1"><name>Tynan</name><age>30</age><gender>Male</gender></li>
+			<li id="2"><name>Randy</name><gender>Male</gender></li>
+			<li id="3"><name>Cassandra</name><gender>Female</gender></li>
+		</pawns>
+		<things>
+			<li Class="Apparel"><def>Hat</def></li>
+			<li Class="Value"><def>Coin</def></li>
+			<li><def>Rock</def></li>
+		</things>
+	</game>
+</savegame>`, generator.WithAttributes(true), generator.WithEnums(2), generator.WithClassPolymorphism(true))
+	dir := t.TempDir()
+	gw := NewGoWriter(registered, true, "saves", WithBackend(BackendProto), WithOptionalStyle(OptionalPointer))
+	noError(t, gw.WriteGoFile(dir, s))
+
+	proto := readFile(t, dir, "saves.proto")
+	assert.Contains(t, proto, "syntax = \"proto3\";\n\npackage saves;\n")
+	assert.Contains(t, proto, `message Game {
+  map<string, string> attr = 1;
+  int64 tick_count = 2;
+  XMLTextString label = 3;
+  repeated Int64List nested = 4;
+  map<string, double> weights = 5;
+  repeated KeysStringEntry cells = 6;
+  repeated Pawns pawns = 7;
+  repeated ThingsItem things = 8;
+}`)
+	assert.Contains(t, proto, `message Pawns {
+  map<string, string> attr = 1;
+  string name = 2;
+  optional int64 age = 3;
+  PawnsGender gender = 4;
+  int64 id = 5;
+}`)
+	assert.Contains(t, proto, `enum PawnsGender {
+  PAWNS_GENDER_UNSPECIFIED = 0;
+  PAWNS_GENDER_FEMALE = 1; // "Female"
+  PAWNS_GENDER_MALE = 2; // "Male"
+}`)
+	assert.Contains(t, proto, "message ThingsItem {\n  oneof value {\n    ThingsApparel apparel = 1;\n    ThingsValue value_2 = 2;\n    Things no_class = 3;\n  }\n}")
+
+	t.Run("protoc", func(t *testing.T) {
+		protoc, err := exec.LookPath("protoc")
+		if err != nil {
+			t.Skip("protoc isn't installed")
+		}
+		output(t, exec.Command(protoc, "--proto_path="+dir, "--descriptor_set_out="+os.DevNull, "saves.proto"))
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		gw := NewGoWriter(registered, true, "", WithBackend(BackendProto), WithMerge(true))
+		assert.Error(t, gw.WriteGoFile(t.TempDir(), s))
+	})
+}
+
+func Test_protoFieldName(t *testing.T) {
+	for name, want := range map[string]string{
+		"tickCount": "tick_count",
+		"_hidden":   "hidden",
+		"modIds":    "mod_ids",
+		"XMLName":   "xml_name",
+		"int64":     "int64",
+		"def.label": "def_label",
+		"li_Inner":  "li",
+		"1st":       "field_1st",
+	} {
+		assert.Equal(t, want, protoFieldName(name), name)
+	}
+}
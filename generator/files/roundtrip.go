@@ -0,0 +1,106 @@
+package files
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/generator/paths"
+)
+
+// roundTripTestFile is the name of the file of the round-trip test.
+const roundTripTestFile = "round_trip_test.go"
+
+// Sample is an XML file the types are generated from.
+type Sample struct {
+	// Name is the name of the file, used to name the test case.
+	Name string
+	// Root is the name of the root element of the file.
+	Root    string
+	Content []byte
+}
+
+// WithRoundTripTest writes a test along with the generated types. For each
+// sample, embedded from the testdata directory, the test unmarshals the
+// sample into the root structure, saves it back and reports the paths where
+// the result diverges from the sample. The samples must be parsed with the
+// default dialect.
+func WithRoundTripTest(samples ...Sample) WriterOption {
+	return func(gw *GoWriter) {
+		gw.roundTripSamples = samples
+	}
+}
+
+// rootFields returns the Go names of the members of the root structure s by
+// element name. It must be called once s is written, which names the
+// members.
+func (gw *GoWriter) rootFields(s *generator.StructInfo, elements []string) map[string]string {
+	best := gw.registeredMember[s.Name][0]
+	fields := make(map[string]string, len(elements))
+	for i, m := range best.Order {
+		fields[elements[i]] = m.Name
+	}
+	return fields
+}
+
+// writeRoundTripTest writes the samples in the testdata directory of path
+// and the test reading them. fields are the Go names of the members of the
+// root structure s by element name.
+func (gw *GoWriter) writeRoundTripTest(path string, s *generator.StructInfo, fields map[string]string) error {
+	if err := os.MkdirAll(filepath.Join(path, "testdata"), os.ModePerm); err != nil {
+		return err
+	}
+	buf := gw.newBuffer(path)
+	// The samples are embedded as strings, which needs a blank import
+	buf.imp = append(buf.imp, `_ "embed"`+"\n")
+	buf.writeExternalImport("testing")
+	buf.writeImport(paths.FilePackage)
+	buf.writeImport(paths.XmlFileSaver)
+	buf.writeImport(paths.XmlUnmarshal)
+	rootName := strcase.ToCamel(s.Name)
+	cases := ""
+	for i, sample := range gw.roundTripSamples {
+		field, ok := fields[sample.Root]
+		if !ok {
+			log.Printf("files.GoWriter: round-trip sample %s: no member for the root element %s, skipped", sample.Name, sample.Root)
+			continue
+		}
+		dataName := "round_trip_" + strconv.Itoa(i) + ".xml"
+		if err := os.WriteFile(filepath.Join(path, "testdata", dataName), sample.Content, 0644); err != nil {
+			return err
+		}
+		variable := "roundTripSample" + strconv.Itoa(i)
+		buf.writeToBody("//go:embed testdata/" + dataName + "\nvar " + variable + " string\n\n")
+		cases += "\t\t{" + strconv.Quote(sample.Name) + ", " + variable + ", func(r *" + rootName + ") any { return r." + field + " }},\n"
+	}
+	buf.writeToFooter("// TestRoundTrip unmarshals the samples the types are generated from, saves\n" +
+		"// them back and compares the result to the samples.\n" +
+		"func TestRoundTrip(t *testing.T) {\n" +
+		"\tsamples := []struct {\n\t\tname    string\n\t\tcontent string\n\t\troot    func(r *" + rootName + ") any\n\t}{\n" +
+		cases +
+		"\t}\n" +
+		"\tfor _, s := range samples {\n" +
+		"\t\tt.Run(s.name, func(t *testing.T) {\n" +
+		"\t\t\toriginal, err := file.ReadFromBuffer(s.content)\n" +
+		"\t\t\tif err != nil {\n\t\t\t\tt.Fatal(err)\n\t\t\t}\n" +
+		"\t\t\tvar r " + rootName + "\n" +
+		"\t\t\tif err = unmarshal.Element(original.XML.Root, &r); err != nil {\n\t\t\t\tt.Fatal(err)\n\t\t\t}\n" +
+		"\t\t\tb, err := xmlFile.SaveWithBuffer(s.root(&r))\n" +
+		"\t\t\tif err != nil {\n\t\t\t\tt.Fatal(err)\n\t\t\t}\n" +
+		"\t\t\tsaved, err := file.ReadFromBuffer(string(b.Bytes()))\n" +
+		"\t\t\tif err != nil {\n\t\t\t\tt.Fatalf(\"the saved file can't be read: %v\", err)\n\t\t\t}\n" +
+		"\t\t\tfor _, d := range original.XML.Diff(saved.XML) {\n" +
+		"\t\t\t\tt.Errorf(\"%v\", d)\n" +
+		"\t\t\t}\n" +
+		"\t\t})\n" +
+		"\t}\n}\n")
+	b, err := gw.source(buf)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, roundTripTestFile), b, 0644)
+}
@@ -0,0 +1,32 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const roundTripDocument = `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<name>Tynan</name>
+		<weight>1.50</weight>
+	</pawn>
+</savegame>`
+
+func TestGoWriter_roundTripTest(t *testing.T) {
+	dir := moduleDir(t)
+	write(t, dir, roundTripDocument, WithRoundTripTest(
+		Sample{Name: "sample.xml", Root: "savegame", Content: []byte(roundTripDocument)},
+		Sample{Name: "other.xml", Root: "world", Content: []byte("<world/>")},
+	))
+	assert.Equal(t, roundTripDocument, readFile(t, dir, filepath.Join("testdata", "round_trip_0.xml")))
+	// The sample of another root element is skipped
+	_, err := os.Stat(filepath.Join(dir, "testdata", "round_trip_1.xml"))
+	assert.True(t, os.IsNotExist(err))
+
+	out := output(t, goCommand(t, dir, "test", "-v", "-run", "TestRoundTrip", "."))
+	assert.Contains(t, out, "--- PASS: TestRoundTrip/sample.xml")
+}
@@ -1,6 +1,7 @@
 package files
 
 import (
+	"errors"
 	"log"
 	"os"
 	"reflect"
@@ -9,6 +10,7 @@ import (
 	"github.com/cruffinoni/rimworld-editor/algorithm"
 	"github.com/cruffinoni/rimworld-editor/generator"
 	"github.com/cruffinoni/rimworld-editor/generator/paths"
+	"github.com/cruffinoni/rimworld-editor/xml/dialect"
 	"github.com/cruffinoni/rimworld-editor/xml/interface"
 )
 
@@ -16,22 +18,110 @@ type GoWriter struct {
 	forcedPackageName string
 	registeredMember  generator.MemberVersioning
 	deleteFolder      bool
+	optionalStyle     OptionalStyle
+	merge             bool
+	// changes are the changes made by the last merge.
+	changes          []Change
+	roundTripSamples []Sample
+	backend          Backend
+	dialect          *dialect.Dialect
+	// rootElementNames are the element names of the structures of the root
+	// elements, by structure name, named by BackendPlain.
+	rootElementNames map[string]string
+	// plainLists are the names of the XMLList types of BackendPlain, by
+	// item tag.
+	plainLists    map[string]string
+	runtimeModule string
+	// packageAliases are the aliases of the runtime packages, by path in the
+	// runtime module.
+	packageAliases map[string]string
+	layout         Layout
+	// outputDir is the directory the files are written in, importBase its
+	// import path with LayoutPerRoot.
+	outputDir  string
+	importBase string
+	// typePackages are the subpackages of the types by name, see
+	// LayoutPerRoot.
+	typePackages map[string]string
+	// written are the files already written, pending the files kept for
+	// LayoutSingleFile.
+	written map[string]bool
+	pending []*buffer
 }
 
-func NewGoWriter(registeredMember generator.MemberVersioning, deleteFolder bool, forcedPackageName string) *GoWriter {
-	return &GoWriter{
+// OptionalStyle is how the members missing from some elements of their
+// structure are marked in the generated code.
+type OptionalStyle int
+
+const (
+	// OptionalUnmarked doesn't mark the optional members.
+	OptionalUnmarked OptionalStyle = iota
+	// OptionalPointer types the optional members of a primitive type as
+	// pointers, which are nil when the element is missing. The other types
+	// are already pointers.
+	OptionalPointer
+	// OptionalAccessor generates a Has<Field>() method for each optional
+	// member, backed by FieldValidated.
+	OptionalAccessor
+)
+
+// OptionalStyles are the optional styles by name.
+var OptionalStyles = map[string]OptionalStyle{
+	"none":     OptionalUnmarked,
+	"pointer":  OptionalPointer,
+	"accessor": OptionalAccessor,
+}
+
+// WriterOption configures a GoWriter.
+type WriterOption func(gw *GoWriter)
+
+// WithOptionalStyle sets how the optional members are marked. They're not
+// marked by default.
+func WithOptionalStyle(style OptionalStyle) WriterOption {
+	return func(gw *GoWriter) {
+		gw.optionalStyle = style
+	}
+}
+
+func NewGoWriter(registeredMember generator.MemberVersioning, deleteFolder bool, forcedPackageName string, opts ...WriterOption) *GoWriter {
+	gw := &GoWriter{
 		forcedPackageName: forcedPackageName,
 		registeredMember:  registeredMember,
 		deleteFolder:      deleteFolder,
+		dialect:           dialect.Default,
+		runtimeModule:     defaultRuntimeModule,
 	}
+	for _, opt := range opts {
+		opt(gw)
+	}
+	return gw
 }
 
-var DefaultGoWriter = NewGoWriter(nil, true, "")
-
 // WriteGoFile writes the struct Go code to the given path.
 // It writes recursively the members of the struct. If a member is a struct,
 // it will call WriteGoFile on it.
 func (gw *GoWriter) WriteGoFile(path string, s *generator.StructInfo) error {
+	if gw.registeredMember == nil {
+		return errors.New("files.GoWriter.WriteGoFile: no registered members")
+	}
+	if err := gw.checkBackend(); err != nil {
+		return err
+	}
+	if err := gw.checkImports(path, s); err != nil {
+		return err
+	}
+	if gw.layout == LayoutPerRoot {
+		importBase, err := packageImportPath(path)
+		if err != nil {
+			return err
+		}
+		gw.importBase = importBase
+	}
+	if gw.merge {
+		return gw.mergeInto(path, func(dir string) error {
+			return gw.writeFiles(dir, s)
+		})
+	}
 	if gw.deleteFolder {
 		if _, err := os.Stat(path); err == nil {
 			if err = os.RemoveAll(path); err != nil {
@@ -42,10 +132,102 @@ func (gw *GoWriter) WriteGoFile(path string, s *generator.StructInfo) error {
 	if err := os.MkdirAll(path, os.ModePerm); err != nil {
 		return err
 	}
-	if gw.registeredMember == nil {
-		gw.registeredMember = generator.RegisteredMembers
+	return gw.writeFiles(path, s)
+}
+
+// writeFiles writes the files of s and its members in path, and the
+// round-trip test if it's asked.
+func (gw *GoWriter) writeFiles(path string, s *generator.StructInfo) error {
+	if gw.backend == BackendPlain {
+		gw.rootElementNames = gw.rootElements(s)
+		gw.plainLists = gw.plainListTypes()
+	}
+	gw.outputDir = path
+	gw.written = make(map[string]bool)
+	gw.pending = nil
+	gw.typePackages = nil
+	if gw.layout == LayoutPerRoot {
+		packages, err := gw.layoutPackages(s)
+		if err != nil {
+			return err
+		}
+		gw.typePackages = packages
+	}
+	e := gw.emitter(path)
+	if len(gw.roundTripSamples) == 0 {
+		return Walk(s, gw.registeredMember, e)
+	}
+	// The members are renamed as they are written
+	var elements []string
+	for _, m := range gw.registeredMember[s.Name][0].Order {
+		elements = append(elements, m.Name)
+	}
+	if err := Walk(s, gw.registeredMember, e); err != nil {
+		return err
+	}
+	return gw.writeRoundTripTest(path, s, gw.rootFields(s, elements))
+}
+
+// emitter returns the Emitter of the backend of gw, writing in path.
+func (gw *GoWriter) emitter(path string) Emitter {
+	if gw.backend == BackendProto {
+		return newProtoEmitter(gw, path)
+	}
+	return &goEmitter{gw: gw, path: path}
+}
+
+// goEmitter writes each type in its own Go file, in the directory of its
+// package, or in a single file with LayoutSingleFile.
+type goEmitter struct {
+	gw   *GoWriter
+	path string
+}
+
+func (e *goEmitter) EmitStruct(s *generator.StructInfo) error {
+	dir, err := e.gw.typeDir(e.path, s.Name)
+	if err != nil {
+		return err
+	}
+	return e.gw.generateStructToPath(dir, s)
+}
+
+func (e *goEmitter) EmitEnum(en *generator.Enum) error {
+	dir, err := e.gw.typeDir(e.path, en.Name)
+	if err != nil {
+		return err
+	}
+	return e.gw.generateEnumToPath(dir, en)
+}
+
+func (e *goEmitter) EmitUnion(u *generator.Union) error {
+	if e.gw.backend == BackendPlain {
+		// The unions are kept as raw XML
+		return SkipReferences
+	}
+	dir, err := e.gw.typeDir(e.path, u.Name)
+	if err != nil {
+		return err
+	}
+	return e.gw.generateUnionToPath(dir, u)
+}
+
+func (e *goEmitter) EmitPolymorphic(p *generator.Polymorphic) error {
+	if e.gw.backend == BackendPlain {
+		// The polymorphic items are kept as raw XML
+		return SkipReferences
+	}
+	dir, err := e.gw.typeDir(e.path, p.Name)
+	if err != nil {
+		return err
 	}
-	return gw.generateStructToPath(path, s)
+	return e.gw.generatePolymorphicToPath(dir, p)
+}
+
+func (e *goEmitter) Flush() error {
+	if e.gw.layout == LayoutSingleFile {
+		return e.gw.writeSingleFile(e.path)
+	}
+	return nil
 }
 
 type generic struct{}
@@ -63,6 +245,57 @@ var (
 	localGenericName = reflect.TypeOf(generic{}).Name()
 )
 
+// writeAttributeFields writes the typed attributes of s as fields tagged
+// ",attr". A name already used by the structure is suffixed by "Attr" and the
+// attributes missing from some elements are pointers.
+func writeAttributeFields(b *buffer, s *generator.StructInfo, names map[string]bool) {
+	if len(s.Attributes) == 0 {
+		return
+	}
+	b.writeToBody("\n")
+	for _, a := range s.Attributes {
+		name := transformToPrivateCamelCase(a.Name)
+		if _, isMethod := tRequired.MethodByName(name); names[name] || isMethod || isReservedField(name) {
+			name += "Attr"
+		}
+		names[name] = true
+		b.writeToBody("\t" + name + " ")
+		if s.IsOptionalAttribute(a) {
+			b.writeToBody("*")
+		}
+		b.writeToBody(a.T.String() + " `xml:\"" + a.Name + ",attr\"`\n")
+	}
+}
+
+// isReservedField reports whether name is the name of a field every
+// generated structure may have.
+func isReservedField(name string) bool {
+	switch name {
+	case "Attr", "FieldValidated":
+		return true
+	}
+	return false
+}
+
+// writePresenceAccessors writes the Has<Field>() methods of the optional
+// fields of the structure. fields are the names of the Go fields, names all
+// the names already used by the structure.
+func writePresenceAccessors(b *buffer, structName string, fields []string, names map[string]bool) {
+	structIdentifier := strings.ToLower(structName[:1])
+	for _, f := range fields {
+		accessor := "Has" + f
+		if names[accessor] {
+			log.Printf("files.writePresenceAccessors: %s.%s already exists", structName, accessor)
+			continue
+		}
+		names[accessor] = true
+		b.writeToFooter("\n// " + accessor + " reports whether " + f + " is present in the XML file.\n" +
+			"func (" + structIdentifier + " *" + structName + ") " + accessor + "() bool {\n" +
+			"\treturn " + structIdentifier + ".FieldValidated[\"" + f + "\"]\n" +
+			"}\n")
+	}
+}
+
 func writeRequiredInterfaces(b *buffer, structName string) {
 	b.writeImport(paths.XmlAttributes, paths.HeaderXml)
 	for i := 0; i < nbRequiredMethod; i++ {
@@ -1,12 +1,7 @@
 package files
 
 import (
-	"errors"
 	"fmt"
-	"go/format"
-	"log"
-	"math/rand"
-	"os"
 	"reflect"
 	"strings"
 
@@ -26,7 +21,7 @@ func getTypeName(t any) string {
 		return va.String()
 	case *generator.CustomType:
 		var s strings.Builder
-		if generator.IsEmptyType(va) || generator.IsMultipleType(va) {
+		if generator.IsEmptyType(va) || generator.IsMultipleType(va) || va.External {
 			s.WriteString(va.Pkg + "." + va.Name)
 			return s.String()
 		}
@@ -38,6 +33,12 @@ func getTypeName(t any) string {
 		return s.String()
 	case *generator.StructInfo:
 		return "*" + strcase.ToCamel(va.Name)
+	case *generator.Enum:
+		return strcase.ToCamel(va.Name)
+	case *generator.Polymorphic:
+		return strcase.ToCamel(va.Name)
+	case *generator.Union:
+		return "*" + strcase.ToCamel(va.Name)
 	case *generator.FixedArray:
 		return fmt.Sprintf("[%d] %s", va.Size, getTypeName(va.PrimaryType))
 	case *xml.Element:
@@ -47,31 +48,23 @@ func getTypeName(t any) string {
 	}
 }
 
-func (gw *GoWriter) checkTypeAndApply(t any, buffer *buffer, path string) error {
+// writeTypeImports imports the packages of the type t. The types it refers
+// to are written in their own files by Walk.
+func writeTypeImports(t any, b *buffer) {
 	switch va := t.(type) {
-	case *generator.StructInfo:
-		return gw.generateStructToPath(path, va)
 	case *xml.Element:
-		buffer.writeImport(paths.HeaderXml)
+		b.writeImport(paths.HeaderXml)
 	case *generator.CustomType:
-		var err error
-		buffer.writeImport(va.ImportPath)
-		if err = gw.checkTypeAndApply(va.Type1, buffer, path); err != nil {
-			return err
-		}
-		if va.Type2 != nil {
-			if err = gw.checkTypeAndApply(va.Type2, buffer, path); err != nil {
-				return err
-			}
+		if va.External {
+			writeExternalType(va, b)
+			return
 		}
+		b.writeImport(va.ImportPath)
+		writeTypeImports(va.Type1, b)
+		writeTypeImports(va.Type2, b)
 	case *generator.FixedArray:
-		// ????
-		//buffer.writeToBody(fmt.Sprintf("[%d]", va.Size))
-		if err := gw.checkTypeAndApply(va.PrimaryType, buffer, path); err != nil {
-			return err
-		}
+		writeTypeImports(va.PrimaryType, b)
 	}
-	return nil
 }
 
 func (gw *GoWriter) writeHeader(b *buffer, pkgName string) {
@@ -83,28 +76,42 @@ func removeInnerKeyword(s string) string {
 	return strings.Replace(s, generator.InnerKeyword, "", -1)
 }
 
-func (gw *GoWriter) writeCustomType(c *generator.CustomType, b *buffer, path string) error {
-	var err error
+// writeExternalType imports the package of a type given by the
+// configuration and asserts that the type implements the interfaces required
+// by the runtime.
+func writeExternalType(c *generator.CustomType, b *buffer) {
+	b.writeExternalImport(c.ImportPath)
+	name := c.Pkg + "." + c.Name
+	if b.writtenHeaders["assert:"+name] {
+		return
+	}
+	b.writtenHeaders["assert:"+name] = true
+	b.writeImport(paths.XmlInterface)
+	b.writeImport(paths.XmlSaver)
+	b.writeAssertion("\nvar (\n" +
+		"\t_ _interface.Assigner = (" + name + ")(nil)\n" +
+		"\t_ saver.Transformer = (" + name + ")(nil)\n" +
+		")\n")
+}
+
+func writeCustomType(c *generator.CustomType, b *buffer) {
+	if c.External {
+		writeExternalType(c, b)
+		b.writeToBody(c.Pkg + "." + c.Name)
+		return
+	}
 	b.writeImport(c.ImportPath)
-	// log.Printf("Custom type %+v", *c)
 	b.writeToBody(c.Pkg + "." + c.Name)
-	//log.Printf("?? > %v", c.Pkg+"."+c.Name)
 	if c.Type1 == nil {
-		// log.Printf("Types: %v & %v", c.type1, c.type2)
-		return nil
+		return
 	}
 	b.writeToBody("[" + getTypeName(c.Type1))
-	if err = gw.checkTypeAndApply(c.Type1, b, path); err != nil {
-		return err
-	}
+	writeTypeImports(c.Type1, b)
 	if c.Type2 != nil {
-		if err = gw.checkTypeAndApply(c.Type2, b, path); err != nil {
-			return err
-		}
+		writeTypeImports(c.Type2, b)
 		b.writeToBody(", " + getTypeName(c.Type2))
 	}
 	b.writeToBody("]")
-	return err
 }
 
 const basicPackageName = "generated"
@@ -123,88 +130,106 @@ func transformToPrivateCamelCase(name string) string {
 	return name
 }
 
-func (gw *GoWriter) generateStructToPath(path string, s *generator.StructInfo) error {
-	if _, err := os.Stat(path + "/" + strcase.ToSnake(s.Name) + ".go"); !errors.Is(err, os.ErrNotExist) {
-		// log.Printf("generateStructToPath: file already exists at: %v", path+"/"+strcase.ToSnake(s.Name)+".go")
-		// log.Printf("Size: %d from %p", len(s.Members), s)
-		return nil
+// fileName returns the name of the file of s, without extension.
+func (gw *GoWriter) fileName(s *generator.StructInfo) string {
+	if s.FileName != "" {
+		return s.FileName
 	}
-	f, err := os.Create(path + "/" + strcase.ToSnake(s.Name) + ".go")
-	if err != nil {
-		return err
+	if v := gw.registeredMember[s.Name]; len(v) > 0 && v[0].FileName != "" {
+		return v[0].FileName
+	}
+	return strcase.ToSnake(s.Name)
+}
+
+// nameField renames m with its Go name, unique in names, and returns its
+// element name. collisions counts the names given twice to suffix them in a
+// stable way.
+func nameField(m *generator.Member, names map[string]bool, collisions map[string]int) string {
+	// Make a copy of the original name for XML tag
+	originalName := m.Name
+	if m.FieldName != "" {
+		m.Name = m.FieldName
+	} else {
+		// Sometimes, name contains "_" at the beginning, so we need to keep it in a way that it's accepted in Go
+		m.Name = transformToPrivateCamelCase(m.Name)
 	}
-	buf := &buffer{
-		writtenHeaders: make(map[string]bool),
+	// The name has been already registered for this structure, it happens when there is 2 names like: "_something", "_Something"
+	// The camel case remove both "_" and change the first letter to an uppercase one leaving with the same symbol twice.
+	if names[m.Name] {
+		collisions[m.Name]++
+		m.Name = fmt.Sprintf("%s_%d", strcase.ToCamel(originalName), collisions[m.Name])
 	}
+	names[m.Name] = true
+	return originalName
+}
+
+// packageName returns the package name of the generated files.
+func (gw *GoWriter) packageName() string {
 	if gw.forcedPackageName != "" {
-		gw.writeHeader(buf, gw.forcedPackageName)
-	} else {
-		gw.writeHeader(buf, basicPackageName)
+		return gw.forcedPackageName
 	}
-	defer func(f *os.File) {
-		err = f.Close()
-		if err != nil {
-			log.Fatalf("generator.StructInfo.generateStructToPath: can't close the file %v", err)
-		}
-	}(f)
+	return basicPackageName
+}
+
+func (gw *GoWriter) generateStructToPath(path string, s *generator.StructInfo) error {
+	if gw.backend == BackendPlain {
+		return gw.generatePlainStructToPath(path, s)
+	}
+	fileName := path + "/" + gw.fileName(s) + ".go"
+	if gw.exists(fileName) {
+		// log.Printf("generateStructToPath: file already exists at: %v", path+"/"+strcase.ToSnake(s.Name)+".go")
+		// log.Printf("Size: %d from %p", len(s.Members), s)
+		return nil
+	}
+	buf := gw.newBuffer(path)
 	structName := strcase.ToCamel(s.Name)
 	if structName == "" {
 		panic("empty struct name")
 	}
+	best := gw.registeredMember[s.Name][0] // Use the best matched version of s.name
 	buf.writeToBody("type " + structName + " struct {\nAttr attributes.Attributes\nFieldValidated map[string]bool\n\n")
 	// log.Printf("S: %s | %d", s.Name, len(registeredMembers[s.Name]))
 	namesRegistered := make(map[string]bool)
-	for _, m := range gw.registeredMember[s.Name][0].Order { // Use the best matched version of s.name
-		// Make a copy of the original name for XML tag
-		originalName := m.Name
-		// Sometimes, name contains "_" at the beginning, so we need to keep it in a way that it's accepted in Go
-		m.Name = transformToPrivateCamelCase(m.Name)
-		// The name has been already registered for this structure, it happens when there is 2 names like: "_something", "_Something"
-		// The camel case remove both "_" and change the first letter to an uppercase one leaving with the same symbol twice.
-		if namesRegistered[m.Name] {
-			m.Name = fmt.Sprintf("%s_%d", strcase.ToCamel(originalName), rand.Intn(100000))
+	// collisions counts the names given twice to suffix them in a stable way
+	collisions := make(map[string]int)
+	// optionals are the Go names of the members missing from some elements
+	var optionals []string
+	for _, m := range best.Order {
+		originalName := nameField(m, namesRegistered, collisions)
+		optional := gw.optionalStyle != OptionalUnmarked && best.IsOptional(m)
+		if optional {
+			optionals = append(optionals, m.Name)
 		}
-		namesRegistered[m.Name] = true
 		buf.writeToBody("\t" + m.Name + " ")
 		switch va := m.T.(type) {
 		case *generator.CustomType:
-			if err = gw.writeCustomType(va, buf, path); err != nil {
-				return err
-			}
+			writeCustomType(va, buf)
+		case *generator.Enum:
+			buf.writeToBody(strcase.ToCamel(va.Name))
+		case *generator.Union:
+			buf.writeToBody("*" + strcase.ToCamel(va.Name))
 		case reflect.Kind:
+			if optional && gw.optionalStyle == OptionalPointer {
+				buf.writeToBody("*")
+			}
 			buf.writeToBody(va.String())
 		case *generator.StructInfo:
 			buf.writeToBody("*" + strcase.ToCamel(va.Name))
-			if s.Name == va.Name {
-				s.PrintOrderedMembers()
-				va.PrintOrderedMembers()
-				log.Panicf("duplicate name for %s & %s\n> s: %+v\nva: %+v", s.Name, va.Name, s, va)
-			}
-			if err = gw.generateStructToPath(path, va); err != nil {
-				return err
-			}
 		case *xml.Element:
 			// headerXml will be imported in the buffer when we write the
 			// required import statement.
 			buf.writeToBody("*xml.Element")
 		case *generator.FixedArray:
 			buf.writeToBody(fmt.Sprintf("[%d] %s", va.Size, getTypeName(va.PrimaryType)))
-			if err := gw.checkTypeAndApply(va.PrimaryType, buf, path); err != nil {
-				return err
-			}
+			writeTypeImports(va.PrimaryType, buf)
 		}
 		buf.writeToBody(" `xml:\"" + removeInnerKeyword(originalName) + "\"`\n")
 	}
+	writeAttributeFields(buf, best, namesRegistered)
 	buf.writeToFooter("}\n")
 	writeRequiredInterfaces(buf, structName)
-	var b []byte
-	b, err = format.Source(buf.bytes())
-	if err != nil {
-		log.Printf("Err: Format buffer:\n%s", buf.bytes())
-		return err
-	}
-	if _, err = f.Write(b); err != nil {
-		return err
+	if gw.optionalStyle == OptionalAccessor {
+		writePresenceAccessors(buf, structName, optionals, namesRegistered)
 	}
-	return nil
+	return gw.writeFile(fileName, buf)
 }
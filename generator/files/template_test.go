@@ -0,0 +1,41 @@
+package files
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/generator/config"
+	"github.com/cruffinoni/rimworld-editor/generator/paths"
+)
+
+func TestGoWriter_externalType(t *testing.T) {
+	// A type of the runtime implements the interfaces it requires
+	importPath := paths.CodePackage + paths.MultipleTypesPath
+	c := &config.Config{
+		Overrides: []*config.Override{
+			{Path: "savegame>pawn>color", Type: "Type", Import: importPath},
+		},
+	}
+	noError(t, c.Validate())
+	s, registered := generate(t, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<name>Tynan</name>
+		<color>#ff0000</color>
+	</pawn>
+</savegame>`, generator.WithConfig(c))
+	dir := moduleDir(t)
+	gw := NewGoWriter(registered, true, "")
+	noError(t, gw.WriteGoFile(dir, s))
+
+	pawn := readFile(t, dir, "pawn.go")
+	assert.Contains(t, pawn, `"`+importPath+`"`)
+	assert.Contains(t, pawn, "*multiple.Type")
+	assert.Contains(t, pawn, "_ _interface.Assigner = (*multiple.Type)(nil)")
+	assert.Contains(t, pawn, "_ saver.Transformer   = (*multiple.Type)(nil)")
+	assert.Contains(t, pawn, `"`+paths.CodePackage+paths.XmlInterface+`"`)
+
+	output(t, goCommand(t, dir, "vet", "./..."))
+}
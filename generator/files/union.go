@@ -0,0 +1,70 @@
+package files
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/generator/paths"
+)
+
+// generateUnionToPath writes the type of u in its own file. It embeds
+// types.Union and chooses the variant from the shape of the element during
+// the unmarshalling. Each variant has its Is and As methods.
+func (gw *GoWriter) generateUnionToPath(path string, u *generator.Union) error {
+	fileName := path + "/" + strcase.ToSnake(u.Name) + ".go"
+	if gw.exists(fileName) {
+		return nil
+	}
+	buf := gw.newBuffer(path)
+	buf.writeImport(paths.HeaderXml)
+	buf.writeImport(paths.CustomTypesPath)
+	buf.writeImport(paths.XmlUnmarshal)
+	var (
+		typeName   = strcase.ToCamel(u.Name)
+		identifier = strings.ToLower(typeName[:1])
+		shapes     = make([]string, len(u.Variants))
+		names      = make([]string, len(u.Variants))
+	)
+	for i, v := range u.Variants {
+		shapes[i] = "unmarshal.Shape" + v.Shape.String()
+		names[i] = strcase.ToCamel(u.VariantName(v))
+	}
+	buf.writeToBody("// " + typeName + " holds a value whose shape varies: " + strings.Join(names, ", ") + ".\n" +
+		"// The value of an element matching none of them is kept as an *xml.Element.\n" +
+		"type " + typeName + " struct {\n\ttypes.Union\n}\n")
+	buf.writeToBody("\n// AssignElement assigns e to the variant matching its shape.\n" +
+		"func (" + identifier + " *" + typeName + ") AssignElement(e *xml.Element) error {\n" +
+		"\tswitch unmarshal.SelectShape(e, " + strings.Join(shapes, ", ") + ") {\n")
+	for i, v := range u.Variants {
+		goType := getTypeName(v.T)
+		if _, ok := v.T.(*generator.CustomType); ok && !strings.HasPrefix(goType, "*") {
+			goType = "*" + goType
+		}
+		writeTypeImports(v.T, buf)
+		buf.writeToBody("\tcase " + strconv.Itoa(i) + ":\n" +
+			"\t\tvar v " + goType + "\n" +
+			"\t\tif err := unmarshal.Value(e, &v); err != nil {\n\t\t\treturn err\n\t\t}\n" +
+			"\t\t" + identifier + ".Set(v)\n")
+		buf.writeToFooter("\n// Is" + names[i] + " reports whether the value is " + article(goType) + " " + goType + ".\n" +
+			"func (" + identifier + " *" + typeName + ") Is" + names[i] + "() bool {\n" +
+			"\t_, ok := " + identifier + ".Value().(" + goType + ")\n" +
+			"\treturn ok\n}\n")
+		buf.writeToFooter("\n// As" + names[i] + " returns the value if it's " + article(goType) + " " + goType + ".\n" +
+			"func (" + identifier + " *" + typeName + ") As" + names[i] + "() (" + goType + ", bool) {\n" +
+			"\tv, ok := " + identifier + ".Value().(" + goType + ")\n" +
+			"\treturn v, ok\n}\n")
+	}
+	buf.writeToBody("\tdefault:\n\t\t" + identifier + ".Keep(e)\n\t}\n\treturn nil\n}\n")
+	return gw.writeFile(fileName, buf)
+}
+
+// article returns the indefinite article of the type name goType.
+func article(goType string) string {
+	if goType != "" && strings.ContainsRune("aeiouAEIOU", rune(goType[0])) {
+		return "an"
+	}
+	return "a"
+}
@@ -0,0 +1,46 @@
+package files
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+)
+
+func TestGoWriter_union(t *testing.T) {
+	s, registered := generate(t, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<values>7</values>
+	</pawn>
+	<pawn>
+		<values>
+			<li>1</li>
+			<li>2</li>
+		</values>
+	</pawn>
+</savegame>`, generator.WithUnions(true))
+	dir := moduleDir(t)
+	gw := NewGoWriter(registered, true, "")
+	noError(t, gw.WriteGoFile(dir, s))
+
+	values := readFile(t, dir, "pawn_values.go")
+	assert.Contains(t, values, "types.Union\n")
+	assert.Contains(t, values, "reports whether the value is an int64.")
+	assert.Contains(t, values, "returns the value if it's an int64.")
+	assert.Contains(t, values, "reports whether the value is a *types.Slice[int64].")
+	output(t, goCommand(t, dir, "vet", "./..."))
+}
+
+func Test_article(t *testing.T) {
+	for goType, want := range map[string]string{
+		"int64":          "an",
+		"uint8":          "an",
+		"float64":        "a",
+		"*types.Slice[]": "a",
+		"Item":           "an",
+	} {
+		assert.Equal(t, want, article(goType), goType)
+	}
+}
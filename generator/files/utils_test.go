@@ -0,0 +1,102 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/cruffinoni/rimworld-editor/file"
+	"github.com/cruffinoni/rimworld-editor/generator"
+)
+
+// noError stops the test if err isn't nil. The package has its own require
+// type, so the require package of testify isn't imported.
+func noError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// generate infers the types of document. The writer renames the members, so
+// each writer is given its own types.
+func generate(t *testing.T, document string, opts ...generator.Option) (*generator.StructInfo, generator.MemberVersioning) {
+	t.Helper()
+	o, err := file.ReadFromBuffer(document)
+	noError(t, err)
+	g := generator.New(opts...)
+	s, err := g.GenerateGoFiles(o.XML.Root)
+	noError(t, err)
+	return s, g.RegisteredMembers
+}
+
+// write writes the types of document in dir with a writer configured by
+// opts, and returns the writer.
+func write(t *testing.T, dir, document string, opts ...WriterOption) *GoWriter {
+	t.Helper()
+	s, registered := generate(t, document)
+	gw := NewGoWriter(registered, true, "", opts...)
+	noError(t, gw.WriteGoFile(dir, s))
+	return gw
+}
+
+// readFile returns the content of the file name of dir.
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	noError(t, err)
+	return string(content)
+}
+
+// tempModule returns a new directory holding a go.mod of content goMod.
+func tempModule(t *testing.T, goMod string) string {
+	t.Helper()
+	root := t.TempDir()
+	noError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte(goMod), 0644))
+	return root
+}
+
+// moduleDir returns a directory of a temporary module, removed once the
+// test is done, where the generated code importing the runtime of this
+// module can be built. The runtime is replaced by the root of this module.
+// The directory doesn't exist, so the writer can delete it.
+func moduleDir(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	noError(t, err)
+	module := tempModule(t, fmt.Sprintf(`module example.com/app
+
+go 1.21
+
+require %[1]s v0.0.0
+
+replace %[1]s => %[2]s
+`, defaultRuntimeModule, strconv.Quote(root)))
+	return filepath.Join(module, "generated")
+}
+
+// goCommand returns the go command running with args in dir. The test is
+// skipped if Go isn't installed.
+func goCommand(t *testing.T, dir string, args ...string) *exec.Cmd {
+	t.Helper()
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go isn't installed")
+	}
+	cmd := exec.Command(goBin, args...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// output runs cmd and returns its output, it stops the test if cmd fails.
+func output(t *testing.T, cmd *exec.Cmd) string {
+	t.Helper()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %v\n%s", cmd.Args, err, out)
+	}
+	return string(out)
+}
@@ -1,47 +1,60 @@
 package generator
 
 import (
+	"reflect"
+
 	"github.com/cruffinoni/rimworld-editor/generator/paths"
-	"github.com/cruffinoni/rimworld-editor/helper"
 	"github.com/cruffinoni/rimworld-editor/xml"
 )
 
-func createArrayOrSlice(e *xml.Element, flag uint) any {
+func (g *Generator) createArrayOrSlice(e *xml.Element, flag uint) (any, error) {
 	k := e.Child
 	count := 0
 	for k != nil {
 		count++
 		if k.Data == nil && k.Child == nil && (count > 0 || k.Next != nil && k.Next.Next == nil) {
 			// Count must be > 0 because empty slice/array must be considered as slice
-			return createFixedArray(e, flag, &offset{
+			return g.createFixedArray(e, flag, &offset{
 				el:   k,
 				size: count - 1, // -1 maybe??
 			})
 		}
 		k = k.Next
 	}
-	return createCustomSlice(e, flag)
+	return g.createCustomSlice(e, flag)
 }
 
 const BasicStructName = "GeneratedStructStarter"
 
-func createTypeFromElement(n *xml.Element, flag uint) any {
+func (g *Generator) createTypeFromElement(n *xml.Element, flag uint) (any, error) {
+	if t, ok := g.overriddenType(n); ok {
+		return t, nil
+	}
 	childName := n.Child.GetName()
-	if helper.IsListTag(childName) {
-		return createArrayOrSlice(n, flag)
-	} else if childName == "keys" {
-		return createCustomTypeForMap(n, flag)
+	if g.dialect.IsListTag(childName) {
+		return g.createArrayOrSlice(n, flag)
+	} else if g.dialect.IsMapKeysTag(childName) {
+		return g.createCustomTypeForMap(n, flag)
 	} else if n.Child.Next != nil && n.Child.Next.GetName() == childName {
-		return createArrayOrSlice(n, flag|forceChild)
+		return g.createArrayOrSlice(n, flag|forceChild)
 	} else {
-		return createStructure(n, flag)
+		return g.createStructure(n, flag)
 	}
 }
 
-func processLeafNode(n *xml.Element, st *StructInfo, flag uint) {
-	var t any
+func (g *Generator) processLeafNode(n *xml.Element, st *StructInfo, flag uint) error {
+	var (
+		t   any
+		err error
+	)
+	if t, ok := g.overriddenType(n); ok {
+		return g.addMember(st, n, t)
+	}
 	if n.Data != nil {
 		t = n.Data.Kind()
+		if t == reflect.String && n.Attr.Empty() {
+			g.countValue(st, n.GetName(), n.Data.GetString())
+		}
 		if !n.Attr.Empty() {
 			t = &CustomType{
 				Name:       "Type",
@@ -51,45 +64,88 @@ func processLeafNode(n *xml.Element, st *StructInfo, flag uint) {
 			}
 		}
 	} else if n.Next != nil && n.Next.GetName() == n.GetName() {
-		t = createArrayOrSlice(n, flag)
+		if t, err = g.createArrayOrSlice(n, flag); err != nil {
+			if t, err = g.fallbackToElement(err, n); err != nil {
+				return err
+			}
+		}
 		for n.Next != nil && n.Next.GetName() == n.GetName() {
 			n = n.Next
 		}
 	} else {
 		t = createEmptyType()
 	}
-	st.addMember(n.GetName(), n.Attr, t)
+	return g.addMember(st, n, t)
 }
 
-func handleElement(e *xml.Element, st *StructInfo, flag uint) error {
-	n := e
-	//if n != nil && n.GetName() == "li" {
-	//	log.Printf("n: %v", n.GetName())
+// handleElement adds the members of e and its siblings, the children of an
+// element of st, to st.
+func (g *Generator) handleElement(e *xml.Element, st *StructInfo, flag uint) error {
+	//if e != nil && e.GetName() == "li" {
+	//	log.Printf("n: %v", e.GetName())
 	//}
 	if st.Name == "" {
 		*st = StructInfo{
-			Name:    addUniqueNumber(BasicStructName),
+			Name:    g.addUniqueNumber(BasicStructName),
 			Members: make(map[string]*Member),
 			Order:   make([]*Member, 0),
 		}
 	}
+	// The members are counted once for the element, even if they're
+	// repeated
+	counted := g.counted
+	g.counted = make(map[string]bool)
+	defer func() {
+		g.counted = counted
+	}()
+	if err := g.handleChildren(e, st, flag); err != nil {
+		return err
+	}
+	g.recordStruct(st, e)
+	if _, ok := g.paths[st]; !ok && e != nil && e.Parent != nil {
+		g.paths[st] = e.Parent.XMLPath()
+	}
+	g.RegisteredMembers[st.Name] = append(g.RegisteredMembers[st.Name], st)
+	return nil
+}
+
+// handleChildren adds the members of e and its siblings to st. The children
+// of the list items are members of st as well.
+func (g *Generator) handleChildren(e *xml.Element, st *StructInfo, flag uint) error {
+	n := e
 	for n != nil {
 		if n.Child != nil {
-			if helper.IsListTag(n.GetName()) {
-				if err := handleElement(n.Child, st, flag); err != nil {
+			if g.dialect.IsListTag(n.GetName()) {
+				if err := g.handleChildren(n.Child, st, flag); err != nil {
 					return err
 				}
 			} else {
-				st.addMember(n.GetName(), n.Attr, createTypeFromElement(n, flag))
+				t, err := g.createTypeFromElement(n, flag)
+				if err != nil {
+					if t, err = g.fallbackToElement(err, n); err != nil {
+						return err
+					}
+				}
+				if err = g.addMember(st, n, t); err != nil {
+					return err
+				}
+			}
+		} else if !g.dialect.IsListTag(n.GetName()) {
+			if err := g.processLeafNode(n, st, flag); err != nil {
+				return err
 			}
-		} else if !helper.IsListTag(n.GetName()) {
-			processLeafNode(n, st, flag)
 		} else {
-			t := createArrayOrSlice(n, flag)
-			st.addMember(n.GetName(), n.Attr, t)
+			t, err := g.createArrayOrSlice(n, flag)
+			if err != nil {
+				if t, err = g.fallbackToElement(err, n); err != nil {
+					return err
+				}
+			}
+			if err = g.addMember(st, n, t); err != nil {
+				return err
+			}
 		}
 		n = n.Next
 	}
-	RegisteredMembers[st.Name] = append(RegisteredMembers[st.Name], st)
 	return nil
 }
@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"github.com/cruffinoni/rimworld-editor/generator/config"
+	"github.com/cruffinoni/rimworld-editor/xml"
+	"github.com/cruffinoni/rimworld-editor/xml/dialect"
+)
+
+// Generator infers Go structures from XML elements. It owns every piece of
+// state of an inference, so several generators can run in parallel.
+type Generator struct {
+	// RegisteredMembers holds every version of the structures found during
+	// the last generation, indexed by structure name.
+	RegisteredMembers MemberVersioning
+
+	dialect *dialect.Dialect
+	config  *config.Config
+	// overrides maps the elements to the override of the configuration
+	// matching them.
+	overrides        map[*xml.Element]*config.Override
+	matchedOverrides map[*config.Override]bool
+	namingPolicy     NamingPolicy
+	deduplicated     map[string][]string
+	withMVFix        bool
+	lenient          bool
+	reporting        bool
+	report           *reportRecorder
+	problems         InferenceErrors
+	uniqueNames      map[string]int
+	// instances and occurrences count, for each version of a structure,
+	// the elements it has been created from and the elements of each
+	// member among them. attrOccurrences counts those of each attribute.
+	instances       map[*StructInfo]int
+	occurrences     map[*StructInfo]map[string]int
+	attrOccurrences map[*StructInfo]map[string]int
+	// paths are the XML paths of the first element each version of a
+	// structure has been created from.
+	paths map[*StructInfo]string
+	// counted are the members already counted for the element being
+	// handled.
+	counted       map[string]bool
+	enumThreshold int
+	// values holds the distinct values of the string members of each
+	// version of a structure.
+	values    map[*StructInfo]map[string]map[string]bool
+	typeNames map[string]bool
+	// generatedNames are the names given to the structures and the
+	// interfaces of the polymorphic items, renamedStructs the names given to
+	// the other structures whose name is taken by one of them, by the name
+	// asked for.
+	generatedNames  map[string]string
+	renamedStructs  map[string]string
+	classes         bool
+	unions          bool
+	typedAttributes bool
+	// variants are the names of the structures of polymorphic items.
+	variants map[string]bool
+	// ancestors are the structures being created, from the outermost.
+	ancestors []ancestor
+	// fixing holds the pairs of structures whose members are being fixed.
+	fixing map[[2]*StructInfo]bool
+	// seed and seedVersions are the structures every generation starts
+	// from, see WithSeed.
+	seed         *StructInfo
+	seedVersions MemberVersioning
+}
+
+// Option configures a Generator.
+type Option func(g *Generator)
+
+// WithMemberVersioningFix enables or disables the reconciliation of the
+// different versions of a structure once the inference is done.
+// It's enabled by default.
+func WithMemberVersioningFix(enabled bool) Option {
+	return func(g *Generator) {
+		g.withMVFix = enabled
+	}
+}
+
+// WithLenientInference makes the generation fall back to *xml.Element for
+// the members whose type can't be inferred instead of failing. The problems
+// are still reported, see Problems.
+func WithLenientInference(enabled bool) Option {
+	return func(g *Generator) {
+		g.lenient = enabled
+	}
+}
+
+// WithDialect sets how the collections are encoded in the XML files.
+// dialect.Default is used when d is nil.
+func WithDialect(d *dialect.Dialect) Option {
+	return func(g *Generator) {
+		g.dialect = dialect.Or(d)
+	}
+}
+
+// WithConfig applies the overrides of c to the generated structures.
+func WithConfig(c *config.Config) Option {
+	return func(g *Generator) {
+		g.config = c
+	}
+}
+
+// New creates a Generator configured with the given options.
+func New(opts ...Option) *Generator {
+	g := &Generator{
+		RegisteredMembers: make(MemberVersioning),
+		uniqueNames:       make(map[string]int),
+		instances:         make(map[*StructInfo]int),
+		occurrences:       make(map[*StructInfo]map[string]int),
+		attrOccurrences:   make(map[*StructInfo]map[string]int),
+		paths:             make(map[*StructInfo]string),
+		values:            make(map[*StructInfo]map[string]map[string]bool),
+		typeNames:         make(map[string]bool),
+		generatedNames:    make(map[string]string),
+		renamedStructs:    make(map[string]string),
+		variants:          make(map[string]bool),
+		fixing:            make(map[[2]*StructInfo]bool),
+		dialect:           dialect.Default,
+		withMVFix:         true,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// reset clears the state left by a previous generation.
+func (g *Generator) reset() {
+	g.RegisteredMembers = make(MemberVersioning)
+	g.uniqueNames = make(map[string]int)
+	g.instances = make(map[*StructInfo]int)
+	g.occurrences = make(map[*StructInfo]map[string]int)
+	g.attrOccurrences = make(map[*StructInfo]map[string]int)
+	g.paths = make(map[*StructInfo]string)
+	g.values = make(map[*StructInfo]map[string]map[string]bool)
+	g.typeNames = make(map[string]bool)
+	g.generatedNames = make(map[string]string)
+	g.renamedStructs = make(map[string]string)
+	g.variants = make(map[string]bool)
+	g.ancestors = nil
+	g.fixing = make(map[[2]*StructInfo]bool)
+	g.problems = nil
+	g.overrides = make(map[*xml.Element]*config.Override)
+	g.matchedOverrides = make(map[*config.Override]bool)
+	g.deduplicated = make(map[string][]string)
+	g.report = nil
+	if g.reporting {
+		g.report = newReportRecorder()
+	}
+}
+
+// Problems returns the problems met during the last lenient generation.
+func (g *Generator) Problems() InferenceErrors {
+	return g.problems
+}
@@ -0,0 +1,350 @@
+// Package jsonschema exports the structures inferred by the generator as a
+// JSON Schema, which validates the documents converted to JSON by
+// xml.Tree.MarshalJSON.
+package jsonschema
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+// Draft is the version of JSON Schema the schemas are written in.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+const (
+	defsPath = "#/$defs/"
+	// attributePattern matches the properties of the attributes.
+	attributePattern = "^" + xml.JSONAttributePrefix
+	integerPattern   = "^-?[0-9]+$"
+)
+
+type exporter struct {
+	registeredMember generator.MemberVersioning
+	defs             *object
+}
+
+// Write writes to w the JSON Schema of the documents whose structures are
+// inferred as root, the structure returned by the generator, once they're
+// converted to JSON. Its members are the root elements of the documents.
+// registeredMember are the structures registered by the generator, the
+// first version of each one is exported like the Go code.
+//
+// Every structure, enumeration, union and polymorphic type is defined in
+// $defs, named like its Go type. The attributes which aren't typed are
+// allowed like they're kept in the Attr map of the structures, and an empty
+// element, converted to null, is accepted where the XML Schema exported by
+// the xsd package accepts it.
+func Write(w io.Writer, root *generator.StructInfo, registeredMember generator.MemberVersioning) error {
+	e := &exporter{
+		registeredMember: registeredMember,
+		defs:             obj(),
+	}
+	properties := obj()
+	for _, m := range root.ElementMembers() {
+		properties.set(m.Element, e.typeOf(m.T))
+	}
+	// A document has a single root element
+	schema := obj(
+		"$schema", Draft,
+		"type", "object",
+		"properties", properties,
+		"minProperties", 1,
+		"maxProperties", 1,
+		"additionalProperties", false,
+	)
+	if len(e.defs.keys) > 0 {
+		schema.set("$defs", e.defs)
+	}
+	b, err := json.MarshalIndent(schema, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func ref(name string) *object {
+	return obj("$ref", defsPath+name)
+}
+
+// define adds the definition named name, unless it's already defined, which
+// also stops the recursive structures. It returns false if it's defined.
+func (e *exporter) define(name string, def *object) bool {
+	if e.defs.get(name) != nil {
+		return false
+	}
+	e.defs.set(name, def)
+	return true
+}
+
+// typeOf returns the schema of the JSON value of an element of type t.
+func (e *exporter) typeOf(t any) *object {
+	switch va := t.(type) {
+	case reflect.Kind:
+		return kindType(va)
+	case *generator.StructInfo:
+		return ref(e.structType(e.registeredMember.Best(va)))
+	case *generator.Enum:
+		return ref(e.enumType(va))
+	case *generator.Union:
+		return ref(e.unionType(va))
+	case *generator.Polymorphic:
+		return ref(e.polymorphicType(va))
+	case *generator.FixedArray:
+		// The missing items are written empty
+		item := nullable(e.typeOf(va.PrimaryType))
+		return listType(va.ItemTag, obj(
+			"type", "array",
+			"items", item,
+			"minItems", va.Size,
+			"maxItems", va.Size,
+		), item)
+	case *generator.CustomType:
+		switch {
+		case va.External || generator.IsMultipleType(va):
+			return obj()
+		case generator.IsEmptyType(va):
+			return emptyType()
+		case generator.IsEmbeddedType(va):
+			return e.embeddedType(va.Type1)
+		case va.Name == "Map":
+			return e.mapType(va)
+		case va.Name == "Slice":
+			item := e.typeOf(va.Type1)
+			return listType(va.ItemTag, obj("type", "array", "items", item), item)
+		}
+	}
+	// *xml.Element and the unknown types take any value
+	return obj()
+}
+
+// listType returns the schema of a list whose items are validated by array,
+// and each item by item. An empty list is null. The items written with the
+// tag of an element rather than the list tags are the property tag of an
+// object, like xml.Tree.MarshalJSON converts the repeated children: an
+// array, or the item itself if it's alone. The attributes of the list are
+// kept.
+func listType(tag string, array, item *object) *object {
+	if tag == "" {
+		return nullable(array)
+	}
+	return obj(
+		"type", []string{"object", "null"},
+		"properties", obj(tag, obj("anyOf", []*object{array, item})),
+		"patternProperties", attributeProperties(),
+		"additionalProperties", false,
+	)
+}
+
+// kindType returns the schema of the data of kind k. A text may look like a
+// number or a boolean, which are converted as such, and may be empty.
+func kindType(k reflect.Kind) *object {
+	switch k {
+	case reflect.String:
+		return obj("type", []string{"string", "number", "boolean", "null"})
+	case reflect.Int64:
+		return obj("type", "integer")
+	case reflect.Float64:
+		return obj("type", "number")
+	case reflect.Bool:
+		return obj("type", "boolean")
+	case reflect.Uint64:
+		// The hexadecimal numbers are kept as strings
+		return obj("type", "string")
+	}
+	return obj()
+}
+
+// attributeType returns the schema of an attribute of kind k.
+func attributeType(k reflect.Kind) *object {
+	if k == reflect.String {
+		return obj("type", []string{"string", "number", "boolean"})
+	}
+	return kindType(k)
+}
+
+// nullable returns the schema accepting null and the values of s.
+func nullable(s *object) *object {
+	switch t := s.get("type").(type) {
+	case string:
+		return s.set("type", []string{t, "null"})
+	case []string:
+		for _, v := range t {
+			if v == "null" {
+				return s
+			}
+		}
+		return s.set("type", append(t, "null"))
+	}
+	if len(s.keys) == 0 {
+		return s
+	}
+	return obj("anyOf", []*object{s, obj("type", "null")})
+}
+
+// attributeProperties returns the pattern of the properties of the
+// attributes which aren't typed.
+func attributeProperties() *object {
+	return obj(attributePattern, obj())
+}
+
+// emptyType returns the schema of an empty element, which may have
+// attributes.
+func emptyType() *object {
+	return obj(
+		"type", []string{"null", "object"},
+		"patternProperties", attributeProperties(),
+		"additionalProperties", false,
+	)
+}
+
+// embeddedType returns the schema of an element holding data of type t and
+// attributes. The elements of the same member without attributes are
+// converted to their data.
+func (e *exporter) embeddedType(t any) *object {
+	data := e.typeOf(t)
+	return obj("anyOf", []*object{
+		obj(
+			"type", "object",
+			"properties", obj(xml.JSONText, data),
+			"patternProperties", attributeProperties(),
+			"additionalProperties", false,
+		),
+		data,
+	})
+}
+
+// mapType returns the schema of the map c: an object if its keys are texts,
+// an array of key and value pairs otherwise. An empty map is an empty
+// object.
+func (e *exporter) mapType(c *generator.CustomType) *object {
+	values := e.typeOf(c.Type2)
+	switch k := c.Type1.(type) {
+	case reflect.Kind:
+		m := obj("type", "object", "additionalProperties", values)
+		if k == reflect.Int64 {
+			m.set("propertyNames", obj("pattern", integerPattern))
+		}
+		return m
+	case *generator.Enum:
+		return obj(
+			"type", "object",
+			"propertyNames", obj("enum", k.Values),
+			"additionalProperties", values,
+		)
+	}
+	return obj(
+		"type", []string{"array", "object"},
+		"items", obj(
+			"type", "object",
+			"properties", obj(xml.JSONMapKey, e.typeOf(c.Type1), xml.JSONMapValue, values),
+			"required", []string{xml.JSONMapKey, xml.JSONMapValue},
+			"additionalProperties", false,
+		),
+		"maxProperties", 0,
+	)
+}
+
+// structType defines the schema of s and returns its name. A structure
+// whose members and attributes are all optional may be empty.
+func (e *exporter) structType(s *generator.StructInfo) string {
+	name := strcase.ToCamel(s.Name)
+	def := obj()
+	if !e.define(name, def) {
+		return name
+	}
+	var (
+		properties = obj()
+		required   []string
+	)
+	for _, m := range s.ElementMembers() {
+		properties.set(m.Element, e.typeOf(m.T))
+		if !s.IsOptional(m.Member) {
+			required = append(required, m.Element)
+		}
+	}
+	for _, a := range s.Attributes {
+		property := xml.JSONAttributePrefix + a.Name
+		properties.set(property, attributeType(a.T))
+		if !s.IsOptionalAttribute(a) {
+			required = append(required, property)
+		}
+	}
+	if len(required) > 0 {
+		def.set("type", "object")
+	} else {
+		def.set("type", []string{"object", "null"})
+	}
+	if len(properties.keys) > 0 {
+		def.set("properties", properties)
+	}
+	if len(required) > 0 {
+		def.set("required", required)
+	}
+	def.set("patternProperties", attributeProperties())
+	def.set("additionalProperties", false)
+	return name
+}
+
+// enumType defines the schema of en and returns its name. The values are
+// converted like the data of the elements.
+func (e *exporter) enumType(en *generator.Enum) string {
+	name := strcase.ToCamel(en.Name)
+	values := make([]any, 0, len(en.Values))
+	for _, v := range en.Values {
+		values = append(values, xml.CreateDataType(v).JSONValue())
+	}
+	e.define(name, obj("enum", values))
+	return name
+}
+
+// unionType defines the schema of u, which accepts the values of any of its
+// variants, and returns its name.
+func (e *exporter) unionType(u *generator.Union) string {
+	name := strcase.ToCamel(u.Name)
+	def := obj()
+	if !e.define(name, def) {
+		return name
+	}
+	variants := make([]*object, 0, len(u.Variants))
+	for _, v := range u.Variants {
+		variants = append(variants, e.typeOf(v.T))
+	}
+	def.set("anyOf", variants)
+	return name
+}
+
+// polymorphicType defines the schema of p and returns its name. An item is
+// validated by the structure of its Class attribute, or by the default
+// structure if it has none.
+func (e *exporter) polymorphicType(p *generator.Polymorphic) string {
+	name := strcase.ToCamel(p.Name)
+	def := obj()
+	if !e.define(name, def) {
+		return name
+	}
+	class := xml.JSONAttributePrefix + generator.ClassAttribute
+	variants := make([]*object, 0, len(p.Variants)+1)
+	for _, c := range p.Classes() {
+		variants = append(variants, obj(
+			"$ref", defsPath+e.structType(e.registeredMember.Best(p.Variants[c])),
+			"type", "object",
+			"properties", obj(class, obj("const", c)),
+			"required", []string{class},
+		))
+	}
+	if p.Default != nil {
+		variants = append(variants, obj(
+			"$ref", defsPath+e.structType(e.registeredMember.Best(p.Default)),
+			"not", obj("type", "object", "required", []string{class}),
+		))
+	}
+	def.set("anyOf", variants)
+	return name
+}
@@ -0,0 +1,340 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/file"
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+// export returns the schema of documents and the documents converted to
+// JSON.
+func export(t *testing.T, documents []string, opts ...generator.Option) (map[string]any, []any) {
+	t.Helper()
+	var (
+		roots     = make([]*xml.Element, 0, len(documents))
+		converted = make([]any, 0, len(documents))
+	)
+	for _, d := range documents {
+		o, err := file.ReadFromBuffer(d)
+		require.NoError(t, err)
+		roots = append(roots, o.XML.Root)
+		b, err := json.Marshal(o.XML)
+		require.NoError(t, err)
+		var v any
+		require.NoError(t, json.Unmarshal(b, &v))
+		converted = append(converted, v)
+	}
+	g := generator.New(opts...)
+	s, err := g.GenerateGoFilesFromCorpus(roots)
+	require.NoError(t, err)
+	var sb strings.Builder
+	require.NoError(t, Write(&sb, s, g.RegisteredMembers))
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal([]byte(sb.String()), &schema))
+	return schema, converted
+}
+
+// validate validates v against the schema s, whose definitions are in root.
+// It implements the keywords written by the exporter.
+func validate(root, s map[string]any, v any) error {
+	if r, ok := s["$ref"].(string); ok {
+		def := root["$defs"].(map[string]any)[strings.TrimPrefix(r, defsPath)].(map[string]any)
+		if err := validate(root, def, v); err != nil {
+			return err
+		}
+	}
+	if t, ok := s["type"]; ok && !hasType(t, v) {
+		return fmt.Errorf("%v isn't of type %v", v, t)
+	}
+	if values, ok := s["enum"].([]any); ok && !contains(values, v) {
+		return fmt.Errorf("%v isn't one of %v", v, values)
+	}
+	if c, ok := s["const"]; ok && c != v {
+		return fmt.Errorf("%v isn't %v", v, c)
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		var errs []string
+		for _, sub := range anyOf {
+			err := validate(root, sub.(map[string]any), v)
+			if err == nil {
+				errs = nil
+				break
+			}
+			errs = append(errs, err.Error())
+		}
+		if errs != nil {
+			return fmt.Errorf("no schema of anyOf matches: %s", strings.Join(errs, "; "))
+		}
+	}
+	if not, ok := s["not"].(map[string]any); ok && validate(root, not, v) == nil {
+		return fmt.Errorf("%v matches the not schema", v)
+	}
+	switch va := v.(type) {
+	case []any:
+		if n, ok := s["minItems"].(float64); ok && len(va) < int(n) {
+			return fmt.Errorf("%d items instead of at least %v", len(va), n)
+		}
+		if n, ok := s["maxItems"].(float64); ok && len(va) > int(n) {
+			return fmt.Errorf("%d items instead of at most %v", len(va), n)
+		}
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, item := range va {
+				if err := validate(root, items, item); err != nil {
+					return fmt.Errorf("[%d]: %w", i, err)
+				}
+			}
+		}
+	case map[string]any:
+		return validateObject(root, s, va)
+	}
+	return nil
+}
+
+func validateObject(root, s map[string]any, v map[string]any) error {
+	if n, ok := s["minProperties"].(float64); ok && len(v) < int(n) {
+		return fmt.Errorf("%d properties instead of at least %v", len(v), n)
+	}
+	if n, ok := s["maxProperties"].(float64); ok && len(v) > int(n) {
+		return fmt.Errorf("%d properties instead of at most %v", len(v), n)
+	}
+	if required, ok := s["required"].([]any); ok {
+		for _, name := range required {
+			if _, ok := v[name.(string)]; !ok {
+				return fmt.Errorf("missing property %v", name)
+			}
+		}
+	}
+	properties, _ := s["properties"].(map[string]any)
+	patterns, _ := s["patternProperties"].(map[string]any)
+	for name, value := range v {
+		if names, ok := s["propertyNames"].(map[string]any); ok {
+			if err := validate(root, names, name); err != nil {
+				return fmt.Errorf("property name %q: %w", name, err)
+			}
+			if p, ok := names["pattern"].(string); ok && !regexp.MustCompile(p).MatchString(name) {
+				return fmt.Errorf("property name %q doesn't match %s", name, p)
+			}
+		}
+		matched := false
+		if sub, ok := properties[name].(map[string]any); ok {
+			matched = true
+			if err := validate(root, sub, value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		for p, sub := range patterns {
+			if regexp.MustCompile(p).MatchString(name) {
+				matched = true
+				if err := validate(root, sub.(map[string]any), value); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+		}
+		if matched {
+			continue
+		}
+		switch additional := s["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("unexpected property %q", name)
+			}
+		case map[string]any:
+			if err := validate(root, additional, value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func hasType(t any, v any) bool {
+	if types, ok := t.([]any); ok {
+		for _, t := range types {
+			if hasType(t, v) {
+				return true
+			}
+		}
+		return false
+	}
+	switch va := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || t == "integer" && va == math.Trunc(va)
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+func contains(values []any, v any) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWrite(t *testing.T) {
+	documents := []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<game>
+		<tickCount>1500</tickCount>
+		<speed>1.50</speed>
+		<paused>True</paused>
+		<name>1</name>
+		<empty />
+		<label lang="en">hello</label>
+		<grid><li></li><li>1</li><li></li></grid>
+		<weights>
+			<keys><li>a</li><li>b</li></keys>
+			<values><li>1</li><li>2</li></values>
+		</weights>
+		<cells>
+			<keys><li><x>1</x><z>2</z></li></keys>
+			<values><li>Sand</li></values>
+		</cells>
+		<pawns>
+			<li id="1"><name>Tynan</name><age>30</age><gender>Male</gender></li>
+			<li id="2"><name>Randy</name><gender>Female</gender></li>
+		</pawns>
+	</game>
+</savegame>`, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<game>
+		<tickCount>3000</tickCount>
+		<speed>2</speed>
+		<paused>False</paused>
+		<name>Colony</name>
+		<empty />
+		<label>bye</label>
+		<grid><li>2</li><li></li><li>3</li></grid>
+		<weights>
+			<keys><li>c</li></keys>
+			<values><li>3</li></values>
+		</weights>
+		<cells>
+			<keys><li><x>3</x><z>4</z></li></keys>
+			<values><li>Soil</li></values>
+		</cells>
+		<pawns />
+	</game>
+</savegame>`}
+	schema, converted := export(t, documents, generator.WithAttributes(true))
+
+	assert.Equal(t, Draft, schema["$schema"])
+	for i, v := range converted {
+		assert.NoError(t, validate(schema, schema, v), "document %d", i)
+	}
+	defs := schema["$defs"].(map[string]any)
+	game := defs["Game"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "integer"}, game["tickCount"])
+	assert.Equal(t, map[string]any{"type": "number"}, game["speed"])
+	assert.Equal(t, map[string]any{"type": "boolean"}, game["paused"])
+	assert.Equal(t, []any{"null", "object"}, game["empty"].(map[string]any)["type"])
+	assert.Equal(t, 3.0, game["grid"].(map[string]any)["maxItems"])
+	assert.Equal(t, "object", game["weights"].(map[string]any)["type"])
+	assert.Equal(t, []any{"array", "object"}, game["cells"].(map[string]any)["type"])
+	pawns := defs["Pawns"].(map[string]any)
+	assert.Equal(t, []any{"name", "gender", "@id"}, pawns["required"])
+
+	t.Run("enums", func(t *testing.T) {
+		schema, converted := export(t, []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawns>
+		<li><name>Tynan</name><gender>Male</gender></li>
+		<li><name>Randy</name><gender>Male</gender></li>
+		<li><name>Cassandra</name><gender>Female</gender></li>
+	</pawns>
+</savegame>`}, generator.WithEnums(2))
+		require.NoError(t, validate(schema, schema, converted[0]))
+		defs := schema["$defs"].(map[string]any)
+		assert.Equal(t, map[string]any{"enum": []any{"Female", "Male"}}, defs["PawnsGender"])
+	})
+
+	t.Run("conversion", func(t *testing.T) {
+		o, err := file.ReadFromBuffer(documents[0])
+		require.NoError(t, err)
+		b, err := json.Marshal(o.XML)
+		require.NoError(t, err)
+		got := string(b)
+		assert.True(t, strings.HasPrefix(got, `{"savegame":{"game":{"tickCount":1500,"speed":1.5,"paused":true,"name":1,"empty":null,`))
+		assert.Contains(t, got, `"label":{"@lang":"en","#text":"hello"}`)
+		assert.Contains(t, got, `"grid":[null,1,null]`)
+		assert.Contains(t, got, `"weights":{"a":1,"b":2}`)
+		assert.Contains(t, got, `"cells":[{"key":{"x":1,"z":2},"value":"Sand"}]`)
+		assert.Contains(t, got, `"pawns":[{"@id":1,"name":"Tynan","age":30,"gender":"Male"},{"@id":2,"name":"Randy","gender":"Female"}]`)
+	})
+
+	t.Run("invalid documents", func(t *testing.T) {
+		for _, document := range []string{
+			`{"savegame": {"game": {"tickCount": "soon"}}}`,
+			`{"savegame": {"game": {"grid": [1, 2]}}}`,
+			`{"savegame": {"game": {"pawns": [{"name": "Tynan", "gender": "Male"}]}}}`,
+			`{"savegame": {"game": {"pawns": [{"@id": 3, "name": "Tynan", "gender": "Other"}]}}}`,
+			`{"savegame": {"game": {"unknown": 1}}}`,
+			`{"savegame": {}, "other": {}}`,
+		} {
+			var v any
+			require.NoError(t, json.Unmarshal([]byte(document), &v))
+			assert.Error(t, validate(schema, schema, v), document)
+		}
+	})
+
+	t.Run("repeated children", func(t *testing.T) {
+		schema, converted := export(t, []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<things>
+		<thing><def>Hat</def></thing>
+		<thing><def>Gun</def></thing>
+	</things>
+	<grid><cell>1</cell><cell></cell></grid>
+</savegame>`, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<things count="1">
+		<thing><def>Rock</def></thing>
+	</things>
+	<grid><cell></cell><cell>2</cell></grid>
+</savegame>`})
+		for i, v := range converted {
+			assert.NoError(t, validate(schema, schema, v), "document %d", i)
+		}
+
+		var v any
+		require.NoError(t, json.Unmarshal([]byte(`{"savegame": {"things": [{"def": "Hat"}], "grid": {"cell": [1, 2]}}}`), &v))
+		assert.Error(t, validate(schema, schema, v))
+	})
+
+	t.Run("polymorphic", func(t *testing.T) {
+		schema, converted := export(t, []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<things>
+		<li Class="Apparel"><def>Hat</def><hp>10</hp></li>
+		<li Class="Weapon"><def>Gun</def><range>20.5</range></li>
+		<li><def>Rock</def></li>
+	</things>
+</savegame>`}, generator.WithClassPolymorphism(true))
+		require.NoError(t, validate(schema, schema, converted[0]))
+
+		var v any
+		require.NoError(t, json.Unmarshal([]byte(`{"savegame": {"things": [{"@Class": "Apparel", "def": "Hat", "range": 1}]}}`), &v))
+		assert.Error(t, validate(schema, schema, v))
+	})
+}
@@ -0,0 +1,61 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// object is a JSON object which keeps the order of its properties.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+// obj returns the object with the properties props, given as name-value
+// pairs. The properties with a nil value are left out.
+func obj(props ...any) *object {
+	o := &object{values: make(map[string]any)}
+	for i := 0; i+1 < len(props); i += 2 {
+		o.set(props[i].(string), props[i+1])
+	}
+	return o
+}
+
+// set sets the property name of o to value, unless value is nil.
+func (o *object) set(name string, value any) *object {
+	if value == nil {
+		return o
+	}
+	if _, ok := o.values[name]; !ok {
+		o.keys = append(o.keys, name)
+	}
+	o.values[name] = value
+	return o
+}
+
+func (o *object) get(name string) any {
+	return o.values[name]
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"fmt"
 	"log"
 	"math"
 	"reflect"
@@ -50,7 +51,7 @@ func containsParentMember(s *StructInfo, a *Member) bool {
 	return false
 }
 
-func consolidateSubStructures(newMember, existingStruct *StructInfo) error {
+func (g *Generator) consolidateSubStructures(newMember, existingStruct *StructInfo) error {
 	if _, ok := existingStruct.Members[newMember.Name]; !ok {
 		m := &Member{
 			T:    newMember,
@@ -60,7 +61,7 @@ func consolidateSubStructures(newMember, existingStruct *StructInfo) error {
 		existingStruct.Members[newMember.Name] = m
 		existingStruct.Order = append(existingStruct.Order, m)
 	}
-	return fixTypeMismatch(&Member{
+	return g.fixTypeMismatch(&Member{
 		T:    existingStruct.Members[newMember.Name],
 		Attr: nil,
 		Name: newMember.Name,
@@ -71,7 +72,32 @@ func consolidateSubStructures(newMember, existingStruct *StructInfo) error {
 	})
 }
 
-func fixTypeMismatch(a, b *Member) error {
+func (g *Generator) fixTypeMismatch(a, b *Member) error {
+	// The types given by the configuration win over the inferred ones
+	if a.Pinned || b.Pinned {
+		if b.Pinned && !a.Pinned {
+			a, b = b, a
+		}
+		b.T = a.T
+		b.Pinned = true
+		g.tagBranch(BranchPinned)
+		return nil
+	}
+	if _, ok := a.T.(*Union); ok {
+		return g.unite(&a.T, &b.T)
+	} else if _, ok = b.T.(*Union); ok {
+		return g.unite(&a.T, &b.T)
+	}
+	if _, ok := a.T.(*Polymorphic); ok {
+		return g.fixPolymorphic(&a.T, &b.T)
+	} else if _, ok = b.T.(*Polymorphic); ok {
+		return g.fixPolymorphic(&a.T, &b.T)
+	}
+	if _, ok := a.T.(*Enum); ok {
+		return g.fixEnum(a, b)
+	} else if _, ok = b.T.(*Enum); ok {
+		return g.fixEnum(a, b)
+	}
 	//log.Printf("Types mismatch: %v (%T) & %v (%T)", getTypeName(a.T), a.T, getTypeName(b.T), b.T)
 	switch va := a.T.(type) {
 	// a: *CustomType
@@ -81,6 +107,7 @@ func fixTypeMismatch(a, b *Member) error {
 		// a: *CustomType[*primary.Empty]
 		// b: ?
 		if IsEmptyType(va) {
+			g.tagBranch(BranchEmptyToConcrete)
 			a.T = b.T
 			return nil
 		}
@@ -90,10 +117,12 @@ func fixTypeMismatch(a, b *Member) error {
 		case *CustomType:
 			//printer.Printf("Fixing 2 custom types: %+v & %+v", va.Type1, vb.Type1)
 			if IsEmptyType(vb) {
+				g.tagBranch(BranchEmptyToConcrete)
 				b.T = a.T
 				return nil
 			}
-			return fixCustomType(va, vb)
+			g.tagBranch(BranchCustomTypes)
+			return g.fixCustomType(va, vb)
 
 		// a: *CustomType[?]
 		// b: *StructInfo
@@ -103,9 +132,11 @@ func fixTypeMismatch(a, b *Member) error {
 			// a: CustomType[*StructInfo]
 			// b: *StructInfo
 			if sliceStructType, ok := va.Type1.(*StructInfo); ok {
-				return consolidateSubStructures(sliceStructType, vb)
+				g.tagBranch(BranchStructConsolidation)
+				return g.consolidateSubStructures(sliceStructType, vb)
 			} else {
 				log.Printf("sub type A not handled: %T (%+v)", va.Type1, va)
+				g.tagBranch(BranchReplaced)
 				a.T = b.T
 			}
 
@@ -116,6 +147,7 @@ func fixTypeMismatch(a, b *Member) error {
 				a.T = b.T
 				return nil
 			}
+			g.tagBranch(BranchArrayOverSlice)
 			switch vbPrimaryType := vb.PrimaryType.(type) {
 
 			// a: *CustomType[?]
@@ -126,10 +158,10 @@ func fixTypeMismatch(a, b *Member) error {
 				// b: *FixedArray[*StructInfo]
 				sliceStructType, ok := va.Type1.(*StructInfo)
 				if !ok {
-					log.Fatalf("SliceStruct in FixedArray detected but the primary type is not supported: %T (%v)", va.Type1, va.Type1)
+					return newInferenceError(ErrUnhandledType, "fixed array of structures mixed with an unsupported custom type", va.Type1, vbPrimaryType)
 				}
 				//log.Printf("Arr: %v & %v", vbPrimaryType.Name, sliceStructType.Name)
-				return fixTypeMismatch(&Member{
+				return g.fixTypeMismatch(&Member{
 					T:    vbPrimaryType,
 					Attr: nil,
 					Name: vbPrimaryType.Name,
@@ -143,12 +175,13 @@ func fixTypeMismatch(a, b *Member) error {
 			// b: *FixedArray[*CustomType[?]]
 			case *CustomType:
 				if IsEmptyType(vbPrimaryType) {
+					g.tagBranch(BranchEmptyToConcrete)
 					vb.PrimaryType = va.Type1
 					// FixedArray take priority over CustomType because slice are detected by default but array are a specialized slice
 					a.T = b.T
 					return nil
 				}
-				return fixTypeMismatch(&Member{
+				return g.fixTypeMismatch(&Member{
 					T:    vbPrimaryType,
 					Attr: nil,
 					Name: vbPrimaryType.Name,
@@ -167,21 +200,21 @@ func fixTypeMismatch(a, b *Member) error {
 				return nil
 			}
 			if !IsEmbeddedType(va) {
-				log.Panicf("type B not handled: %+v (%T) | %+v (%T)", a.T, a.T, b.T, b.T)
-				return nil
+				return newInferenceError(ErrUnhandledType, "custom type mixed with a primary type", a.T, b.T)
 			}
+			g.tagBranch(BranchEmbeddedPrimary)
 			b.T = a.T
 
 		default:
 			// a: *CustomType[*embedded.Primary]
 			// b: ?
-			log.Printf("Is IsEmbeddedPrimaryType? %v (%s)", embedded.IsEmbeddedPrimaryType(getTypeName(a.T)), getTypeName(a.T))
-			log.Panicf("type B not handled: %+v (%T) | %+v (%T)", a.T, a.T, b.T, b.T)
+			return newInferenceError(ErrUnhandledType, fmt.Sprintf("custom type mixed with %T (embedded primary type: %v)", b.T, embedded.IsEmbeddedPrimaryType(getTypeName(a.T))), a.T, b.T)
 		}
 	case *StructInfo:
 		if bStruct, okStruct := b.T.(*StructInfo); okStruct {
 			if !containsParentMember(va, a) {
-				FixMembers(va, bStruct)
+				g.tagBranch(BranchStructConsolidation)
+				return g.FixMembers(va, bStruct)
 			}
 			return nil
 		} else {
@@ -189,7 +222,7 @@ func fixTypeMismatch(a, b *Member) error {
 			// To avoid code duplication, we just swap those 2 and run through the actual code
 			switch b.T.(type) {
 			case *FixedArray, *CustomType, reflect.Kind:
-				if err := fixTypeMismatch(b, a); err != nil {
+				if err := g.fixTypeMismatch(b, a); err != nil {
 					return err
 				}
 				a.T = b.T
@@ -198,11 +231,12 @@ func fixTypeMismatch(a, b *Member) error {
 	case *FixedArray:
 		if bFArr, okStruct := b.T.(*FixedArray); okStruct {
 			if va.Size != bFArr.Size {
+				g.tagBranch(BranchFixedArraySize)
 				va.Size = int(math.Max(float64(va.Size), float64(bFArr.Size)))
 				bFArr.Size = va.Size
 			}
-			if !IsSameType(bFArr.PrimaryType, va.PrimaryType, 0) {
-				return fixTypeMismatch(&Member{
+			if !IsSameType(bFArr.PrimaryType, va.PrimaryType) {
+				return g.fixTypeMismatch(&Member{
 					T:    bFArr.PrimaryType,
 					Attr: nil,
 					Name: "",
@@ -215,7 +249,7 @@ func fixTypeMismatch(a, b *Member) error {
 				//return nil
 				//switch b.T.(type) {
 				//case *FixedArray, *CustomType, reflect.Kind:
-				//	if err := fixTypeMismatch(b, a); err != nil {
+				//	if err := g.fixTypeMismatch(b, a); err != nil {
 				//		return err
 				//	}
 				//	a.T = b.T
@@ -225,7 +259,7 @@ func fixTypeMismatch(a, b *Member) error {
 			log.Printf("Mismatch between FixedArray (%v) & %T (%v)", getTypeName(a), b.T, getTypeName(b.T))
 			switch b.T.(type) {
 			case *FixedArray, *CustomType, reflect.Kind:
-				if err := fixTypeMismatch(b, a); err != nil {
+				if err := g.fixTypeMismatch(b, a); err != nil {
 					return err
 				}
 				a.T = b.T
@@ -237,8 +271,13 @@ func fixTypeMismatch(a, b *Member) error {
 			// We have completely 2 different types with same name. Example of tag <name> which might be a structure representing the name, forename and surname
 			// of a pawn but can be also a string for "feature" tag.
 			if isRelevantType(b.T) {
-				b.Name = addUniqueNumber(b.Name)
+				if g.unite(&a.T, &b.T) == nil {
+					return nil
+				}
+				g.tagBranch(BranchFirstTypeKept)
+				b.Name = g.addUniqueNumber(b.Name)
 			} else {
+				g.tagBranch(BranchEmptyToConcrete)
 				b.T = a.T
 			}
 		}
@@ -246,36 +285,46 @@ func fixTypeMismatch(a, b *Member) error {
 		// Previous type is an int64 and the next type might an int overflow considered as a string
 		if va == reflect.Int64 && bt == reflect.String {
 			log.Printf("Previous type is an int64 and the next type might an int overflow considered as a string")
+			g.tagBranch(BranchIntToString)
 			a.T = reflect.String
 		} else if va == reflect.Int64 && bt == reflect.Float64 {
+			g.tagBranch(BranchIntToFloat)
 			a.T = reflect.Float64
 		} else if va == reflect.Float64 && bt == reflect.Int64 {
+			g.tagBranch(BranchIntToFloat)
 			b.T = reflect.Float64
 		}
 	}
 	return nil
 }
 
-const MaxDepth = 50
+// typeComparer compares types which may refer to themselves.
+type typeComparer struct {
+	// visiting holds the pairs of structures being compared to stop on
+	// recursive structures.
+	visiting map[[2]*StructInfo]bool
+}
 
-func hasSameMembers(a, b *StructInfo, depth uint32) bool {
-	//log.Printf("A: %v & b %v => %d", a.Name, b.Name, depth)
-	if depth > MaxDepth {
-		panic("max depth reached")
-		return false
-	}
+func hasSameMembers(a, b *StructInfo) bool {
+	return (&typeComparer{visiting: make(map[[2]*StructInfo]bool)}).hasSameMembers(a, b)
+}
+
+func (c *typeComparer) hasSameMembers(a, b *StructInfo) bool {
 	if len(a.Members) != len(b.Members) {
 		return false
 	}
+	// A pair being compared is assumed identical, the other members decide
+	pair := [2]*StructInfo{a, b}
+	if a == b || c.visiting[pair] {
+		return true
+	}
+	c.visiting[pair] = true
+	defer delete(c.visiting, pair)
 	for i := range a.Members {
 		if _, ok := b.Members[i]; !ok {
 			return false
 		}
-		if getTypeName(a.Name) == getTypeName(a.Members[i].Name) ||
-			getTypeName(a.Name) == getTypeName(b.Members[i].Name) {
-			return false
-		}
-		if !IsSameType(a.Members[i], b.Members[i], depth+1) {
+		if !c.isSameType(a.Members[i], b.Members[i]) {
 			return false
 		}
 	}
@@ -288,6 +337,10 @@ func getTypeName(a any) string {
 		return getTypeName(va.Type1)
 	case *StructInfo:
 		return va.Name
+	case *Polymorphic:
+		return va.Name
+	case *Union:
+		return va.Name
 	case *FixedArray:
 		return getTypeName(va.PrimaryType)
 	case *Member:
@@ -304,13 +357,12 @@ func getTypeName(a any) string {
 }
 
 // IsSameType compares the types of two objects a and b and returns true if they are the same type.
-// depth is used to prevent infinite recursion in case of nested types.
-func IsSameType(a, b any, depth uint32) bool {
-	// If depth has exceeded MaxDepth, log a message and return false to stop further recursion.
-	if depth > MaxDepth {
-		log.Printf("Max depth reached: %v", depth)
-		return false
-	}
+// The structures referring to themselves are compared once, see typeComparer.
+func IsSameType(a, b any) bool {
+	return (&typeComparer{visiting: make(map[[2]*StructInfo]bool)}).isSameType(a, b)
+}
+
+func (c *typeComparer) isSameType(a, b any) bool {
 	// If either a or b is nil, compare their equality and return the result.
 	if a == nil || b == nil {
 		return a == b
@@ -322,14 +374,15 @@ func IsSameType(a, b any, depth uint32) bool {
 		if bType, ok := b.(*CustomType); ok && bType != nil {
 			// If the type's name is the same as the parent's name, return false to avoid infinite recursion.
 			if getTypeName(va.Type1) == "" || getTypeName(bType.Type1) == "" {
-				log.Panicf("type name is empty: %T > %+v | %T > %+v", va.Type1, va, bType.Type1, bType)
+				log.Printf("type name is empty: %T > %+v | %T > %+v", va.Type1, va, bType.Type1, bType)
+				return false
 			}
 			//if getTypeName(va.Type1) == getTypeName(bType.Type1) || getTypeName(va.Type2) == getTypeName(bType.Type2) {
 			//	return false
 			//}
-			// Compare the properties of CustomType a with b recursively using IsSameType.
+			// Compare the properties of CustomType a with b recursively using isSameType.
 			return va.Name == bType.Name && va.Pkg == bType.Pkg &&
-				IsSameType(bType.Type1, va.Type1, depth+1) && IsSameType(bType.Type2, va.Type2, depth+1)
+				c.isSameType(bType.Type1, va.Type1) && c.isSameType(bType.Type2, va.Type2)
 		} else {
 			return false
 		}
@@ -337,23 +390,29 @@ func IsSameType(a, b any, depth uint32) bool {
 		// If "a" is a pointer to StructInfo, compare its properties with b.
 		if bType, ok := b.(*StructInfo); ok && bType != nil {
 			// Compare the properties of StructInfo a with b recursively using hasSameMembers.
-			return va.Name == bType.Name && hasSameMembers(bType, va, depth+1)
+			return va.Name == bType.Name && c.hasSameMembers(bType, va)
 		} else {
 			return false
 		}
+	case *Polymorphic:
+		bPoly, ok := b.(*Polymorphic)
+		return ok && isSamePolymorphic(va, bPoly)
+	case *Union:
+		bUnion, ok := b.(*Union)
+		return ok && isSameUnion(c, va, bUnion)
 	case *FixedArray:
 		// If "a" is a pointer to FixedArray, compare its properties with b.
 		if bFixArr, ok := b.(*FixedArray); ok && bFixArr != nil {
-			// Compare the properties of FixedArray a with b recursively using IsSameType.
-			return IsSameType(va.PrimaryType, bFixArr.PrimaryType, depth+1) && va.Size == bFixArr.Size
+			// Compare the properties of FixedArray a with b recursively using isSameType.
+			return c.isSameType(va.PrimaryType, bFixArr.PrimaryType) && va.Size == bFixArr.Size
 		} else {
 			return false
 		}
 	case *Member:
 		// If "a" is a pointer to Member, compare its properties with b.
 		if bMember, ok := b.(*Member); ok && bMember != nil {
-			// Compare the properties of Member a with b recursively using IsSameType.
-			return va.Name == bMember.Name && IsSameType(bMember.T, va.T, depth+1)
+			// Compare the properties of Member a with b recursively using isSameType.
+			return va.Name == bMember.Name && c.isSameType(bMember.T, va.T)
 		} else {
 			return false
 		}
@@ -386,68 +445,62 @@ func updateOrderedMembers(a *StructInfo) {
 	}
 }
 
-func detectForRecursiveStructure(a *StructInfo, detected bool) bool {
-	for _, i := range a.Order {
-		if i.Name == a.Name {
-			log.Printf("Struct has a member with the same name of the struct")
-
-			//os.Exit(0)
-			return true
-		}
-	}
-	return false
-}
-
-var TmpFixMemberCall = 0
-
-func FixMembers(a, b *StructInfo) {
-	TmpFixMemberCall++
-	//log.Printf("Tmp: %d", TmpFixMemberCall)
-	if TmpFixMemberCall > 100000 {
-		log.Panicf("Probable infinite recursion w/ %v/%v (a/b) calling himself.", a.Name, b.Name)
+// FixMembers merges the members of a and b into each other and fixes the
+// type mismatches between the members they have in common.
+func (g *Generator) FixMembers(a, b *StructInfo) error {
+	// The structures already being fixed are reached again through a
+	// recursive member, they are left to the first call
+	if a == b || g.fixing[[2]*StructInfo{a, b}] || g.fixing[[2]*StructInfo{b, a}] {
+		return nil
 	}
-	//if detectForRecursiveStructure(a, false) || detectForRecursiveStructure(b, false) {
-	//	return
-	//}
+	g.fixing[[2]*StructInfo{a, b}] = true
+	defer delete(g.fixing, [2]*StructInfo{a, b})
 	//printer.Printf("Fixing structures (%v & %v): %+v (%p)", a.Name, b.Name, a, a)
 	//if a == b {
 	//	log.Printf("Pointer is the same: %v (%p) = %v (%p)", a.Name, a, b.Name, b)
 	//	return
 	//}
-	for name, m := range a.Members {
+	for _, name := range a.memberNames() {
+		m := a.Members[name]
 		if _, ok := b.Members[name]; !ok {
 			b.Members[name] = m
 			b.Order = append(b.Order, m)
 		}
 		/*
-			 else if !IsSameType(m, mb, 0) {
+			 else if !IsSameType(m, mb) {
 				if strings.Contains(a.Name, BasicStructName) {
 					log.Printf("Here")
 				}
 				// Member exist in "a" and "b" with the same name, but it has not the same type which leads to ignoring one of them
 				// because the fix below assume both type are identical but doesn't contain the same thing
-				err := fixTypeMismatch(a.Members[name], b.Members[name])
+				err := g.fixTypeMismatch(a.Members[name], b.Members[name])
 				if errors.Is(err, ErrUnsolvableMismatch) {
-					b.Members[name].Name = addUniqueNumber(b.Members[name].Name)
+					b.Members[name].Name = g.addUniqueNumber(b.Members[name].Name)
 				} else if err != nil {
 					panic(err.Error())
 				}
 			}
 		*/
 	}
-	for name, m := range b.Members {
+	for _, name := range b.memberNames() {
+		m := b.Members[name]
 		if _, ok := a.Members[name]; !ok {
 			a.Members[name] = m
 			a.Order = append(a.Order, m)
 		}
 	}
-	for i := range a.Members {
+	mergeAttributes(a, b)
+	for _, i := range a.memberNames() {
 		if _, ok := b.Members[i]; !ok {
-			a.PrintOrderedMembers()
-			b.PrintOrderedMembers()
-			log.Panicf("FixMembers: '%v' doesn't exist in b", i)
+			return &InferenceError{
+				Path:     g.memberPath(a, i),
+				Member:   i,
+				Types:    []any{a, b},
+				Category: ErrMissingChild,
+				Detail:   "the member doesn't exist in both structures",
+			}
 		}
-		if !IsSameType(a.Members[i].T, b.Members[i].T, 0) {
+		if !IsSameType(a.Members[i].T, b.Members[i].T) {
 			if a.Members[i] == b.Members[i] {
 				//log.Panicf("Identical pointer called in 'FixMembers': %v", a.Members[i].Name)
 				continue
@@ -458,13 +511,29 @@ func FixMembers(a, b *StructInfo) {
 			//}
 
 			//log.Printf("%T (%v) | %T (%v) is not same type", a.Members[i].T, a.Members[i].Name, b.Members[i].T, b.Members[i].Name)
-			err := fixTypeMismatch(a.Members[i], b.Members[i])
-			if err != nil {
-				panic(err.Error())
+			if err := g.fixMemberMismatch(a.Name, a.Members[i], b.Members[i]); err != nil {
+				ie := asInferenceError(err, nil)
+				if ie.Member == "" {
+					ie.Member = a.Name + "." + i
+				}
+				if ie.Path == "" {
+					ie.Path = g.memberPath(b, i)
+				}
+				if ie.Path == "" {
+					ie.Path = g.memberPath(a, i)
+				}
+				if !g.lenient {
+					return ie
+				}
+				g.problems = append(g.problems, ie)
+				g.recordFallback(ie.Member, ie)
+				a.Members[i].T = createXMLElementType()
+				b.Members[i].T = a.Members[i].T
 			}
 			updateOrderedMembers(a)
 			updateOrderedMembers(b)
 		}
 	}
 	//log.Printf("End")
+	return nil
 }
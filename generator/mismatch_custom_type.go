@@ -6,12 +6,10 @@ import (
 	"reflect"
 )
 
-var ErrUnsolvableMismatch = errors.New("unsolvable mismatch")
-
 // fixCustomType main purpose is to reconcile the types of two CustomType values.
 // If either of them is nil, it updates the nil value to match the other.
 // If both are non-nil, it ensures that the Type1 and Type2 fields of both CustomType values are consistent by using helper functions.
-func fixCustomType(a, b *CustomType) error {
+func (g *Generator) fixCustomType(a, b *CustomType) error {
 	if a == nil {
 		a = b
 		return nil
@@ -21,13 +19,17 @@ func fixCustomType(a, b *CustomType) error {
 		return nil
 	}
 
-	if err := reconcileTypes(&a.Type1, &b.Type1, a, b); err != nil {
+	if err := g.reconcileTypes(&a.Type1, &b.Type1, a, b); err != nil {
 		if !errors.Is(err, ErrUnsolvableMismatch) {
 			return err
 		}
 		log.Printf("(t1) data a: %v -> %T (%v)", a.Name, a.Type1, a.Type1)
 		log.Printf("(t1) data b: %v -> %T (%v)", b.Name, b.Type1, b.Type1)
 		log.Printf("Multiple type detected")
+		if g.unite(&a.Type1, &b.Type1) == nil {
+			return nil
+		}
+		g.tagBranch(BranchElementFallback)
 
 		// a: *CustomType[*multiple.Type] / *CustomType[*xml.Element]
 		// b: *CustomType[*multiple.Type] / *CustomType[*xml.Element]
@@ -35,11 +37,12 @@ func fixCustomType(a, b *CustomType) error {
 		b.Type1 = createXMLElementType()
 		return nil
 	}
-	if err := reconcileTypes(&a.Type2, &b.Type2, a, b); err != nil {
+	if err := g.reconcileTypes(&a.Type2, &b.Type2, a, b); err != nil {
 		log.Printf("(t2) data a: %v -> %T (%+v)", a.Name, a.Type2, a.Type2)
 		log.Printf("(t2) data b: %v -> %T (%+v)", b.Name, b.Type2, b.Type2)
-		log.Printf("(t2) can't decide on which type work on between %T & %T", a.Type2, b.Type2)
-		panic("Multiple type detected for type2")
+		if errors.Is(err, ErrUnsolvableMismatch) {
+			return newInferenceError(ErrUnsolvableMismatch, "can't decide on the type of the values", a.Type2, b.Type2)
+		}
 		return err
 	}
 	return nil
@@ -57,32 +60,44 @@ func updateCustomType(dest, src *CustomType) {
 	dest.ImportPath = src.ImportPath
 }
 
-func reconcileTypes(aType, bType *any, a, b *CustomType) error {
+func (g *Generator) reconcileTypes(aType, bType *any, a, b *CustomType) error {
 	if *aType == nil && *bType == nil {
 		return nil
 	}
 	aRefType := reflect.TypeOf(*aType)
 	bRefType := reflect.TypeOf(*bType)
 
-	if !IsSameType(*aType, *bType, 0) {
+	if !IsSameType(*aType, *bType) {
 		if shouldUpdateType(aRefType, bRefType, *aType, *bType) {
 			updateCustomType(b, a)
 		} else if shouldUpdateType(bRefType, aRefType, *bType, *aType) {
 			updateCustomType(a, b)
 		} else {
-			return handleMismatch(aType, bType, a, b)
+			return g.handleMismatch(aType, bType, a, b)
 		}
 	}
 	return nil
 }
 
-func handleMismatch(aType, bType *any, a, b *CustomType) error {
+func (g *Generator) handleMismatch(aType, bType *any, a, b *CustomType) error {
+	_, unionA := (*aType).(*Union)
+	_, unionB := (*bType).(*Union)
+	if unionA || unionB {
+		return g.unite(aType, bType)
+	}
+	_, polyA := (*aType).(*Polymorphic)
+	_, polyB := (*bType).(*Polymorphic)
+	if polyA || polyB {
+		return g.fixPolymorphic(aType, bType)
+	}
 	switch va := (*aType).(type) {
 	case reflect.Kind:
 		if vb, ok := (*bType).(reflect.Kind); ok {
 			if isIntFloatMismatch(va, vb) {
+				g.tagBranch(BranchIntToFloat)
 				*aType = reflect.Float64
 			} else if isIntFloatMismatch(vb, va) {
+				g.tagBranch(BranchIntToFloat)
 				*bType = reflect.Float64
 			}
 			if isStringMapMismatch(va, a, vb) {
@@ -93,7 +108,7 @@ func handleMismatch(aType, bType *any, a, b *CustomType) error {
 		}
 	case *StructInfo:
 		if vb, ok := (*bType).(*StructInfo); ok {
-			FixMembers(va, vb)
+			return g.FixMembers(va, vb)
 		} else {
 			return ErrUnsolvableMismatch
 		}
@@ -26,10 +26,10 @@ func explainIsSameType(a, b any, e *explanations) *explanations {
 			if va.Pkg != bType.Pkg {
 				e.content = append(e.content, fmt.Sprintf("[CustomType] a pkg is diff from b ('%v' != '%v')", va.Pkg, bType.Pkg))
 			}
-			if !IsSameType(bType.Type1, va.Type1, 0) {
+			if !IsSameType(bType.Type1, va.Type1) {
 				e.content = append(e.content, fmt.Sprintf("[CustomType] a type1 is diff from b (%T != %T)", va.Type1, bType.Type1))
 			}
-			if !IsSameType(bType.Type2, va.Type2, 0) {
+			if !IsSameType(bType.Type2, va.Type2) {
 				e.content = append(e.content, fmt.Sprintf("[CustomType] a type2 is diff from b (%T != %T)", va.Type2, bType.Type2))
 			}
 			return e
@@ -42,10 +42,8 @@ func explainIsSameType(a, b any, e *explanations) *explanations {
 			if va.Name != bType.Name {
 				e.content = append(e.content, fmt.Sprintf("[StructInfo] a name is diff from b ('%v' != '%v')", va.Name, bType.Name))
 			}
-			if !hasSameMembers(bType, va, 0) {
+			if !hasSameMembers(bType, va) {
 				e.content = append(e.content, fmt.Sprintf("[StructInfo] a has not the same members of b (len: %d <> %d)", len(va.Members), len(bType.Members)))
-				va.PrintOrderedMembers()
-				bType.PrintOrderedMembers()
 			}
 		} else {
 			e.content = append(e.content, "[StructInfo] b is not type StructInfo but a is")
@@ -53,7 +51,7 @@ func explainIsSameType(a, b any, e *explanations) *explanations {
 		}
 	case *FixedArray:
 		if bFixArr, ok := b.(*FixedArray); ok {
-			if !IsSameType(va.PrimaryType, bFixArr.PrimaryType, 0) {
+			if !IsSameType(va.PrimaryType, bFixArr.PrimaryType) {
 				e.content = append(e.content, fmt.Sprintf("[FixedArray] a is not same type w/ b (%T != %T)", bFixArr.PrimaryType, va.PrimaryType))
 				return e
 			}
@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"github.com/cruffinoni/rimworld-editor/generator/config"
+	"github.com/cruffinoni/rimworld-editor/generator/paths"
+	"github.com/cruffinoni/rimworld-editor/xml"
+	"github.com/cruffinoni/rimworld-editor/xml/path"
+)
+
+// resolveOverrides finds the elements of root matched by the overrides of the
+// configuration.
+func (g *Generator) resolveOverrides(root *xml.Element) {
+	if g.config == nil {
+		return
+	}
+	for _, o := range g.config.Overrides {
+		for _, e := range path.FindWithPath(o.Path, root) {
+			g.overrides[e] = o
+			g.matchedOverrides[o] = true
+		}
+	}
+}
+
+// overrideFor returns the override matching e, or nil.
+func (g *Generator) overrideFor(e *xml.Element) *config.Override {
+	if e == nil {
+		return nil
+	}
+	return g.overrides[e]
+}
+
+// overriddenType returns the type the configuration pins e to, if any.
+func (g *Generator) overriddenType(e *xml.Element) (any, bool) {
+	o := g.overrideFor(e)
+	if o == nil || o.Type == "" {
+		return nil, false
+	}
+	if pkg, name, ok := o.ExternalType(); ok {
+		return &CustomType{
+			Name:       name,
+			Pkg:        "*" + pkg,
+			ImportPath: o.Import,
+			External:   true,
+		}, true
+	}
+	if o.IsRaw() {
+		return createXMLElementType(), true
+	}
+	k, _ := o.Kind()
+	if !e.Attr.Empty() {
+		return &CustomType{
+			Name:       "Type",
+			Pkg:        "*embedded",
+			Type1:      k,
+			ImportPath: paths.EmbeddedTypePath,
+		}, true
+	}
+	return k, true
+}
+
+// UnmatchedOverrides returns the overrides of the configuration which didn't
+// match any element during the last generation, in their configuration order.
+func (g *Generator) UnmatchedOverrides() []*config.Override {
+	if g.config == nil {
+		return nil
+	}
+	var unmatched []*config.Override
+	for _, o := range g.config.Overrides {
+		if !g.matchedOverrides[o] {
+			unmatched = append(unmatched, o)
+		}
+	}
+	return unmatched
+}
@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/generator/config"
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+func TestGenerator_overrides(t *testing.T) {
+	const (
+		document = `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<world>
+		<seed>42</seed>
+		<info>
+			<name>Rimworld</name>
+		</info>
+	</world>
+</savegame>
+`
+		configuration = `
+overrides:
+  - path: savegame>world
+    struct_name: planet
+    field_name: Planet
+    file_name: planet_gen
+  - path: savegame>world>seed
+    field_name: WorldSeed
+  - path: savegame>factions
+    struct_name: faction
+`
+	)
+	fileName := filepath.Join(t.TempDir(), "xmlgen.yaml")
+	require.NoError(t, os.WriteFile(fileName, []byte(configuration), 0644))
+	c, err := config.Load(fileName)
+	require.NoError(t, err)
+	require.Len(t, c.Overrides, 3)
+
+	g := New(WithConfig(c))
+	got, err := g.GenerateGoFiles(readBuffer(t, document))
+	require.NoError(t, err)
+
+	savegame, ok := got.Members["savegame"].T.(*StructInfo)
+	require.True(t, ok)
+	require.Contains(t, savegame.Members, "world")
+	world := savegame.Members["world"]
+	assert.Equal(t, "Planet", world.FieldName)
+	planet, ok := world.T.(*StructInfo)
+	require.True(t, ok)
+	assert.Equal(t, "planet", planet.Name)
+	assert.Equal(t, "planet_gen", planet.FileName)
+	require.Contains(t, g.RegisteredMembers, "planet")
+	assert.NotContains(t, g.RegisteredMembers, "world")
+	assert.Equal(t, "WorldSeed", planet.Members["seed"].FieldName)
+	assert.Empty(t, planet.Members["info"].FieldName)
+
+	unmatched := g.UnmatchedOverrides()
+	require.Len(t, unmatched, 1)
+	assert.Equal(t, "savegame>factions", unmatched[0].Path)
+}
+
+func TestLoadConfig_invalid(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]struct {
+		content string
+		err     error
+	}{
+		"empty.json":        {`{"overrides": [{"path": "savegame>world"}]}`, config.ErrEmptyOverride},
+		"no_path.yml":       {"overrides:\n  - struct_name: world\n", config.ErrMissingPath},
+		"unsupported.xml":   {`<overrides />`, config.ErrUnsupportedFile},
+		"unknown_type.json": {`{"overrides": [{"path": "savegame>id", "type": "int32"}]}`, config.ErrUnknownType},
+		"missing_type.yaml": {"overrides:\n  - path: savegame>color\n    import: example.com/colors\n", config.ErrMissingType},
+	}
+	for name, f := range files {
+		t.Run(name, func(t *testing.T) {
+			fileName := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(fileName, []byte(f.content), 0644))
+			_, err := config.Load(fileName)
+			require.ErrorIs(t, err, f.err)
+		})
+	}
+}
+
+func TestGenerator_typeOverrides(t *testing.T) {
+	documents := []string{`
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<id>00123</id>
+	<color>#ff0000</color>
+	<code>12</code>
+	<payload>
+		<anything>1</anything>
+	</payload>
+</savegame>
+`, `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<id>00124</id>
+	<code>
+		<major>1</major>
+	</code>
+</savegame>
+`}
+	c := &config.Config{
+		Overrides: []*config.Override{
+			{Path: "savegame>id", Type: "string"},
+			{Path: "savegame>color", Type: "RGB", Import: "example.com/colors"},
+			{Path: "savegame>code", Type: "string"},
+			{Path: "savegame>payload", Type: config.TypeRaw},
+		},
+	}
+	require.NoError(t, c.Validate())
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		roots = append(roots, readBuffer(t, d))
+	}
+	g := New(WithConfig(c))
+	got, err := g.GenerateGoFilesFromCorpus(roots)
+	require.NoError(t, err)
+	require.Empty(t, g.UnmatchedOverrides())
+
+	savegame, ok := got.Members["savegame"].T.(*StructInfo)
+	require.True(t, ok)
+	assert.Equal(t, reflect.String, savegame.Members["id"].T)
+	assert.Equal(t, reflect.String, savegame.Members["code"].T)
+	assert.True(t, savegame.Members["code"].Pinned)
+	assert.IsType(t, &xml.Element{}, savegame.Members["payload"].T)
+	assert.NotContains(t, g.RegisteredMembers, "payload")
+	assert.Equal(t, &CustomType{
+		Name:       "RGB",
+		Pkg:        "*colors",
+		ImportPath: "example.com/colors",
+		External:   true,
+	}, savegame.Members["color"].T)
+}
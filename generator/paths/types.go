@@ -7,5 +7,10 @@ const (
 	PrimaryTypesPath  = "xml/types/primary"
 	HeaderXml         = "xml"
 	XmlAttributes     = "xml/attributes"
-	CodePackage       = "github.com/cruffinoni/rimworld-editor/"
+	XmlInterface      = "xml/interface"
+	XmlSaver          = "xml/saver"
+	XmlFileSaver      = "xml/saver/xmlFile"
+	XmlUnmarshal      = "xml/unmarshal"
+	FilePackage       = "file"
+	CodePackage       = "github.com/cruffinoni/xml-generator/"
 )
@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+// ClassAttribute is the attribute giving the class of polymorphic elements.
+const ClassAttribute = "Class"
+
+// Polymorphic is the type of the list items whose children depend on their
+// Class attribute. Each class has its own structure.
+type Polymorphic struct {
+	// Name is the name of the interface implemented by the variants.
+	Name string
+	// Variants are the structures of the items, indexed by class.
+	Variants map[string]*StructInfo
+	// Default is the structure of the items without class, if any.
+	Default *StructInfo
+}
+
+// Classes returns the classes of p, sorted.
+func (p *Polymorphic) Classes() []string {
+	classes := make([]string, 0, len(p.Variants))
+	for c := range p.Variants {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// WithClassPolymorphism infers one structure per Class attribute value for
+// the list items having this attribute, instead of merging all of them into
+// one structure. It's disabled by default.
+func WithClassPolymorphism(enabled bool) Option {
+	return func(g *Generator) {
+		g.classes = enabled
+	}
+}
+
+// classIdentifier turns a class ("Verse.CompProperties_Power") into a part of
+// a structure name.
+func classIdentifier(class string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, class)
+}
+
+// createPolymorphic creates a Polymorphic from the list items of e if at
+// least one of them has a class. ok is false otherwise.
+func (g *Generator) createPolymorphic(e *xml.Element, flag uint) (t any, ok bool, err error) {
+	if !g.classes || flag&forceChild > 0 || e.Child == nil {
+		return nil, false, nil
+	}
+	hasClass := false
+	for n := e.Child; n != nil; n = n.Next {
+		if !g.dialect.IsListTag(n.GetName()) {
+			return nil, false, nil
+		}
+		if n.Attr.Get(ClassAttribute) != "" {
+			hasClass = true
+		}
+	}
+	if !hasClass {
+		return nil, false, nil
+	}
+	name := e.GetName()
+	// The names are reserved, so an unrelated element with the same name
+	// isn't merged with them
+	p := &Polymorphic{
+		Name:     g.stableTypeName(g.generatedNames, name+"_item"),
+		Variants: make(map[string]*StructInfo),
+	}
+	flag &^= forceFullCheck | forceChildApplied
+	for n := e.Child; n != nil; n = n.Next {
+		var (
+			class = n.Attr.Get(ClassAttribute)
+			s     *StructInfo
+		)
+		if class == "" {
+			if p.Default == nil {
+				p.Default = &StructInfo{Name: g.stableTypeName(g.generatedNames, name), Members: make(map[string]*Member)}
+			}
+			s = p.Default
+		} else {
+			if p.Variants[class] == nil {
+				p.Variants[class] = &StructInfo{
+					Name:    g.stableTypeName(g.generatedNames, name+"_"+classIdentifier(class)),
+					Members: make(map[string]*Member),
+				}
+			}
+			s = p.Variants[class]
+		}
+		g.variants[s.Name] = true
+		g.countInstance(s)
+		if err = g.handleElement(n.Child, s, flag); err != nil {
+			return nil, true, err
+		}
+	}
+	return p, true, nil
+}
+
+// isSamePolymorphic reports whether a and b have the same name and classes.
+func isSamePolymorphic(a, b *Polymorphic) bool {
+	if a.Name != b.Name || len(a.Variants) != len(b.Variants) || (a.Default == nil) != (b.Default == nil) {
+		return false
+	}
+	for c := range a.Variants {
+		if _, ok := b.Variants[c]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// mergePolymorphic adds the classes of src to dst and fixes the members of
+// the classes they have in common.
+func (g *Generator) mergePolymorphic(dst, src *Polymorphic) error {
+	for _, c := range src.Classes() {
+		v, ok := dst.Variants[c]
+		if !ok {
+			dst.Variants[c] = src.Variants[c]
+			continue
+		}
+		if v != src.Variants[c] {
+			if err := g.FixMembers(v, src.Variants[c]); err != nil {
+				return err
+			}
+		}
+	}
+	if src.Default != nil {
+		return g.mergeDefaultVariant(dst, src.Default)
+	}
+	return nil
+}
+
+// mergeDefaultVariant merges s, the structure of items without class, into
+// the default variant of p.
+func (g *Generator) mergeDefaultVariant(p *Polymorphic, s *StructInfo) error {
+	if p.Default == nil {
+		g.variants[s.Name] = true
+		p.Default = s
+		return nil
+	}
+	if p.Default == s {
+		return nil
+	}
+	return g.FixMembers(p.Default, s)
+}
+
+// fixPolymorphic reconciles two types when at least one of them is a
+// Polymorphic. A structure is merged into the default variant.
+func (g *Generator) fixPolymorphic(aType, bType *any) error {
+	pa, okA := (*aType).(*Polymorphic)
+	pb, okB := (*bType).(*Polymorphic)
+	switch {
+	case okA && okB:
+		g.tagBranch(BranchClassUnion)
+		if err := g.mergePolymorphic(pa, pb); err != nil {
+			return err
+		}
+		*bType = pa
+	case okA:
+		s, ok := (*bType).(*StructInfo)
+		if !ok {
+			return newInferenceError(ErrUnhandledType, "polymorphic items mixed with a non structure type", pa, *bType)
+		}
+		g.tagBranch(BranchClassUnion)
+		if err := g.mergeDefaultVariant(pa, s); err != nil {
+			return err
+		}
+		*bType = pa
+	case okB:
+		return g.fixPolymorphic(bType, aType)
+	default:
+		return ErrUnsolvableMismatch
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+func TestGenerator_classPolymorphism(t *testing.T) {
+	documents := []string{`
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<comps>
+		<li Class="CompProperties_Power">
+			<basePowerConsumption>100</basePowerConsumption>
+		</li>
+		<li Class="CompProperties_Flickable">
+			<commandTexture>UI/Flick</commandTexture>
+		</li>
+		<li Class="CompProperties_Power">
+			<basePowerConsumption>150.5</basePowerConsumption>
+			<shortCircuitInRain>True</shortCircuitInRain>
+		</li>
+	</comps>
+</savegame>
+`, `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<comps>
+		<li Class="Verse.CompProperties_Glower">
+			<glowRadius>5</glowRadius>
+		</li>
+		<li>
+			<compClass>CompForbiddable</compClass>
+		</li>
+	</comps>
+</savegame>
+`}
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		roots = append(roots, readBuffer(t, d))
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		g := New()
+		got, err := g.GenerateGoFilesFromCorpus(roots)
+		require.NoError(t, err)
+		savegame := got.Members["savegame"].T.(*StructInfo)
+		assert.IsType(t, &StructInfo{}, savegame.Members["comps"].T.(*CustomType).Type1)
+	})
+
+	g := New(WithClassPolymorphism(true))
+	got, err := g.GenerateGoFilesFromCorpus(roots)
+	require.NoError(t, err)
+	savegame := got.Members["savegame"].T.(*StructInfo)
+	comps, ok := savegame.Members["comps"].T.(*CustomType)
+	require.True(t, ok)
+	require.IsType(t, &Polymorphic{}, comps.Type1)
+	p := comps.Type1.(*Polymorphic)
+	assert.Equal(t, "comps_item", p.Name)
+	assert.Equal(t, []string{"CompProperties_Flickable", "CompProperties_Power", "Verse.CompProperties_Glower"}, p.Classes())
+
+	power := g.RegisteredMembers[p.Variants["CompProperties_Power"].Name][0]
+	assert.Equal(t, "comps_CompProperties_Power", power.Name)
+	assert.Equal(t, reflect.Float64, power.Members["basePowerConsumption"].T)
+	assert.Contains(t, power.Members, "shortCircuitInRain")
+	assert.NotContains(t, power.Members, "commandTexture")
+	assert.Equal(t, "comps_Verse_CompProperties_Glower", p.Variants["Verse.CompProperties_Glower"].Name)
+
+	require.NotNil(t, p.Default)
+	assert.Equal(t, "comps", p.Default.Name)
+	assert.Contains(t, p.Default.Members, "compClass")
+	assert.NotContains(t, p.Default.Members, "glowRadius")
+}
+
+func TestGenerator_classPolymorphismNames(t *testing.T) {
+	const (
+		settings = `
+	<settings>
+		<comps><radius>5</radius></comps>
+	</settings>`
+		comps = `
+	<comps>
+		<li Class="CompProperties_Power"><basePowerConsumption>100</basePowerConsumption></li>
+		<li><compClass>CompForbiddable</compClass></li>
+	</comps>`
+	)
+	documents := map[string]string{
+		"element first":     "<savegame>" + settings + comps + "</savegame>",
+		"polymorphic first": "<savegame>" + comps + settings + "</savegame>",
+	}
+	for name, d := range documents {
+		t.Run(name, func(t *testing.T) {
+			g := New(WithClassPolymorphism(true))
+			got, err := g.GenerateGoFiles(readBuffer(t, d))
+			require.NoError(t, err)
+			savegame := got.Members["savegame"].T.(*StructInfo)
+			p := savegame.Members["comps"].T.(*CustomType).Type1.(*Polymorphic)
+			settings := g.RegisteredMembers[savegame.Members["settings"].T.(*StructInfo).Name][0]
+			element := g.RegisteredMembers[settings.Members["comps"].T.(*StructInfo).Name][0]
+
+			require.NotNil(t, p.Default)
+			assert.NotEqual(t, element.Name, p.Default.Name)
+			assert.Contains(t, element.Members, "radius")
+			assert.NotContains(t, element.Members, "compClass")
+			def := g.RegisteredMembers[p.Default.Name][0]
+			assert.Contains(t, def.Members, "compClass")
+			assert.NotContains(t, def.Members, "radius")
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+// ancestor is a structure being created from the element e. The
+// structures are created depth first, so the ones being created are always
+// those of the ancestors of the current element.
+type ancestor struct {
+	e *xml.Element
+	s *StructInfo
+}
+
+// recursiveStructure returns the structure of the closest ancestor of e
+// having the shape of e, or nil if there is none. An element has the shape
+// of an ancestor when they have the same name and at least one child in
+// common, the children of the list items being those of the list. The items
+// of a list have the shape of its parent when they hold a list of the same
+// name and another child of the parent, e.g. the items of the subNodes of a
+// node holding subNodes themselves.
+func (g *Generator) recursiveStructure(e *xml.Element) *StructInfo {
+	if s := g.recursiveItems(e); s != nil {
+		return s
+	}
+	var names map[string]bool
+	for i := len(g.ancestors) - 1; i >= 0; i-- {
+		a := g.ancestors[i]
+		if a.e == e || a.e.GetName() != e.GetName() || g.overrideFor(a.e) != g.overrideFor(e) {
+			continue
+		}
+		if names == nil {
+			names = g.childNames(e, make(map[string]bool))
+		}
+		for name := range g.childNames(a.e, make(map[string]bool)) {
+			if names[name] {
+				return a.s
+			}
+		}
+	}
+	return nil
+}
+
+// recursiveItems returns the structure of the parent of the list e if its
+// items have the shape of the parent, see recursiveStructure.
+func (g *Generator) recursiveItems(e *xml.Element) *StructInfo {
+	if len(g.ancestors) == 0 || e.Child == nil || !g.dialect.IsListTag(e.Child.GetName()) {
+		return nil
+	}
+	parent := g.ancestors[len(g.ancestors)-1]
+	if parent.e != e.Parent || g.overrideFor(e) != nil {
+		return nil
+	}
+	items := g.childNames(e, make(map[string]bool))
+	if !items[e.GetName()] {
+		return nil
+	}
+	for name := range g.childNames(parent.e, make(map[string]bool)) {
+		if name != e.GetName() && items[name] {
+			return parent.s
+		}
+	}
+	return nil
+}
+
+// childNames adds the names of the children of e to names and returns it.
+func (g *Generator) childNames(e *xml.Element, names map[string]bool) map[string]bool {
+	for n := e.Child; n != nil; n = n.Next {
+		if g.dialect.IsListTag(n.GetName()) {
+			g.childNames(n, names)
+		} else {
+			names[n.GetName()] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+// nestedCategories returns a document with depth categories nested in each
+// other.
+func nestedCategories(depth int) string {
+	var sb strings.Builder
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<savegame>\n")
+	for i := 0; i < depth; i++ {
+		sb.WriteString("<category><name>c</name>")
+	}
+	sb.WriteString("<weight>1.5</weight>")
+	for i := 0; i < depth; i++ {
+		sb.WriteString("</category>")
+	}
+	sb.WriteString("\n</savegame>\n")
+	return sb.String()
+}
+
+func TestGenerator_recursiveStructures(t *testing.T) {
+	documents := []string{`
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<node>
+		<label>root</label>
+		<subNodes>
+			<li>
+				<label>a</label>
+				<subNodes>
+					<li>
+						<label>a1</label>
+					</li>
+				</subNodes>
+			</li>
+			<li>
+				<label>b</label>
+				<weight>2</weight>
+			</li>
+		</subNodes>
+	</node>
+</savegame>
+`, nestedCategories(3), nestedCategories(80)}
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		roots = append(roots, readBuffer(t, d))
+	}
+
+	g := New()
+	got, err := g.GenerateGoFilesFromCorpus(roots)
+	require.NoError(t, err)
+	savegame := got.Members["savegame"].T.(*StructInfo)
+
+	node := savegame.Members["node"].T.(*StructInfo)
+	subNodes, ok := node.Members["subNodes"].T.(*CustomType)
+	require.True(t, ok)
+	// The items of the subNodes are nodes themselves
+	assert.Same(t, node, subNodes.Type1)
+	assert.Contains(t, node.Members, "weight")
+	assert.NotContains(t, g.RegisteredMembers, "node_subNodes0")
+
+	category := savegame.Members["category"].T.(*StructInfo)
+	assert.Same(t, category, category.Members["category"].T)
+	assert.Contains(t, category.Members, "weight")
+	// One version per document, whatever its depth
+	require.Len(t, g.RegisteredMembers["category"], 2)
+	for _, v := range g.RegisteredMembers["category"] {
+		assert.Same(t, v, v.Members["category"].T)
+	}
+
+	assert.True(t, IsSameType(category, category))
+	other := &StructInfo{Name: "category", Members: make(map[string]*Member)}
+	for name, m := range category.Members {
+		other.Members[name] = &Member{Name: name, T: m.T}
+	}
+	other.Members["category"].T = other
+	assert.True(t, IsSameType(category, other))
+	other.Members["name"].T = other
+	assert.False(t, IsSameType(category, other))
+
+	other.Members["name"].T = category.Members["name"].T
+	other.Members["extra"] = &Member{Name: "extra", T: reflect.Int64}
+	require.NoError(t, g.FixMembers(category, other))
+	assert.Contains(t, category.Members, "extra")
+}
@@ -0,0 +1,428 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+// Branch names the branch of fixTypeMismatch which reconciled two types.
+type Branch string
+
+const (
+	// BranchPinned keeps the type given by the configuration.
+	BranchPinned Branch = "pinned"
+	// BranchEmptyToConcrete replaces an empty type by a concrete one.
+	BranchEmptyToConcrete Branch = "empty_to_concrete"
+	// BranchCustomTypes reconciles the parameters of two custom types.
+	BranchCustomTypes Branch = "custom_types"
+	// BranchStructConsolidation merges the members of two structures.
+	BranchStructConsolidation Branch = "struct_consolidation"
+	// BranchArrayOverSlice prefers a fixed array over a slice.
+	BranchArrayOverSlice Branch = "array_over_slice"
+	// BranchFixedArraySize keeps the biggest size of two fixed arrays.
+	BranchFixedArraySize Branch = "fixed_array_size"
+	// BranchEmbeddedPrimary keeps the primary type with attributes over the
+	// bare primary type.
+	BranchEmbeddedPrimary Branch = "embedded_primary"
+	// BranchIntToFloat widens an int64 to a float64.
+	BranchIntToFloat Branch = "int_to_float"
+	// BranchIntToString widens an int64 to a string, e.g. for int overflows.
+	BranchIntToString Branch = "int_to_string"
+	// BranchReplaced replaces the existing type by the new one.
+	BranchReplaced Branch = "replaced"
+	// BranchFirstTypeKept keeps the existing type and ignores the new one.
+	BranchFirstTypeKept Branch = "first_type_kept"
+	// BranchClassUnion merges the classes of polymorphic items.
+	BranchClassUnion Branch = "class_union"
+	// BranchEnum keeps the enumeration of a seed over a text, see WithSeed.
+	BranchEnum Branch = "enum"
+	// BranchUnion unites types of different shapes into a union.
+	BranchUnion Branch = "union"
+	// BranchElementFallback gives up and types the values as *xml.Element.
+	BranchElementFallback Branch = "element_fallback"
+)
+
+// Report describes how the last generation inferred the structures. It's
+// only filled with WithReport.
+type Report struct {
+	Structs []*StructReport `json:"structs"`
+	// Fallbacks are the members typed as *xml.Element or multiple.Type.
+	Fallbacks []*FallbackReport `json:"fallbacks"`
+}
+
+// StructReport describes an inferred structure.
+type StructReport struct {
+	Name string `json:"name"`
+	// MergedFrom are the structures merged into this one by the
+	// deduplication.
+	MergedFrom []string `json:"merged_from,omitempty"`
+	// Paths are the XML paths of the elements of the structure, without
+	// their indexes.
+	Paths []string `json:"paths"`
+	// Instances is the number of elements seen for the structure.
+	Instances int             `json:"instances"`
+	Members   []*MemberReport `json:"members"`
+}
+
+// MemberReport describes a member of an inferred structure.
+type MemberReport struct {
+	Name      string `json:"name"`
+	FieldName string `json:"field_name,omitempty"`
+	Type      string `json:"type"`
+	Pinned    bool   `json:"pinned,omitempty"`
+	// Optional is set when the member is missing from some elements of the
+	// structure.
+	Optional bool     `json:"optional,omitempty"`
+	Paths    []string `json:"paths"`
+	// Instances is the number of times the member has been seen.
+	Instances       int               `json:"instances"`
+	Reconciliations []*Reconciliation `json:"reconciliations,omitempty"`
+}
+
+// Reconciliation describes a type mismatch fixed on a member.
+type Reconciliation struct {
+	// Branches are the branches of fixTypeMismatch taken to fix the
+	// mismatch, outermost first.
+	Branches []Branch `json:"branches"`
+	From     string   `json:"from"`
+	With     string   `json:"with"`
+	To       string   `json:"to"`
+	// Explanation tells why both types were different.
+	Explanation []string `json:"explanation,omitempty"`
+	// Count is the number of times the same mismatch has been fixed.
+	Count int `json:"count"`
+}
+
+// FallbackReport describes a member which fell back to an untyped type.
+type FallbackReport struct {
+	Struct string   `json:"struct"`
+	Member string   `json:"member"`
+	Type   string   `json:"type"`
+	Paths  []string `json:"paths"`
+	Reason string   `json:"reason"`
+}
+
+// WriteJSON writes r as indented JSON to w.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WithReport enables the recording of the Report of each generation.
+func WithReport(enabled bool) Option {
+	return func(g *Generator) {
+		g.reporting = enabled
+	}
+}
+
+type memberStats struct {
+	paths           map[string]bool
+	instances       int
+	reconciliations []*Reconciliation
+}
+
+type structStats struct {
+	paths     map[string]bool
+	instances int
+	members   map[string]*memberStats
+}
+
+// reportRecorder collects the facts of a generation while it runs.
+type reportRecorder struct {
+	structs map[string]*structStats
+	// reasons are the causes of the fallbacks to *xml.Element, indexed by
+	// schema path or by "struct.member".
+	reasons map[string]string
+	// branches are the branches taken by the mismatch being fixed.
+	branches []Branch
+}
+
+func newReportRecorder() *reportRecorder {
+	return &reportRecorder{
+		structs: make(map[string]*structStats),
+		reasons: make(map[string]string),
+	}
+}
+
+func (r *reportRecorder) structStats(name string) *structStats {
+	s, ok := r.structs[name]
+	if !ok {
+		s = &structStats{
+			paths:   make(map[string]bool),
+			members: make(map[string]*memberStats),
+		}
+		r.structs[name] = s
+	}
+	return s
+}
+
+func (r *reportRecorder) memberStats(structName, name string) *memberStats {
+	s := r.structStats(structName)
+	m, ok := s.members[name]
+	if !ok {
+		m = &memberStats{paths: make(map[string]bool)}
+		s.members[name] = m
+	}
+	return m
+}
+
+// schemaPath returns the XML path of e without the indexes of the elements,
+// so every element of a list shares the same path.
+func schemaPath(e *xml.Element) string {
+	var names []string
+	for n := e; n != nil; n = n.Parent {
+		names = append([]string{n.GetName()}, names...)
+	}
+	return strings.Join(names, ">")
+}
+
+// recordStruct counts an element of the structure s, whose first child is
+// first.
+func (g *Generator) recordStruct(s *StructInfo, first *xml.Element) {
+	if g.report == nil {
+		return
+	}
+	stats := g.report.structStats(s.Name)
+	stats.instances++
+	if first != nil && first.Parent != nil {
+		stats.paths[schemaPath(first.Parent)] = true
+	}
+}
+
+// recordMember counts an element of the member named after e in s.
+func (g *Generator) recordMember(s *StructInfo, e *xml.Element) {
+	if g.report == nil {
+		return
+	}
+	stats := g.report.memberStats(s.Name, e.GetName())
+	stats.instances++
+	stats.paths[schemaPath(e)] = true
+}
+
+// recordFallback records why the type of key fell back to *xml.Element.
+func (g *Generator) recordFallback(key string, err error) {
+	if g.report == nil {
+		return
+	}
+	if _, ok := g.report.reasons[key]; !ok {
+		g.report.reasons[key] = err.Error()
+	}
+}
+
+// tagBranch records a branch taken by the mismatch being fixed.
+func (g *Generator) tagBranch(b Branch) {
+	if g.report == nil {
+		return
+	}
+	g.report.branches = append(g.report.branches, b)
+}
+
+// fixMemberMismatch calls fixOrUnite on the member a of the structure
+// named structName and records the reconciliation.
+func (g *Generator) fixMemberMismatch(structName string, a, b *Member) error {
+	if g.report == nil {
+		return g.fixOrUnite(a, b)
+	}
+	var (
+		r = &Reconciliation{
+			From:        describeType(a.T),
+			With:        describeType(b.T),
+			Explanation: explainIsSameType(a.T, b.T, &explanations{}).content,
+			Count:       1,
+		}
+		// Fixing structures fixes their members, which are recorded
+		// separately
+		outer = g.report.branches
+	)
+	g.report.branches = nil
+	err := g.fixOrUnite(a, b)
+	r.Branches, r.To = g.report.branches, describeType(a.T)
+	g.report.branches = outer
+
+	stats := g.report.memberStats(structName, a.Name)
+	for _, existing := range stats.reconciliations {
+		if existing.sameAs(r) {
+			existing.Count++
+			return err
+		}
+	}
+	stats.reconciliations = append(stats.reconciliations, r)
+	return err
+}
+
+func (r *Reconciliation) sameAs(o *Reconciliation) bool {
+	return r.From == o.From && r.With == o.With && r.To == o.To &&
+		reflect.DeepEqual(r.Branches, o.Branches)
+}
+
+// describeType returns a Go-like representation of the inferred type t.
+func describeType(t any) string {
+	switch va := t.(type) {
+	case reflect.Kind:
+		return va.String()
+	case *StructInfo:
+		return "*" + va.Name
+	case *xml.Element:
+		return "*xml.Element"
+	case *Enum:
+		return va.Name
+	case *Polymorphic:
+		return va.Name
+	case *Union:
+		variants := make([]string, len(va.Variants))
+		for i, v := range va.Variants {
+			variants[i] = describeType(v.T)
+		}
+		return "union[" + strings.Join(variants, " | ") + "]"
+	case *FixedArray:
+		return fmt.Sprintf("[%d]%s", va.Size, describeType(va.PrimaryType))
+	case *CustomType:
+		var sb strings.Builder
+		if strings.HasPrefix(va.Pkg, "*") {
+			sb.WriteString("*")
+		}
+		sb.WriteString(strings.TrimPrefix(va.Pkg, "*") + "." + va.Name)
+		if va.Type1 != nil {
+			sb.WriteString("[" + describeType(va.Type1))
+			if va.Type2 != nil {
+				sb.WriteString(", " + describeType(va.Type2))
+			}
+			sb.WriteString("]")
+		}
+		return sb.String()
+	case nil:
+		return "nil"
+	}
+	return fmt.Sprintf("%T", t)
+}
+
+// fallbackType returns the untyped type held by t, if any.
+func fallbackType(t any) (string, bool) {
+	switch va := t.(type) {
+	case *xml.Element:
+		return describeType(va), true
+	case *FixedArray:
+		return fallbackType(va.PrimaryType)
+	case *CustomType:
+		if IsMultipleType(va) {
+			return describeType(va), true
+		}
+		if ft, ok := fallbackType(va.Type1); ok {
+			return ft, true
+		}
+		return fallbackType(va.Type2)
+	}
+	return "", false
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Report returns the report of the last generation, or nil when WithReport
+// isn't enabled.
+func (g *Generator) Report() *Report {
+	if g.report == nil {
+		return nil
+	}
+	r := &Report{
+		Structs:   make([]*StructReport, 0, len(g.RegisteredMembers)),
+		Fallbacks: make([]*FallbackReport, 0),
+	}
+	for _, name := range g.RegisteredMembers.Names() {
+		versions := g.RegisteredMembers[name]
+		if len(versions) == 0 {
+			continue
+		}
+		s := versions[0]
+		sr := &StructReport{
+			Name:       name,
+			MergedFrom: g.deduplicated[name],
+			Members:    make([]*MemberReport, 0, len(s.Members)),
+		}
+		// The statistics of the merged structures are folded into the one
+		// they've been merged into
+		paths := make(map[string]bool)
+		members := make(map[string]*memberStats)
+		for _, alias := range append([]string{name}, g.deduplicated[name]...) {
+			stats, ok := g.report.structs[alias]
+			if !ok {
+				continue
+			}
+			sr.Instances += stats.instances
+			for p := range stats.paths {
+				paths[p] = true
+			}
+			for n, ms := range stats.members {
+				folded, ok := members[n]
+				if !ok {
+					folded = &memberStats{paths: make(map[string]bool)}
+					members[n] = folded
+				}
+				folded.instances += ms.instances
+				for p := range ms.paths {
+					folded.paths[p] = true
+				}
+				folded.reconciliations = append(folded.reconciliations, ms.reconciliations...)
+			}
+		}
+		sr.Paths = sortedKeys(paths)
+		for _, mName := range s.memberNames() {
+			m := s.Members[mName]
+			mr := &MemberReport{
+				Name:      mName,
+				FieldName: m.FieldName,
+				Type:      describeType(m.T),
+				Pinned:    m.Pinned,
+				Optional:  s.IsOptional(m),
+				Paths:     make([]string, 0),
+			}
+			if ms, ok := members[mName]; ok {
+				mr.Paths = sortedKeys(ms.paths)
+				mr.Instances = ms.instances
+				mr.Reconciliations = ms.reconciliations
+			}
+			sr.Members = append(sr.Members, mr)
+			if ft, ok := fallbackType(m.T); ok {
+				r.Fallbacks = append(r.Fallbacks, &FallbackReport{
+					Struct: name,
+					Member: mName,
+					Type:   ft,
+					Paths:  mr.Paths,
+					Reason: g.fallbackReason(name, m, mr.Paths),
+				})
+			}
+		}
+		r.Structs = append(r.Structs, sr)
+	}
+	return r
+}
+
+// fallbackReason returns why the member m of the structure named structName
+// fell back to an untyped type.
+func (g *Generator) fallbackReason(structName string, m *Member, paths []string) string {
+	if m.Pinned {
+		return "type pinned by the configuration"
+	}
+	if reason, ok := g.report.reasons[structName+"."+m.Name]; ok {
+		return reason
+	}
+	for _, p := range paths {
+		if reason, ok := g.report.reasons[p]; ok {
+			return reason
+		}
+	}
+	return "no type could be inferred from the data"
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+func findStructReport(r *Report, name string) *StructReport {
+	for _, s := range r.Structs {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func findMemberReport(s *StructReport, name string) *MemberReport {
+	for _, m := range s.Members {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func TestGenerator_report(t *testing.T) {
+	documents := []string{`
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<age>3</age>
+		<name />
+		<values>
+			<li>1</li>
+			<li>2</li>
+		</values>
+	</pawn>
+</savegame>
+`, `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<age>3.5</age>
+		<name>Bob</name>
+		<values>7</values>
+	</pawn>
+</savegame>
+`}
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		roots = append(roots, readBuffer(t, d))
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		g := New(WithLenientInference(true))
+		_, err := g.GenerateGoFilesFromCorpus(roots)
+		require.Error(t, err)
+		assert.Nil(t, g.Report())
+	})
+
+	g := New(WithLenientInference(true), WithReport(true))
+	_, err := g.GenerateGoFilesFromCorpus(roots)
+	require.Error(t, err)
+	r := g.Report()
+	require.NotNil(t, r)
+
+	pawn := findStructReport(r, "pawn")
+	require.NotNil(t, pawn)
+	assert.Equal(t, []string{"savegame>pawn"}, pawn.Paths)
+	assert.Equal(t, 2, pawn.Instances)
+
+	age := findMemberReport(pawn, "age")
+	require.NotNil(t, age)
+	assert.Equal(t, "float64", age.Type)
+	assert.Equal(t, []string{"savegame>pawn>age"}, age.Paths)
+	assert.Equal(t, 2, age.Instances)
+	require.Len(t, age.Reconciliations, 1)
+	assert.Equal(t, []Branch{BranchIntToFloat}, age.Reconciliations[0].Branches)
+	assert.Equal(t, "int64", age.Reconciliations[0].From)
+	assert.Equal(t, "float64", age.Reconciliations[0].To)
+	assert.NotEmpty(t, age.Reconciliations[0].Explanation)
+
+	name := findMemberReport(pawn, "name")
+	require.NotNil(t, name)
+	assert.Equal(t, "string", name.Type)
+	require.Len(t, name.Reconciliations, 1)
+	assert.Equal(t, []Branch{BranchEmptyToConcrete}, name.Reconciliations[0].Branches)
+
+	require.Len(t, r.Fallbacks, 1)
+	assert.Equal(t, "pawn", r.Fallbacks[0].Struct)
+	assert.Equal(t, "values", r.Fallbacks[0].Member)
+	assert.Equal(t, "*xml.Element", r.Fallbacks[0].Type)
+	assert.Contains(t, r.Fallbacks[0].Reason, ErrUnhandledType.Error())
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteJSON(&buf))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, r, &decoded)
+}
@@ -2,6 +2,7 @@ package generator
 
 import (
 	"log"
+	"sort"
 
 	"github.com/cruffinoni/rimworld-editor/cmd/app/ui/term/printer"
 	"github.com/cruffinoni/rimworld-editor/xml"
@@ -9,52 +10,95 @@ import (
 
 type MemberVersioning map[string][]*StructInfo
 
-var RegisteredMembers = make(MemberVersioning)
-
+// cleanUpMVPtrs removes the duplicated pointers of each version list. The
+// first occurrence is kept, so the first version stays the one found first.
 func cleanUpMVPtrs(mv MemberVersioning) {
 	for i := range mv {
-		l := len(mv[i])
 		uniquePtr := make(map[*StructInfo]bool)
-		if l >= 1 {
-			for j := 0; j < l; j++ {
-				if _, ok := uniquePtr[mv[i][j]]; !ok {
-					uniquePtr[mv[i][j]] = true
-				}
-			}
-			mv[i] = make([]*StructInfo, len(uniquePtr))
-			j := 0
-			for k := range uniquePtr {
-				mv[i][j] = k
-				j++
+		versions := mv[i][:0]
+		for _, v := range mv[i] {
+			if !uniquePtr[v] {
+				uniquePtr[v] = true
+				versions = append(versions, v)
 			}
 		}
+		mv[i] = versions
+	}
+}
+
+// Names returns the names of the registered structures, sorted.
+func (mv MemberVersioning) Names() []string {
+	names := make([]string, 0, len(mv))
+	for name := range mv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Best returns the version of s which is written, the first one
+// registered. It's s if the structure isn't registered.
+func (mv MemberVersioning) Best(s *StructInfo) *StructInfo {
+	if versions := mv[s.Name]; len(versions) > 0 {
+		return versions[0]
 	}
+	return s
 }
 
 // GenerateGoFiles generates the Go files (with the corresponding structs)
 // for the given XML file, but it doesn't write anything.
 // To do that, call WriteGoFile.
-func GenerateGoFiles(root *xml.Element, withMVFix bool) *StructInfo {
+//
+// By default, the generation stops at the first type that can't be inferred
+// and returns an *InferenceError. With WithLenientInference, such members are
+// typed as *xml.Element and the problems are returned as InferenceErrors
+// along with the structure.
+func (g *Generator) GenerateGoFiles(root *xml.Element) (*StructInfo, error) {
+	return g.GenerateGoFilesFromCorpus([]*xml.Element{root})
+}
+
+// GenerateGoFilesFromCorpus works like GenerateGoFiles but infers one schema
+// from several XML files. Every root is registered in the same
+// MemberVersioning before the type mismatches are fixed, so a member missing
+// from one file or a collection empty in another is typed from the files
+// where it's present.
+func (g *Generator) GenerateGoFilesFromCorpus(roots []*xml.Element) (*StructInfo, error) {
 	s := &StructInfo{
 		Members: make(map[string]*Member),
 	}
-	//log.Printf("Generating Go files for %s", root.XMLPath())
-	RegisteredMembers = make(MemberVersioning)
-	UniqueNumber = 0
-	if err := handleElement(root, s, flagNone); err != nil {
-		panic(err)
+	g.reset()
+	g.plantSeed(s)
+	for _, root := range roots {
+		g.resolveOverrides(root)
+		//log.Printf("Generating Go files for %s", root.XMLPath())
+		g.countInstance(s)
+		if err := g.handleElement(root, s, flagNone); err != nil {
+			return nil, err
+		}
 	}
-	if withMVFix {
+	if g.withMVFix {
 		printer.Print("Cleaning up the MemberVersioning pointers")
-		cleanUpMVPtrs(RegisteredMembers)
-		printer.Printf("{-BOLD}%d{-RESET} members registered. Fixing type mismatch.", len(RegisteredMembers))
-		FixRegisteredMembers(RegisteredMembers)
+		cleanUpMVPtrs(g.RegisteredMembers)
+		printer.Printf("{-BOLD}%d{-RESET} members registered. Fixing type mismatch.", len(g.RegisteredMembers))
+		if err := g.FixRegisteredMembers(); err != nil {
+			return nil, err
+		}
 	}
-	return s
+	g.deduplicate()
+	g.nameUnions()
+	g.applyOccurrences()
+	g.applyEnums()
+	if len(g.problems) > 0 {
+		return s, g.problems
+	}
+	return s, nil
 }
 
-func FixRegisteredMembers(mv MemberVersioning) {
-	for i := range mv {
+// FixRegisteredMembers reconciles all the versions registered for each struct
+// name so the first one holds every member.
+func (g *Generator) FixRegisteredMembers() error {
+	mv := g.RegisteredMembers
+	for _, i := range mv.Names() {
 		l := len(mv[i])
 		if l >= 1 {
 			printer.Printf("Fixing %s ({-BOLD,F_RED}%d{-RESET} fix to do)...", i, l)
@@ -64,10 +108,67 @@ func FixRegisteredMembers(mv MemberVersioning) {
 					log.Printf("Identical pointers: %p & %p / Probable infinite recursion - %v", mv[i][0], mv[i][j], i)
 					continue
 				}
-				FixMembers(mv[i][0], mv[i][j])
+				if err := g.FixMembers(mv[i][0], mv[i][j]); err != nil {
+					return err
+				}
 				//log.Printf("Done")
 			}
 		}
 		deleteDuplicateTitle(mv[i][0])
 	}
+	return nil
+}
+
+// countInstance counts an element from which s is inferred.
+func (g *Generator) countInstance(s *StructInfo) {
+	g.instances[s]++
+}
+
+// countOccurrence counts an element of the member name of s, once for the
+// element of s being handled.
+func (g *Generator) countOccurrence(s *StructInfo, name string) {
+	if g.counted[name] {
+		return
+	}
+	g.counted[name] = true
+	if g.occurrences[s] == nil {
+		g.occurrences[s] = make(map[string]int)
+	}
+	g.occurrences[s][name]++
+}
+
+// applyOccurrences sums the counts of every version of each structure and
+// sets them on the versions and their members.
+func (g *Generator) applyOccurrences() {
+	for _, name := range g.RegisteredMembers.Names() {
+		var (
+			versions    = g.RegisteredMembers[name]
+			seen        = make(map[*StructInfo]bool, len(versions))
+			instances   int
+			occurrences = make(map[string]int)
+			attributes  = make(map[string]int)
+		)
+		for _, v := range versions {
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
+			instances += g.instances[v]
+			for m, n := range g.occurrences[v] {
+				occurrences[m] += n
+			}
+			for a, n := range g.attrOccurrences[v] {
+				attributes[a] += n
+			}
+		}
+		for _, v := range versions {
+			v.Instances = instances
+			for m, member := range v.Members {
+				member.Occurrences = occurrences[m]
+			}
+			for _, a := range v.Attributes {
+				a.Occurrences = attributes[a.Name]
+			}
+		}
+	}
 }
@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"reflect"
+	"sort"
+)
+
+// WithSeed starts every generation from the structures of seed, e.g. read
+// from a schema, which the samples then refine. seed is the root structure,
+// its members are the root elements, and registered are the versions of its
+// structures by name, like the ones of a generation.
+//
+// The seed versions come first, so the versions inferred from the samples
+// are merged into them. The counts of the seed (Instances and Occurrences)
+// are added to the ones of the samples. The structures of the seed are
+// refined in place, so a seed is meant for a single generation.
+func WithSeed(seed *StructInfo, registered MemberVersioning) Option {
+	return func(g *Generator) {
+		g.seed = seed
+		g.seedVersions = registered
+	}
+}
+
+// plantSeed registers the structures of the seed, if any, and gives its root
+// elements to the root structure s.
+func (g *Generator) plantSeed(s *StructInfo) {
+	if g.seed == nil {
+		return
+	}
+	s.Name = g.addUniqueNumber(BasicStructName)
+	s.Members = make(map[string]*Member, len(g.seed.Members))
+	for _, name := range g.seed.memberNames() {
+		m := g.seed.Members[name]
+		s.Members[name] = m
+		s.Order = append(s.Order, m)
+	}
+	g.RegisteredMembers[s.Name] = []*StructInfo{s}
+	g.seedCounts(s, g.seed)
+	for name, versions := range g.seedVersions {
+		for _, v := range versions {
+			if v == g.seed {
+				continue
+			}
+			g.RegisteredMembers[name] = append(g.RegisteredMembers[name], v)
+			g.seedCounts(v, v)
+		}
+	}
+}
+
+// seedCounts adds the counts of v, a structure of the seed, to the ones of s
+// and reserves the names of the enumerations of v.
+func (g *Generator) seedCounts(s, v *StructInfo) {
+	g.instances[s] += v.Instances
+	for name, m := range v.Members {
+		if g.occurrences[s] == nil {
+			g.occurrences[s] = make(map[string]int)
+		}
+		g.occurrences[s][name] += m.Occurrences
+		if e, ok := m.T.(*Enum); ok {
+			g.typeNames[e.Name] = true
+		}
+	}
+	for _, a := range v.Attributes {
+		if g.attrOccurrences[s] == nil {
+			g.attrOccurrences[s] = make(map[string]int)
+		}
+		g.attrOccurrences[s][a.Name] += a.Occurrences
+	}
+}
+
+// fixEnum reconciles two types when at least one of them is an Enum, which
+// comes from a seed. A text takes the enumeration, the values of two
+// enumerations are merged and any other type turns the enumeration back
+// into a text.
+func (g *Generator) fixEnum(a, b *Member) error {
+	ea, okA := a.T.(*Enum)
+	eb, okB := b.T.(*Enum)
+	switch {
+	case okA && okB:
+		ea.Values = mergeEnumValues(ea.Values, eb.Values)
+		b.T = ea
+	case okA && b.T == reflect.String:
+		b.T = ea
+	case okB && a.T == reflect.String:
+		a.T = eb
+	default:
+		if okA {
+			a.T = reflect.String
+		} else {
+			b.T = reflect.String
+		}
+		return g.fixTypeMismatch(a, b)
+	}
+	g.tagBranch(BranchEnum)
+	return nil
+}
+
+// mergeEnumValues returns the values of a and b, sorted.
+func mergeEnumValues(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	values := make([]string, 0, len(a)+len(b))
+	for _, v := range append(a[:len(a):len(a)], b...) {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
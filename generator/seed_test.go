@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_seed(t *testing.T) {
+	const document = `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<game>
+		<gender>Other</gender>
+		<tickCount>1500</tickCount>
+	</game>
+</savegame>
+`
+	// seed is the schema of a savegame whose game has an enumerated gender
+	// and a label, as read from an XSD.
+	seed := func() (*StructInfo, MemberVersioning) {
+		gender := &Member{Name: "gender", T: &Enum{Name: "Gender", Values: []string{"Female", "Male"}}, Occurrences: 1}
+		label := &Member{Name: "label", T: reflect.String, Occurrences: 1}
+		game := &StructInfo{
+			Name:      "game",
+			Members:   map[string]*Member{"gender": gender, "label": label},
+			Order:     []*Member{gender, label},
+			Instances: 1,
+		}
+		gameMember := &Member{Name: "game", T: game, Occurrences: 1}
+		savegame := &StructInfo{
+			Name:      "savegame",
+			Members:   map[string]*Member{"game": gameMember},
+			Order:     []*Member{gameMember},
+			Instances: 1,
+		}
+		savegameMember := &Member{Name: "savegame", T: savegame, Occurrences: 1}
+		root := &StructInfo{
+			Name:      BasicStructName + "0",
+			Members:   map[string]*Member{"savegame": savegameMember},
+			Order:     []*Member{savegameMember},
+			Instances: 1,
+		}
+		return root, MemberVersioning{
+			root.Name:     {root},
+			savegame.Name: {savegame},
+			game.Name:     {game},
+		}
+	}
+
+	t.Run("without samples", func(t *testing.T) {
+		g := New(WithSeed(seed()))
+		got, err := g.GenerateGoFilesFromCorpus(nil)
+		require.NoError(t, err)
+		require.Contains(t, got.Members, "savegame")
+		game := g.RegisteredMembers["game"][0]
+		assert.IsType(t, &Enum{}, game.Members["gender"].T)
+		assert.Equal(t, []string{"gender", "label"}, game.memberNames())
+		assert.Equal(t, 1, game.Members["label"].Occurrences)
+	})
+
+	t.Run("refined by a sample", func(t *testing.T) {
+		g := New(WithSeed(seed()))
+		_, err := g.GenerateGoFiles(readBuffer(t, document))
+		require.NoError(t, err)
+		game := g.RegisteredMembers["game"][0]
+		require.IsType(t, &Enum{}, game.Members["gender"].T)
+		assert.Equal(t, []string{"Female", "Male"}, game.Members["gender"].T.(*Enum).Values)
+		assert.Equal(t, reflect.Int64, game.Members["tickCount"].T)
+		assert.Equal(t, 2, game.Instances)
+		assert.Equal(t, 1, game.Members["label"].Occurrences)
+		assert.Equal(t, 1, game.Members["tickCount"].Occurrences)
+	})
+}
+
+func TestGenerator_fixEnum(t *testing.T) {
+	var (
+		g      = New()
+		female = &Enum{Name: "Gender", Values: []string{"Female"}}
+		male   = &Enum{Name: "Gender", Values: []string{"Male"}}
+	)
+
+	a, b := &Member{T: female}, &Member{T: male}
+	require.NoError(t, g.fixEnum(a, b))
+	assert.Equal(t, []string{"Female", "Male"}, female.Values)
+	assert.Same(t, female, b.T)
+
+	a, b = &Member{T: female}, &Member{T: reflect.String}
+	require.NoError(t, g.fixEnum(a, b))
+	assert.Same(t, female, b.T)
+
+	a, b = &Member{T: reflect.Int64}, &Member{T: female}
+	require.NoError(t, g.fixEnum(a, b))
+	assert.Equal(t, reflect.String, a.T)
+	assert.Equal(t, reflect.String, b.T)
+}
@@ -2,10 +2,10 @@ package generator
 
 import (
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/cruffinoni/rimworld-editor/helper"
 	"github.com/cruffinoni/rimworld-editor/xml"
 	"github.com/cruffinoni/rimworld-editor/xml/attributes"
 )
@@ -14,12 +14,35 @@ type Member struct {
 	T    any
 	Attr attributes.Attributes
 	Name string
+	// FieldName is the name of the Go field, if it's overridden by the
+	// configuration. Name is used otherwise.
+	FieldName string
+	// Pinned is set when the type comes from the configuration. Such a type
+	// is never changed to fix a mismatch.
+	Pinned bool
+	// Occurrences is the number of elements of the structure in which the
+	// member is present. It's set once the generation is done.
+	Occurrences int
 }
 
 type StructInfo struct {
 	Name    string
 	Members map[string]*Member
 	Order   []*Member
+	// FileName is the name of the file of the structure, if it's overridden
+	// by the configuration.
+	FileName string
+	// Instances is the number of elements the structure has been inferred
+	// from. It's set once the generation is done.
+	Instances int
+	// Attributes are the typed attributes of the elements, sorted by name.
+	Attributes []*Attribute
+}
+
+// IsOptional reports whether m, a member of s, is missing from some of the
+// elements of s.
+func (s *StructInfo) IsOptional(m *Member) bool {
+	return m.Occurrences < s.Instances
 }
 
 const (
@@ -47,12 +70,87 @@ const (
 	Complex reflect.Kind = 100
 )
 
-var UniqueNumber = int64(0)
+// addUniqueNumber suffixes name with the number of times it has been asked
+// for. Every name has its own counter, so a new name in the input doesn't
+// shift the suffixes of the others.
+func (g *Generator) addUniqueNumber(name string) string {
+	n := g.uniqueNames[name]
+	g.uniqueNames[name]++
+	return name + strconv.Itoa(n)
+}
 
-func addUniqueNumber(name string) string {
-	name += strconv.FormatInt(UniqueNumber, 10)
-	UniqueNumber++
-	return name
+// pathDerivedName returns the name of e prefixed by the name of its closest
+// parent which is not a list item, so the structures sharing a generic name
+// ("node", list items, ...) are told apart by where they are.
+func (g *Generator) pathDerivedName(e *xml.Element, name string) string {
+	p := e.Parent
+	for p != nil && g.dialect.IsListTag(p.GetName()) {
+		p = p.Parent
+	}
+	if p == nil {
+		return name
+	}
+	return p.GetName() + "_" + name
+}
+
+// memberNames returns the names of the members of s in a stable order: the
+// order of s.Order first, then the remaining members sorted by name.
+func (s *StructInfo) memberNames() []string {
+	names := make([]string, 0, len(s.Members))
+	seen := make(map[string]bool, len(s.Members))
+	for _, m := range s.Order {
+		if _, ok := s.Members[m.Name]; ok && !seen[m.Name] {
+			seen[m.Name] = true
+			names = append(names, m.Name)
+		}
+	}
+	rest := make([]string, 0, len(s.Members)-len(names))
+	for name := range s.Members {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// ElementMember is a member with the name of its element.
+type ElementMember struct {
+	*Member
+	Element string
+}
+
+// ElementMembers returns the members of s in their order, named after their
+// elements without InnerKeyword. The Go writer renames the members once it
+// has written them, so the element names are taken from the keys of
+// s.Members. The members missing from s.Order follow, sorted by name.
+func (s *StructInfo) ElementMembers() []ElementMember {
+	names := make(map[*Member]string, len(s.Members))
+	for name, m := range s.Members {
+		names[m] = name
+	}
+	ordered := make([]ElementMember, 0, len(s.Members))
+	seen := make(map[string]bool, len(s.Members))
+	for _, m := range s.Order {
+		if name, ok := names[m]; ok && !seen[name] {
+			seen[name] = true
+			ordered = append(ordered, ElementMember{Member: m, Element: name})
+		}
+	}
+	rest := make([]string, 0, len(s.Members)-len(ordered))
+	for name := range s.Members {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		ordered = append(ordered, ElementMember{Member: s.Members[name], Element: name})
+	}
+	for i := range ordered {
+		ordered[i].Element = strings.ReplaceAll(ordered[i].Element, InnerKeyword, "")
+	}
+	return ordered
 }
 
 // It seems sometimes "node", "nodes" and "subNodes" are used multiple times at multiple levels inside the same file
@@ -67,38 +165,53 @@ func needToForceRandomName(name string) bool {
 	return false
 }
 
-const maxTransversalDepth = 10
+// MaxTransversalDepth is the number of ancestors naming the structure of a
+// transversal tag.
+const MaxTransversalDepth = 10
 
 // createStructure creates a new structure from the given element.
 // Then the function will recursively call handleElement on the children of the element.
 // It removes the duplicates from the members of the struct.
-func createStructure(e *xml.Element, flag uint) any {
+func (g *Generator) createStructure(e *xml.Element, flag uint) (any, error) {
 	// forceChild is a flag that forces the child of the current child to be used
 	// It is useful for the case of lists
 	if flag&forceChild > 0 {
 		flag &^= forceChild
 		// Quick way to determine if the child is a structure
 		if e.Child != nil && e.Child.Child != nil {
-			return createStructure(e.Child, flag|forceChildApplied)
+			return g.createStructure(e.Child, flag|forceChildApplied)
 		} else {
 			// The array authorize cells to be empty
 			//panic("generate.createStructure|forceChild: missing child")
 		}
 	}
 	if e.Child == nil {
-		panic("generate.createStructure: missing child")
+		return nil, asInferenceError(newInferenceError(ErrMissingChild, "a structure must have children"), e)
 	}
 	name := e.GetName()
 	lowerName := strings.ToLower(name)
 
 	if needToForceRandomName(lowerName) && (flag&forceRandomName) == 0 {
 		flag |= forceRandomName
-	} else if helper.IsListTag(name) {
+	} else if g.dialect.IsListTag(name) {
 		// This case comes when the tag is an innerList of a list which can happen multiple times
 		// in the file, so we need to set it a random name
 
 		//log.Printf("generate.createStructure: '%s' & child name: %v & e %v & %v", name, e.Child.GetName(), lowerName, e.Parent.GetName())
-		return createStructure(e.Parent, flag|forceRandomName)
+		return g.createStructure(e.Parent, flag|forceRandomName)
+	}
+
+	// The element repeats the shape of one of its ancestors, so the
+	// structure refers to itself
+	if s := g.recursiveStructure(e); s != nil {
+		g.ancestors = append(g.ancestors, ancestor{e: e, s: s})
+		defer func() {
+			g.ancestors = g.ancestors[:len(g.ancestors)-1]
+		}()
+		if err := g.fillStructure(s, e, flag&^forceRandomName); err != nil {
+			return nil, err
+		}
+		return s, nil
 	}
 
 	// In this case, the child has the same name as his parent which
@@ -111,12 +224,13 @@ func createStructure(e *xml.Element, flag uint) any {
 		}
 		name += InnerKeyword
 	}
-	// vals is a special case where it serves as a transversal tag
-	if (name == "vals" || name == "values" || strings.Contains(lowerName, "inner")) && e.Parent != nil {
+	// Transversal tags (vals for instance) are named after their parents
+	transversal := g.dialect.IsTransversalTag(name) && e.Parent != nil
+	if transversal {
 		//log.Printf("Special case for: %v = %v", name, e.Parent.GetName()+"_"+name)
 		depth := 0
 		parent := e.Parent
-		for parent != nil && depth < maxTransversalDepth {
+		for parent != nil && depth < MaxTransversalDepth {
 			depth++
 			name = parent.GetName() + "_" + name
 			parent = parent.Parent
@@ -124,12 +238,39 @@ func createStructure(e *xml.Element, flag uint) any {
 	}
 	if (flag & forceRandomName) > 0 {
 		flag &^= forceRandomName
-		name = addUniqueNumber(name)
+		if !transversal {
+			name = g.pathDerivedName(e, name)
+		}
+		name = g.addUniqueNumber(name)
+	}
+	// The name is reserved by a generated structure
+	if g.typeNames[name] {
+		name = g.stableTypeName(g.renamedStructs, name)
+	}
+	var fileName string
+	if o := g.overrideFor(e); o != nil {
+		if o.StructName != "" {
+			name = o.StructName
+		}
+		fileName = o.FileName
 	}
 	s := &StructInfo{
-		Name:    name,
-		Members: make(map[string]*Member),
+		Name:     name,
+		Members:  make(map[string]*Member),
+		FileName: fileName,
+	}
+	g.ancestors = append(g.ancestors, ancestor{e: e, s: s})
+	defer func() {
+		g.ancestors = g.ancestors[:len(g.ancestors)-1]
+	}()
+	if err := g.fillStructure(s, e, flag); err != nil {
+		return nil, err
 	}
+	return s, nil
+}
+
+// fillStructure adds the members of the children of e to s.
+func (g *Generator) fillStructure(s *StructInfo, e *xml.Element, flag uint) error {
 	// The forceFullCheck check apply only to this structure, not to the children
 
 	// If "forceFullCheck" is asked, it means we are in a slice/map, and we want
@@ -144,45 +285,64 @@ func createStructure(e *xml.Element, flag uint) any {
 		}
 		flag &^= forceFullCheck | forceChildApplied
 		for n != nil {
-			if err := handleElement(n.Child, s, flag); err != nil {
-				panic(err)
+			g.countInstance(s)
+			g.addAttributes(s, n)
+			if err := g.handleElement(n.Child, s, flag); err != nil {
+				return err
 			}
 			n = n.Next
 		}
-	} else {
-		if err := handleElement(e.Child, s, flag&^forceFullCheck); err != nil {
-			panic(err)
-		}
+		return nil
 	}
-	flag &^= forceChildApplied
-	return s
+	g.countInstance(s)
+	g.addAttributes(s, e)
+	return g.handleElement(e.Child, s, flag&^forceFullCheck)
 }
 
 // addMember adds a new Member to the StructInfo map.
 // If the Member already exists, the function checks if the type of the existing Member and the new Member are the same.
 // If they are not, the function fixes the type mismatch.
-func (s *StructInfo) addMember(name string, attr attributes.Attributes, t any) {
+// e is the element the member comes from.
+func (g *Generator) addMember(s *StructInfo, e *xml.Element, t any) error {
+	name, attr := e.GetName(), e.Attr
+	o := g.overrideFor(e)
+	pinned := o != nil && o.Type != ""
+	g.recordMember(s, e)
+	g.countOccurrence(s, name)
+	m, ok := s.Members[name]
 	// If there is no existing Member with the same name, add the new Member to the map
-	if _, ok := s.Members[name]; !ok {
-		s.Members[name] = &Member{
-			T:    t,
-			Attr: attr,
-			Name: name,
+	if !ok {
+		m = &Member{
+			T:      t,
+			Attr:   attr,
+			Name:   name,
+			Pinned: pinned,
 		}
-		s.Order = append(s.Order, s.Members[name])
-	} else {
+		s.Members[name] = m
+		s.Order = append(s.Order, m)
+	} else if pinned {
+		m.T = t
+		m.Pinned = true
+	} else if m.Pinned {
+		// The type of the member is given by the configuration
+	} else if !IsSameType(t, m.T) {
 		// Check if the existing Member and the new Member are of the same type
-		if !IsSameType(t, s.Members[name].T, 0) {
-			// log.Printf("Type mismatch: %v > %v | %v", name, s.Members[name].T, t)
-			// If the types are different, fix the type mismatch
-			err := fixTypeMismatch(s.Members[name], &Member{
-				Name: name,
-				T:    t,
-				Attr: attr,
-			})
-			if err != nil {
-				panic(err)
+		// log.Printf("Type mismatch: %v > %v | %v", name, m.T, t)
+		// If the types are different, fix the type mismatch
+		err := g.fixMemberMismatch(s.Name, m, &Member{
+			Name: name,
+			T:    t,
+			Attr: attr,
+		})
+		if err != nil {
+			if _, err = g.fallbackToElement(err, e); err != nil {
+				return err
 			}
+			m.T = createXMLElementType()
 		}
 	}
+	if o != nil && o.FieldName != "" {
+		m.FieldName = o.FieldName
+	}
+	return nil
 }
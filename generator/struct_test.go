@@ -8,6 +8,10 @@ import (
 	"github.com/go-test/deep"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/file"
+	"github.com/cruffinoni/rimworld-editor/xml"
+	"github.com/cruffinoni/rimworld-editor/xml/dialect"
 )
 
 func Test_createStructure(t *testing.T) {
@@ -125,8 +129,9 @@ func Test_createStructure(t *testing.T) {
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			root := resetVarsAndReadBuffer(t, tt.args)
-			res := createStructure(root.Child, tt.args.flag)
+			g, root := newGeneratorAndReadBuffer(t, tt.args)
+			res, err := g.createStructure(root.Child, tt.args.flag)
+			require.NoError(t, err)
 			require.IsType(t, res, tt.want)
 			got := res.(*StructInfo)
 			wanted := tt.want.(*StructInfo)
@@ -669,10 +674,348 @@ func TestGenerateGoFiles(t *testing.T) {
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			root := resetVarsAndReadBuffer(t, tt.args)
-			if diff := deep.Equal(tt.want, GenerateGoFiles(root, true)); diff != nil {
+			g, root := newGeneratorAndReadBuffer(t, tt.args)
+			got, err := g.GenerateGoFiles(root)
+			require.NoError(t, err)
+			if diff := deep.Equal(tt.want, got); diff != nil {
 				assert.FailNow(t, strings.Join(diff, "\n"))
 			}
 		})
 	}
 }
+
+func TestGenerateGoFilesFromCorpus(t *testing.T) {
+	documents := []string{`
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<version>1</version>
+	<mods />
+</savegame>
+`, `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<version>2</version>
+	<mods>
+		<li>Core</li>
+		<li>Royalty</li>
+	</mods>
+	<seed>17.5</seed>
+</savegame>
+`}
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		roots = append(roots, readBuffer(t, d))
+	}
+	got, err := New().GenerateGoFilesFromCorpus(roots)
+	require.NoError(t, err)
+	require.Contains(t, got.Members, "savegame")
+	want := createStructForTest("savegame", map[string]*Member{
+		"version": {T: reflect.Int64},
+		"mods":    {T: createCustomSliceForTest(reflect.String)},
+		"seed":    {T: reflect.Float64},
+	})
+	savegame, ok := got.Members["savegame"].T.(*StructInfo)
+	require.True(t, ok)
+	require.Len(t, savegame.Members, len(want.Members))
+	for name, m := range want.Members {
+		require.Contains(t, savegame.Members, name)
+		if diff := deep.Equal(m.T, savegame.Members[name].T); diff != nil {
+			assert.FailNow(t, name+": "+strings.Join(diff, "\n"))
+		}
+	}
+}
+
+func TestGenerator_isolation(t *testing.T) {
+	documents := map[string]string{
+		"quests": `<savegame><quests><completed>True</completed></quests></savegame>`,
+		"pawns":  `<savegame><pawns><li><name>Tynan</name></li></pawns></savegame>`,
+	}
+	for name, d := range documents {
+		name, d := name, d
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := New()
+			_, err := g.GenerateGoFiles(readBuffer(t, d))
+			require.NoError(t, err)
+			require.Contains(t, g.RegisteredMembers, "savegame")
+			for other := range documents {
+				if other != name {
+					require.NotContains(t, g.RegisteredMembers, other)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateGoFiles_inferenceError(t *testing.T) {
+	const document = `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<progress>
+		<keys>
+			<li>CataphractArmor</li>
+		</keys>
+	</progress>
+</savegame>
+`
+	t.Run("strict", func(t *testing.T) {
+		_, err := New().GenerateGoFiles(readBuffer(t, document))
+		require.Error(t, err)
+		require.ErrorIs(t, err, ErrMissingChild)
+		var ie *InferenceError
+		require.ErrorAs(t, err, &ie)
+		assert.Equal(t, "savegame>progress", ie.Path)
+	})
+	t.Run("lenient", func(t *testing.T) {
+		g := New(WithLenientInference(true))
+		got, err := g.GenerateGoFiles(readBuffer(t, document))
+		var problems InferenceErrors
+		require.ErrorAs(t, err, &problems)
+		require.Len(t, problems, 1)
+		assert.Equal(t, g.Problems(), problems)
+		assert.ErrorIs(t, err, ErrMissingChild)
+		assert.NotErrorIs(t, err, ErrUnsolvableMismatch)
+		savegame, ok := got.Members["savegame"].T.(*StructInfo)
+		require.True(t, ok)
+		require.Contains(t, savegame.Members, "progress")
+		assert.IsType(t, &xml.Element{}, savegame.Members["progress"].T)
+	})
+}
+
+func TestGenerateGoFilesFromCorpus_inferenceError(t *testing.T) {
+	// The versions of pawn can't be reconciled once the inference is done
+	documents := []string{
+		`<savegame><pawn><values><li>1</li><li>2</li></values></pawn></savegame>`,
+		`<savegame><pawn><values>7</values></pawn></savegame>`,
+	}
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		roots = append(roots, readBuffer(t, d))
+	}
+	_, err := New().GenerateGoFilesFromCorpus(roots)
+	var ie *InferenceError
+	require.ErrorAs(t, err, &ie)
+	assert.Equal(t, "pawn.values", ie.Member)
+	assert.Equal(t, "savegame>pawn>values", ie.Path)
+	assert.True(t, strings.HasPrefix(ie.Error(), "savegame>pawn>values: "), ie.Error())
+}
+
+func TestGenerateGoFiles_dialect(t *testing.T) {
+	const document = `
+<?xml version="1.0" encoding="utf-8"?>
+<catalog>
+	<tags>
+		<item>new</item>
+		<item>sale</item>
+	</tags>
+	<prices>
+		<names>
+			<item>book</item>
+		</names>
+		<amounts>
+			<item>12.5</item>
+		</amounts>
+	</prices>
+</catalog>
+`
+	d := &dialect.Dialect{
+		ListTags:  []string{"item"},
+		MapKeys:   "names",
+		MapValues: "amounts",
+	}
+	require.NoError(t, d.Validate())
+	o, err := file.ReadFromBufferWithDialect(document, d)
+	require.NoError(t, err)
+	got, err := New(WithDialect(d)).GenerateGoFiles(o.XML.Root)
+	require.NoError(t, err)
+	catalog, ok := got.Members["catalog"].T.(*StructInfo)
+	require.True(t, ok)
+	want := map[string]any{
+		"tags":   createCustomSliceForTest(reflect.String),
+		"prices": createCustomMapForTest(reflect.String, reflect.Float64),
+	}
+	for name, w := range want {
+		require.Contains(t, catalog.Members, name)
+		if diff := deep.Equal(w, catalog.Members[name].T); diff != nil {
+			assert.FailNow(t, name+": "+strings.Join(diff, "\n"))
+		}
+	}
+}
+
+// describeRegisteredMembers returns a stable textual form of the structures
+// registered by g, with their members in order.
+func describeRegisteredMembers(g *Generator) string {
+	var sb strings.Builder
+	for _, name := range g.RegisteredMembers.Names() {
+		sb.WriteString(name + ":")
+		for _, v := range g.RegisteredMembers[name] {
+			sb.WriteString(" [")
+			for _, m := range v.Order {
+				sb.WriteString(" " + m.Name + "=" + getTypeName(m.T))
+			}
+			sb.WriteString(" ]")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func TestGenerateGoFiles_deterministic(t *testing.T) {
+	const document = `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<world>
+		<subnodes>
+			<id>1</id>
+			<label>first</label>
+		</subnodes>
+		<grid>
+			<subnodes>
+				<x>3</x>
+			</subnodes>
+		</grid>
+	</world>
+	<pawns>
+		<li>
+			<name>Tynan</name>
+			<age>32</age>
+		</li>
+		<li>
+			<name>Randy</name>
+			<skills>
+				<li>
+					<def>Shooting</def>
+					<level>4</level>
+				</li>
+			</skills>
+		</li>
+	</pawns>
+</savegame>
+`
+	var want string
+	for i := 0; i < 20; i++ {
+		g := New()
+		_, err := g.GenerateGoFiles(readBuffer(t, document))
+		require.NoError(t, err)
+		got := describeRegisteredMembers(g)
+		if i == 0 {
+			want = got
+			// The generic names are prefixed by their parent and suffixed
+			// by a counter of their own.
+			require.Contains(t, g.RegisteredMembers, "world_subnodes0")
+			require.Contains(t, g.RegisteredMembers, "grid_subnodes0")
+			continue
+		}
+		require.Equal(t, want, got)
+	}
+}
+
+func TestGenerateGoFiles_occurrences(t *testing.T) {
+	documents := []string{`
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<world>
+		<seed>42</seed>
+	</world>
+	<pawns>
+		<li>
+			<name>Tynan</name>
+			<age>32</age>
+		</li>
+		<li>
+			<name>Randy</name>
+		</li>
+		<li>
+			<name>Cassandra</name>
+			<age>28</age>
+		</li>
+	</pawns>
+</savegame>
+`, `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<world>
+		<seed>7</seed>
+		<name>Rimworld</name>
+	</world>
+</savegame>
+`}
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		roots = append(roots, readBuffer(t, d))
+	}
+	g := New()
+	got, err := g.GenerateGoFilesFromCorpus(roots)
+	require.NoError(t, err)
+
+	savegame := got.Members["savegame"].T.(*StructInfo)
+	require.Equal(t, 2, g.RegisteredMembers["savegame"][0].Instances)
+	assert.False(t, savegame.IsOptional(savegame.Members["world"]))
+	assert.True(t, savegame.IsOptional(savegame.Members["pawns"]))
+
+	world := g.RegisteredMembers["world"][0]
+	assert.Equal(t, 2, world.Instances)
+	assert.Equal(t, 2, world.Members["seed"].Occurrences)
+	assert.Equal(t, 1, world.Members["name"].Occurrences)
+	assert.True(t, world.IsOptional(world.Members["name"]))
+
+	pawn := savegame.Members["pawns"].T.(*CustomType).Type1.(*StructInfo)
+	pawn = g.RegisteredMembers[pawn.Name][0]
+	assert.Equal(t, 3, pawn.Instances)
+	assert.Equal(t, 3, pawn.Members["name"].Occurrences)
+	assert.Equal(t, 2, pawn.Members["age"].Occurrences)
+	assert.False(t, pawn.IsOptional(pawn.Members["name"]))
+	assert.True(t, pawn.IsOptional(pawn.Members["age"]))
+
+	t.Run("repeated sibling", func(t *testing.T) {
+		g := New()
+		got, err := g.GenerateGoFiles(readBuffer(t, `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawns>
+		<li>
+			<name>Tynan</name>
+			<tag><label>colonist</label></tag>
+			<tag><label>drafted</label></tag>
+		</li>
+		<li>
+			<name>Randy</name>
+		</li>
+	</pawns>
+</savegame>
+`))
+		require.NoError(t, err)
+		savegame := got.Members["savegame"].T.(*StructInfo)
+		pawn := savegame.Members["pawns"].T.(*CustomType).Type1.(*StructInfo)
+		pawn = g.RegisteredMembers[pawn.Name][0]
+		assert.Equal(t, 2, pawn.Instances)
+		assert.Equal(t, 1, pawn.Members["tag"].Occurrences)
+		assert.True(t, pawn.IsOptional(pawn.Members["tag"]))
+	})
+}
+
+func TestStructInfo_ElementMembers(t *testing.T) {
+	g := New()
+	got, err := g.GenerateGoFiles(readBuffer(t, `
+<?xml version="1.0" encoding="utf-8"?>
+<game>
+	<tick>12</tick>
+	<game><seed>42</seed></game>
+</game>
+`))
+	require.NoError(t, err)
+	game := g.RegisteredMembers.Best(got.Members["game"].T.(*StructInfo))
+	assert.Same(t, g.RegisteredMembers["game"][0], game)
+	// The writer renames the members
+	for _, m := range game.Order {
+		m.Name = strings.ToUpper(m.Name)
+	}
+	var elements []string
+	for _, m := range game.ElementMembers() {
+		elements = append(elements, m.Element)
+	}
+	assert.Equal(t, []string{"tick", "game"}, elements)
+
+	orphan := &StructInfo{Name: "orphan"}
+	assert.Same(t, orphan, g.RegisteredMembers.Best(orphan))
+}
@@ -0,0 +1,249 @@
+package generator
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Shape is the layout of the elements of a type. The variants of a Union are
+// told apart by their shape, so a Union has at most one variant per shape.
+type Shape int
+
+// The shapes are sorted from the most specific to the least specific one,
+// like the shapes of the unmarshal package.
+const (
+	ShapeEmpty Shape = iota
+	ShapeBool
+	ShapeInt
+	ShapeFloat
+	ShapeText
+	ShapeList
+	ShapeMap
+	ShapeStructure
+)
+
+var shapeNames = [...]string{"Empty", "Bool", "Int", "Float", "Text", "List", "Map", "Structure"}
+
+func (s Shape) String() string {
+	return shapeNames[s]
+}
+
+// Variant is one of the types of a Union.
+type Variant struct {
+	Shape Shape
+	T     any
+}
+
+// Union is the type of the members whose elements have different shapes
+// that can't be reconciled, e.g. a number in some elements and a list in
+// others. See WithUnions.
+type Union struct {
+	// Name is set once the generation is done.
+	Name string
+	// Variants are sorted by shape.
+	Variants []*Variant
+}
+
+// WithUnions types the members whose types can't be reconciled as a Union of
+// these types instead of an *xml.Element, as long as their elements can be
+// told apart by their shape. It's disabled by default.
+func WithUnions(enabled bool) Option {
+	return func(g *Generator) {
+		g.unions = enabled
+	}
+}
+
+// shapeOf returns the shape of the elements of type t. ok is false if t
+// can't be a variant of a Union.
+func shapeOf(t any) (s Shape, ok bool) {
+	switch va := t.(type) {
+	case reflect.Kind:
+		switch va {
+		case reflect.Bool:
+			return ShapeBool, true
+		case reflect.Int64:
+			return ShapeInt, true
+		case reflect.Float64:
+			return ShapeFloat, true
+		case reflect.String:
+			return ShapeText, true
+		}
+	case *Enum:
+		return ShapeText, true
+	case *StructInfo:
+		return ShapeStructure, true
+	case *FixedArray:
+		return ShapeList, true
+	case *CustomType:
+		switch {
+		case va.External || IsMultipleType(va):
+			return 0, false
+		case IsEmptyType(va):
+			return ShapeEmpty, true
+		case IsEmbeddedType(va):
+			return ShapeText, true
+		case va.Name == "Map":
+			return ShapeMap, true
+		case va.Name == "Slice":
+			return ShapeList, true
+		}
+	}
+	return 0, false
+}
+
+// addVariant adds t to the variants of u. A type having the shape of a
+// variant is reconciled with it.
+func (g *Generator) addVariant(u *Union, t any) error {
+	if o, ok := t.(*Union); ok {
+		for _, v := range o.Variants {
+			if err := g.addVariant(u, v.T); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	shape, ok := shapeOf(t)
+	if !ok {
+		return newInferenceError(ErrUnsolvableMismatch, "the type can't be a variant of a union", t)
+	}
+	for _, v := range u.Variants {
+		if v.Shape != shape {
+			continue
+		}
+		if IsSameType(v.T, t) {
+			return nil
+		}
+		// Both types have the same shape, so they can't be told apart
+		a, b := &Member{T: v.T}, &Member{T: t}
+		if err := g.fixTypeMismatch(a, b); err != nil {
+			return err
+		}
+		v.T = a.T
+		return nil
+	}
+	u.Variants = append(u.Variants, &Variant{Shape: shape, T: t})
+	sort.SliceStable(u.Variants, func(i, j int) bool {
+		return u.Variants[i].Shape < u.Variants[j].Shape
+	})
+	return nil
+}
+
+// unite replaces the types aType and bType, which can't be reconciled, by a
+// Union of both. A Union already there is extended.
+func (g *Generator) unite(aType, bType *any) error {
+	if !g.unions {
+		return ErrUnsolvableMismatch
+	}
+	u, ok := (*aType).(*Union)
+	other := *bType
+	if !ok {
+		if u, ok = (*bType).(*Union); ok {
+			other = *aType
+		} else {
+			u = &Union{}
+			if err := g.addVariant(u, *aType); err != nil {
+				return err
+			}
+		}
+	}
+	if err := g.addVariant(u, other); err != nil {
+		return err
+	}
+	g.tagBranch(BranchUnion)
+	*aType, *bType = u, u
+	return nil
+}
+
+// fixOrUnite calls fixTypeMismatch and unites the types of a and b when they
+// can't be reconciled.
+func (g *Generator) fixOrUnite(a, b *Member) error {
+	err := g.fixTypeMismatch(a, b)
+	if err == nil || !g.unions || !errors.Is(err, ErrUnsolvableMismatch) && !errors.Is(err, ErrUnhandledType) {
+		return err
+	}
+	if g.unite(&a.T, &b.T) != nil {
+		return err
+	}
+	return nil
+}
+
+func isSameUnion(c *typeComparer, a, b *Union) bool {
+	if len(a.Variants) != len(b.Variants) {
+		return false
+	}
+	for i, v := range a.Variants {
+		if v.Shape != b.Variants[i].Shape || !c.isSameType(v.T, b.Variants[i].T) {
+			return false
+		}
+	}
+	return true
+}
+
+// nameUnions names the unions of the members of the registered structures
+// after the structure and the member. It must run once the structures are
+// deduplicated.
+func (g *Generator) nameUnions() {
+	if !g.unions {
+		return
+	}
+	for _, name := range g.RegisteredMembers.Names() {
+		versions := g.RegisteredMembers[name]
+		if len(versions) == 0 {
+			continue
+		}
+		for _, mName := range versions[0].memberNames() {
+			g.nameUnion(versions[0].Members[mName].T, name+"_"+mName)
+		}
+	}
+}
+
+// nameUnion names the unions of t, if they aren't yet.
+func (g *Generator) nameUnion(t any, name string) {
+	switch va := t.(type) {
+	case *Union:
+		if va.Name == "" {
+			va.Name = g.freeTypeName(name)
+		}
+	case *CustomType:
+		g.nameUnion(va.Type1, name+"_item")
+		g.nameUnion(va.Type2, name+"_value")
+	case *FixedArray:
+		g.nameUnion(va.PrimaryType, name+"_item")
+	}
+}
+
+// VariantName returns the name of the variant v, unique in its Union.
+func (u *Union) VariantName(v *Variant) string {
+	name := variantName(v.T)
+	for _, o := range u.Variants {
+		if o != v && variantName(o.T) == name {
+			return name + "_" + v.Shape.String()
+		}
+	}
+	return name
+}
+
+func variantName(t any) string {
+	switch va := t.(type) {
+	case reflect.Kind:
+		return va.String()
+	case *Enum:
+		return va.Name
+	case *StructInfo:
+		return va.Name
+	case *FixedArray:
+		return "array_" + variantName(va.PrimaryType)
+	case *CustomType:
+		if va.Type1 == nil {
+			return strings.TrimPrefix(va.Pkg, "*") + "_" + va.Name
+		}
+		name := va.Name + "_" + variantName(va.Type1)
+		if va.Type2 != nil {
+			name += "_" + variantName(va.Type2)
+		}
+		return name
+	}
+	return "value"
+}
@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+func TestGenerator_unions(t *testing.T) {
+	documents := []string{`
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<values>
+			<li>1</li>
+			<li>2</li>
+		</values>
+		<owner>Tynan</owner>
+	</pawn>
+</savegame>
+`, `
+<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<values>7</values>
+		<owner>
+			<name>Randy</name>
+		</owner>
+	</pawn>
+</savegame>
+`}
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		roots = append(roots, readBuffer(t, d))
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		g := New(WithLenientInference(true))
+		_, err := g.GenerateGoFilesFromCorpus(roots)
+		require.Error(t, err)
+	})
+
+	g := New(WithUnions(true), WithReport(true))
+	got, err := g.GenerateGoFilesFromCorpus(roots)
+	require.NoError(t, err)
+	pawn := got.Members["savegame"].T.(*StructInfo).Members["pawn"].T.(*StructInfo)
+
+	values, ok := pawn.Members["values"].T.(*Union)
+	require.True(t, ok)
+	assert.Equal(t, "pawn_values", values.Name)
+	require.Len(t, values.Variants, 2)
+	assert.Equal(t, ShapeInt, values.Variants[0].Shape)
+	assert.Equal(t, reflect.Int64, values.Variants[0].T)
+	assert.Equal(t, ShapeList, values.Variants[1].Shape)
+	slice, ok := values.Variants[1].T.(*CustomType)
+	require.True(t, ok)
+	assert.Equal(t, reflect.Int64, slice.Type1)
+	assert.Equal(t, "int64", values.VariantName(values.Variants[0]))
+	assert.Equal(t, "Slice_int64", values.VariantName(values.Variants[1]))
+
+	owner, ok := pawn.Members["owner"].T.(*Union)
+	require.True(t, ok)
+	assert.Equal(t, "pawn_owner", owner.Name)
+	require.Len(t, owner.Variants, 2)
+	assert.Equal(t, ShapeText, owner.Variants[0].Shape)
+	assert.Equal(t, ShapeStructure, owner.Variants[1].Shape)
+	assert.IsType(t, &StructInfo{}, owner.Variants[1].T)
+
+	r := g.Report()
+	require.NotNil(t, r)
+	assert.Empty(t, r.Fallbacks)
+	m := findMemberReport(findStructReport(r, "pawn"), "values")
+	require.NotNil(t, m)
+	require.NotEmpty(t, m.Reconciliations)
+	assert.Contains(t, m.Reconciliations[0].Branches, BranchUnion)
+
+	t.Run("same_shape", func(t *testing.T) {
+		g := New(WithUnions(true))
+		u := &Union{}
+		require.NoError(t, g.addVariant(u, reflect.Int64))
+		require.NoError(t, g.addVariant(u, reflect.Int64))
+		require.NoError(t, g.addVariant(u, reflect.String))
+		assert.Len(t, u.Variants, 2)
+		assert.ErrorIs(t, g.addVariant(u, &xml.Element{}), ErrUnsolvableMismatch)
+	})
+}
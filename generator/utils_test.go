@@ -22,15 +22,17 @@ type tests struct {
 	want any
 }
 
-func resetVarsAndReadBuffer(t *testing.T, args args) *xml.Element {
-	UniqueNumber = 0
-	RegisteredMembers = make(MemberVersioning)
-	root, err := file.ReadFromBuffer(args.xmlContent)
+func readBuffer(t *testing.T, xmlContent string) *xml.Element {
+	root, err := file.ReadFromBuffer(xmlContent)
 	require.Nil(t, err)
 	require.NotNil(t, root)
 	return root.XML.Root
 }
 
+func newGeneratorAndReadBuffer(t *testing.T, args args) (*Generator, *xml.Element) {
+	return New(), readBuffer(t, args.xmlContent)
+}
+
 type emptyStructWithAttr struct {
 	Attr attributes.Attributes
 }
@@ -0,0 +1,70 @@
+package xsd
+
+import (
+	_xml "encoding/xml"
+	"strings"
+)
+
+type attr struct {
+	name, value string
+}
+
+// node is a tag of the schema.
+type node struct {
+	tag      string
+	attr     []attr
+	children []*node
+}
+
+// xs returns the schema tag named tag with the attributes attr, given as
+// name-value pairs. The attributes with an empty value are left out.
+func xs(tag string, attr ...string) *node {
+	n := &node{tag: "xs:" + tag}
+	for i := 0; i+1 < len(attr); i += 2 {
+		n.set(attr[i], attr[i+1])
+	}
+	return n
+}
+
+// set sets the attribute name of n to value, unless value is empty.
+func (n *node) set(name, value string) *node {
+	if value == "" {
+		return n
+	}
+	for i := range n.attr {
+		if n.attr[i].name == name {
+			n.attr[i].value = value
+			return n
+		}
+	}
+	n.attr = append(n.attr, attr{name: name, value: value})
+	return n
+}
+
+// add appends the non-nil children to n.
+func (n *node) add(children ...*node) *node {
+	for _, c := range children {
+		if c != nil {
+			n.children = append(n.children, c)
+		}
+	}
+	return n
+}
+
+func (n *node) write(sb *strings.Builder, depth int) {
+	sb.WriteString(strings.Repeat("\t", depth) + "<" + n.tag)
+	for _, a := range n.attr {
+		sb.WriteString(" " + a.name + `="`)
+		_ = _xml.EscapeText(sb, []byte(a.value))
+		sb.WriteString(`"`)
+	}
+	if len(n.children) == 0 {
+		sb.WriteString("/>\n")
+		return
+	}
+	sb.WriteString(">\n")
+	for _, c := range n.children {
+		c.write(sb, depth+1)
+	}
+	sb.WriteString(strings.Repeat("\t", depth) + "</" + n.tag + ">\n")
+}
@@ -0,0 +1,797 @@
+package xsd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cruffinoni/rimworld-editor/file"
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/generator/paths"
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+var (
+	ErrNotSchema   = errors.New("xsd: the document isn't a schema")
+	ErrNoElement   = errors.New("xsd: the schema declares no element")
+	ErrUnsupported = errors.New("xsd: unsupported declaration")
+	ErrUnknownRef  = errors.New("xsd: unknown reference")
+)
+
+// builtinKinds are the kinds of the built-in types of XML Schema. The other
+// built-in types are strings, xs:anyType is untyped.
+var builtinKinds = map[string]reflect.Kind{
+	"anyType":            reflect.Invalid,
+	"boolean":            reflect.Bool,
+	"decimal":            reflect.Float64,
+	"float":              reflect.Float64,
+	"double":             reflect.Float64,
+	"integer":            reflect.Int64,
+	"int":                reflect.Int64,
+	"long":               reflect.Int64,
+	"short":              reflect.Int64,
+	"byte":               reflect.Int64,
+	"nonNegativeInteger": reflect.Int64,
+	"nonPositiveInteger": reflect.Int64,
+	"positiveInteger":    reflect.Int64,
+	"negativeInteger":    reflect.Int64,
+	"unsignedLong":       reflect.Int64,
+	"unsignedInt":        reflect.Int64,
+	"unsignedShort":      reflect.Int64,
+	"unsignedByte":       reflect.Int64,
+}
+
+// particle is an element of a content model.
+type particle struct {
+	decl *xml.Element
+	name string
+	// optional is set when the element may be missing.
+	optional bool
+	// maxOccurs is -1 when it's unbounded.
+	maxOccurs int
+}
+
+type structKey struct {
+	name string
+	t    *xml.Element
+}
+
+type reader struct {
+	options
+	elements        map[string]*xml.Element
+	complexTypes    map[string]*xml.Element
+	simpleTypes     map[string]*xml.Element
+	groups          map[string]*xml.Element
+	attributeGroups map[string]*xml.Element
+	registered      generator.MemberVersioning
+	// structs are the structures read by name and type, so the elements
+	// sharing both share their structure.
+	structs map[structKey]*generator.StructInfo
+	// expanding are the structures being read by type, a type found again
+	// while it's read refers to itself.
+	expanding map[*xml.Element]*generator.StructInfo
+	enums     map[*xml.Element]*generator.Enum
+	enumList  []*generator.Enum
+}
+
+// Read reads the XML Schema content and returns the structures of the
+// documents it describes: root, whose members are the global elements, and
+// every version of the structures by name, root included.
+//
+// The structures are named like the generator names the ones it infers, so
+// they can seed a generation which the samples refine, see
+// generator.WithSeed. A generation seeded without samples also reconciles
+// the versions of the structures declared with different types, so it's how
+// the Go types of a schema are generated.
+//
+// The schema must be in a single file. The mixed contents, except the unions
+// of the exported schemas with WithUnions, and xs:anyType are typed
+// *xml.Element, xs:any and xs:anyAttribute are ignored.
+func Read(content string, opts ...Option) (root *generator.StructInfo, registered generator.MemberVersioning, err error) {
+	o, err := file.ReadFromBuffer(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	schema := o.XML.Root
+	if schema == nil || schema.GetName() != "schema" {
+		return nil, nil, ErrNotSchema
+	}
+	r := &reader{
+		options:         newOptions(opts),
+		elements:        make(map[string]*xml.Element),
+		complexTypes:    make(map[string]*xml.Element),
+		simpleTypes:     make(map[string]*xml.Element),
+		groups:          make(map[string]*xml.Element),
+		attributeGroups: make(map[string]*xml.Element),
+		registered:      make(generator.MemberVersioning),
+		structs:         make(map[structKey]*generator.StructInfo),
+		expanding:       make(map[*xml.Element]*generator.StructInfo),
+		enums:           make(map[*xml.Element]*generator.Enum),
+	}
+	var globals []*xml.Element
+	for n := schema.Child; n != nil; n = n.Next {
+		name := n.Attr.Get("name")
+		switch n.GetName() {
+		case "element":
+			r.elements[name] = n
+			globals = append(globals, n)
+		case "complexType":
+			r.complexTypes[name] = n
+		case "simpleType":
+			r.simpleTypes[name] = n
+		case "group":
+			r.groups[name] = n
+		case "attributeGroup":
+			r.attributeGroups[name] = n
+		case "import", "include", "redefine", "override":
+			return nil, nil, fmt.Errorf("%w: <%s>, the schema must be in a single file", ErrUnsupported, n.GetName())
+		}
+	}
+	if len(globals) == 0 {
+		return nil, nil, ErrNoElement
+	}
+	root = &generator.StructInfo{
+		Name:      generator.BasicStructName + "0",
+		Members:   make(map[string]*generator.Member),
+		Instances: 1,
+	}
+	for _, e := range globals {
+		name := e.Attr.Get("name")
+		t, err := r.elementType(e, []string{name})
+		if err != nil {
+			return nil, nil, err
+		}
+		addMember(root, name, t, false)
+	}
+	r.registered[root.Name] = []*generator.StructInfo{root}
+	r.nameEnums()
+	return root, r.registered, nil
+}
+
+// local returns the local part of the qualified name qname. The prefixes
+// aren't resolved: the names of the schema are looked up first, then the
+// built-in types.
+func local(qname string) string {
+	if i := strings.LastIndexByte(qname, ':'); i >= 0 {
+		return qname[i+1:]
+	}
+	return qname
+}
+
+// occurs returns the occurrence bounds of the particle n, maxOccurs is -1
+// when unbounded.
+func occurs(n *xml.Element) (minOccurs, maxOccurs int) {
+	minOccurs, maxOccurs = 1, 1
+	if v := n.Attr.Get("minOccurs"); v != "" {
+		minOccurs, _ = strconv.Atoi(v)
+	}
+	switch v := n.Attr.Get("maxOccurs"); v {
+	case "":
+	case "unbounded":
+		maxOccurs = -1
+	default:
+		maxOccurs, _ = strconv.Atoi(v)
+	}
+	return minOccurs, maxOccurs
+}
+
+// multiplyOccurs returns the maximum occurrences of a particle which may
+// occur a times in a group which may occur b times.
+func multiplyOccurs(a, b int) int {
+	if a < 0 || b < 0 {
+		return -1
+	}
+	return a * b
+}
+
+func addMember(s *generator.StructInfo, name string, t any, optional bool) {
+	if _, ok := s.Members[name]; ok {
+		return
+	}
+	m := &generator.Member{T: t, Name: name, Occurrences: s.Instances}
+	if optional {
+		m.Occurrences = 0
+	}
+	s.Members[name] = m
+	s.Order = append(s.Order, m)
+}
+
+// structName names the structure of the element at path, from the root
+// element, like the generator does.
+func (r *reader) structName(path []string) string {
+	name, parents := path[len(path)-1], path[:len(path)-1]
+	if len(parents) > 0 && name == parents[len(parents)-1] {
+		for i := len(parents) - 1; i >= 0; i-- {
+			name += "_" + parents[i]
+		}
+		name += generator.InnerKeyword
+	}
+	if r.dialect.IsTransversalTag(name) {
+		for i, depth := len(parents)-1, 0; i >= 0 && depth < generator.MaxTransversalDepth; i, depth = i-1, depth+1 {
+			name = parents[i] + "_" + name
+		}
+	}
+	return name
+}
+
+// elementType returns the type of the element declared by decl, at path.
+func (r *reader) elementType(decl *xml.Element, path []string) (any, error) {
+	if ref := decl.Attr.Get("ref"); ref != "" {
+		global, ok := r.elements[local(ref)]
+		if !ok {
+			return nil, fmt.Errorf("%w: element %s", ErrUnknownRef, ref)
+		}
+		decl = global
+	}
+	if t := decl.Attr.Get("type"); t != "" {
+		return r.namedType(t, path)
+	}
+	for c := decl.Child; c != nil; c = c.Next {
+		switch c.GetName() {
+		case "complexType":
+			return r.complexType(c, path)
+		case "simpleType":
+			return r.simpleType(c, path), nil
+		}
+	}
+	return &xml.Element{}, nil
+}
+
+// namedType returns the type named qname, for the element at path.
+func (r *reader) namedType(qname string, path []string) (any, error) {
+	name := local(qname)
+	if n, ok := r.complexTypes[name]; ok {
+		return r.complexType(n, path)
+	}
+	if n, ok := r.simpleTypes[name]; ok {
+		return r.simpleType(n, path), nil
+	}
+	k, ok := builtinKinds[name]
+	switch {
+	case !ok:
+		return reflect.String, nil
+	case k == reflect.Invalid:
+		return &xml.Element{}, nil
+	}
+	return k, nil
+}
+
+// simpleType returns the type of the simple type n, for the element at
+// path: a kind or an Enum for the enumerated strings.
+func (r *reader) simpleType(n *xml.Element, path []string) any {
+	kind, values := r.simpleKind(n)
+	if kind == reflect.Invalid {
+		return reflect.String
+	}
+	if kind != reflect.String || len(values) == 0 {
+		return kind
+	}
+	if isBoolEnumeration(values) {
+		return reflect.Bool
+	}
+	if e, ok := r.enums[n]; ok {
+		return e
+	}
+	name := n.Attr.Get("name")
+	if name == "" {
+		name = path[0]
+		if len(path) > 1 {
+			name = r.structName(path[:len(path)-1]) + "_" + path[len(path)-1]
+		}
+	}
+	sort.Strings(values)
+	e := &generator.Enum{Name: name, Values: values}
+	r.enums[n] = e
+	r.enumList = append(r.enumList, e)
+	return e
+}
+
+// isBoolEnumeration reports whether values are the booleans, like the
+// boolean type of the exported schemas.
+func isBoolEnumeration(values []string) bool {
+	for _, v := range values {
+		if !strings.EqualFold(v, "true") && !strings.EqualFold(v, "false") {
+			return false
+		}
+	}
+	return true
+}
+
+// simpleKind returns the kind of the simple type n and its enumerated
+// values. The kind is reflect.Invalid if n only accepts the empty string.
+func (r *reader) simpleKind(n *xml.Element) (reflect.Kind, []string) {
+	for c := n.Child; c != nil; c = c.Next {
+		switch c.GetName() {
+		case "restriction":
+			kind := reflect.String
+			if base := c.Attr.Get("base"); base != "" {
+				kind = r.baseKind(base)
+			}
+			var values []string
+			for f := c.Child; f != nil; f = f.Next {
+				switch f.GetName() {
+				case "simpleType":
+					kind, _ = r.simpleKind(f)
+				case "enumeration":
+					values = append(values, f.Attr.Get("value"))
+				case "length", "maxLength":
+					if f.Attr.Get("value") == "0" {
+						return reflect.Invalid, nil
+					}
+				}
+			}
+			return kind, values
+		case "union":
+			var kinds []reflect.Kind
+			for _, t := range strings.Fields(c.Attr.Get("memberTypes")) {
+				kinds = append(kinds, r.baseKind(t))
+			}
+			for f := c.Child; f != nil; f = f.Next {
+				if f.GetName() == "simpleType" {
+					k, _ := r.simpleKind(f)
+					kinds = append(kinds, k)
+				}
+			}
+			return widenKinds(kinds), nil
+		}
+	}
+	return reflect.String, nil
+}
+
+// baseKind returns the kind of the simple type named qname.
+func (r *reader) baseKind(qname string) reflect.Kind {
+	name := local(qname)
+	if n, ok := r.simpleTypes[name]; ok {
+		k, _ := r.simpleKind(n)
+		return k
+	}
+	if k, ok := builtinKinds[name]; ok && k != reflect.Invalid {
+		return k
+	}
+	return reflect.String
+}
+
+// widenKinds returns the kind holding the values of every kind of kinds,
+// the empty strings aside.
+func widenKinds(kinds []reflect.Kind) reflect.Kind {
+	widest := reflect.Invalid
+	for _, k := range kinds {
+		switch {
+		case k == reflect.Invalid || k == widest:
+		case widest == reflect.Invalid:
+			widest = k
+		case (k == reflect.Int64 || k == reflect.Float64) && (widest == reflect.Int64 || widest == reflect.Float64):
+			widest = reflect.Float64
+		default:
+			return reflect.String
+		}
+	}
+	return widest
+}
+
+// content returns the element particles and the attribute declarations of
+// the complex type n, the ones of its base type first.
+func (r *reader) content(n *xml.Element) ([]particle, []*xml.Element, error) {
+	var (
+		ps    []particle
+		attrs []*xml.Element
+		err   error
+	)
+	for c := n.Child; c != nil; c = c.Next {
+		switch c.GetName() {
+		case "complexContent":
+			for d := c.Child; d != nil; d = d.Next {
+				if d.GetName() != "extension" && d.GetName() != "restriction" {
+					continue
+				}
+				if base, ok := r.complexTypes[local(d.Attr.Get("base"))]; ok && d.GetName() == "extension" {
+					bps, battrs, err := r.content(base)
+					if err != nil {
+						return nil, nil, err
+					}
+					ps, attrs = append(ps, bps...), append(attrs, battrs...)
+				}
+				dps, dattrs, err := r.content(d)
+				if err != nil {
+					return nil, nil, err
+				}
+				ps, attrs = append(ps, dps...), append(attrs, dattrs...)
+			}
+		case "sequence", "all", "choice", "group":
+			if ps, err = r.collect(c, ps, false, 1); err != nil {
+				return nil, nil, err
+			}
+		case "attribute":
+			attrs = append(attrs, c)
+		case "attributeGroup":
+			g, ok := r.attributeGroups[local(c.Attr.Get("ref"))]
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: attribute group %s", ErrUnknownRef, c.Attr.Get("ref"))
+			}
+			_, gattrs, err := r.content(g)
+			if err != nil {
+				return nil, nil, err
+			}
+			attrs = append(attrs, gattrs...)
+		}
+	}
+	return ps, attrs, nil
+}
+
+// collect appends to ps the element particles of the model group n, which
+// is optional or may occur up to maxOccurs times within its parents.
+func (r *reader) collect(n *xml.Element, ps []particle, optional bool, maxOccurs int) ([]particle, error) {
+	minN, maxN := occurs(n)
+	optional = optional || minN == 0
+	maxOccurs = multiplyOccurs(maxOccurs, maxN)
+	switch n.GetName() {
+	case "element":
+		name := n.Attr.Get("name")
+		if name == "" {
+			name = local(n.Attr.Get("ref"))
+		}
+		return append(ps, particle{decl: n, name: name, optional: optional, maxOccurs: maxOccurs}), nil
+	case "group":
+		ref := n.Attr.Get("ref")
+		if ref == "" {
+			break
+		}
+		g, ok := r.groups[local(ref)]
+		if !ok {
+			return nil, fmt.Errorf("%w: group %s", ErrUnknownRef, ref)
+		}
+		n = g
+	case "choice":
+		// Only one of the alternatives is present
+		alternatives := 0
+		for c := n.Child; c != nil; c = c.Next {
+			switch c.GetName() {
+			case "element", "sequence", "choice", "group":
+				alternatives++
+			}
+		}
+		optional = optional || alternatives > 1
+	}
+	var err error
+	for c := n.Child; c != nil; c = c.Next {
+		switch c.GetName() {
+		case "element", "sequence", "all", "choice", "group":
+			if ps, err = r.collect(c, ps, optional, maxOccurs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ps, nil
+}
+
+// complexType returns the type of the complex type n, for the element at
+// path: a list, a map, an embedded type, an empty type or a structure.
+func (r *reader) complexType(n *xml.Element, path []string) (any, error) {
+	if s, ok := r.expanding[n]; ok {
+		return s, nil
+	}
+	if n.Attr.Get("mixed") == "true" {
+		return r.mixedType(n, path)
+	}
+	for c := n.Child; c != nil; c = c.Next {
+		if c.GetName() != "simpleContent" {
+			continue
+		}
+		kind := reflect.String
+		for d := c.Child; d != nil; d = d.Next {
+			if base := d.Attr.Get("base"); base != "" {
+				if kind = r.baseKind(base); kind == reflect.Invalid {
+					kind = reflect.String
+				}
+			}
+		}
+		return &generator.CustomType{
+			Name:       "Type",
+			Pkg:        "*embedded",
+			Type1:      kind,
+			ImportPath: paths.EmbeddedTypePath,
+		}, nil
+	}
+	ps, attrs, err := r.content(n)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(ps) == 0:
+		return &generator.CustomType{
+			Name:       "Empty",
+			Pkg:        "*primary",
+			ImportPath: paths.PrimaryTypesPath,
+		}, nil
+	case r.isList(ps):
+		return r.listType(ps, path)
+	case r.isMap(ps):
+		return r.mapType(ps, path)
+	}
+	return r.structure(n, path, ps, attrs)
+}
+
+// listType returns the type of the list whose items are ps, for the element
+// at path.
+func (r *reader) listType(ps []particle, path []string) (any, error) {
+	// The items are named after the list, like the generator does
+	t, err := r.elementType(ps[0].decl, path)
+	if err != nil {
+		return nil, err
+	}
+	var tag string
+	if !r.dialect.IsListTag(ps[0].name) {
+		tag = ps[0].name
+	}
+	if ps[0].maxOccurs > 1 {
+		return &generator.FixedArray{Size: ps[0].maxOccurs, PrimaryType: t, ItemTag: tag}, nil
+	}
+	return &generator.CustomType{
+		Name:       "Slice",
+		Pkg:        "*types",
+		Type1:      t,
+		ImportPath: paths.CustomTypesPath,
+		ItemTag:    tag,
+	}, nil
+}
+
+// isMap reports whether ps are the keys and the values of a map.
+func (r *reader) isMap(ps []particle) bool {
+	return len(ps) == 2 && ps[0].name == r.dialect.MapKeys && ps[1].name == r.dialect.MapValues
+}
+
+// mapType returns the type of the map whose keys and values are ps, for the
+// element at path.
+func (r *reader) mapType(ps []particle, path []string) (any, error) {
+	keys, err := r.elementType(ps[0].decl, append(path[:len(path):len(path)], ps[0].name))
+	if err != nil {
+		return nil, err
+	}
+	values, err := r.elementType(ps[1].decl, append(path[:len(path):len(path)], ps[1].name))
+	if err != nil {
+		return nil, err
+	}
+	return &generator.CustomType{
+		Name:       "Map",
+		Pkg:        "types",
+		Type1:      itemType(keys),
+		Type2:      itemType(values),
+		ImportPath: paths.CustomTypesPath,
+	}, nil
+}
+
+// mixedType returns the type of the mixed complex type n, for the element
+// at path. The unions of the exported schemas are mixed types whose children
+// are the ones of one of their variants: with WithUnions, they're read as a
+// Union of a text and of these variants. The other mixed types are
+// *xml.Element.
+func (r *reader) mixedType(n *xml.Element, path []string) (any, error) {
+	if !r.unions {
+		return &xml.Element{}, nil
+	}
+	var content []*xml.Element
+	for c := n.Child; c != nil; c = c.Next {
+		switch c.GetName() {
+		case "element", "sequence", "all", "choice", "group", "complexContent", "simpleContent":
+			content = append(content, c)
+		}
+	}
+	if len(content) != 1 {
+		return &xml.Element{}, nil
+	}
+	// The variants are the alternatives of an optional choice, or its only
+	// alternative
+	alternatives := content
+	if _, maxOccurs := occurs(content[0]); content[0].GetName() == "choice" && maxOccurs == 1 {
+		alternatives = nil
+		for c := content[0].Child; c != nil; c = c.Next {
+			switch c.GetName() {
+			case "element", "sequence", "all", "choice", "group":
+				alternatives = append(alternatives, c)
+			}
+		}
+	}
+	u := &generator.Union{Variants: []*generator.Variant{{Shape: generator.ShapeText, T: reflect.String}}}
+	for _, a := range alternatives {
+		v, err := r.variant(a, path)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return &xml.Element{}, nil
+		}
+		u.Variants = append(u.Variants, v)
+	}
+	sort.SliceStable(u.Variants, func(i, j int) bool {
+		return u.Variants[i].Shape < u.Variants[j].Shape
+	})
+	for i := 1; i < len(u.Variants); i++ {
+		if u.Variants[i].Shape == u.Variants[i-1].Shape {
+			return &xml.Element{}, nil
+		}
+	}
+	return u, nil
+}
+
+// variant returns the variant of a union whose children are declared by the
+// alternative n, for the element at path, nil if it's not one of the
+// exported variants. The members of a structure are an unbounded choice
+// between their elements, so they're in any order.
+func (r *reader) variant(n *xml.Element, path []string) (*generator.Variant, error) {
+	ps, err := r.collect(n, nil, false, 1)
+	if err != nil || len(ps) == 0 {
+		return nil, err
+	}
+	switch {
+	case r.isMembers(n, ps):
+		for i := range ps {
+			ps[i].optional, ps[i].maxOccurs = true, 1
+		}
+		s, err := r.structure(n, path, ps, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &generator.Variant{Shape: generator.ShapeStructure, T: s}, nil
+	case r.isList(ps):
+		t, err := r.listType(ps, path)
+		if err != nil {
+			return nil, err
+		}
+		return &generator.Variant{Shape: generator.ShapeList, T: t}, nil
+	case r.isMap(ps):
+		t, err := r.mapType(ps, path)
+		if err != nil {
+			return nil, err
+		}
+		return &generator.Variant{Shape: generator.ShapeMap, T: t}, nil
+	}
+	return nil, nil
+}
+
+// isMembers reports whether the alternative n, whose particles are ps, is
+// an unbounded choice between distinct elements which aren't list items:
+// the members of a structure.
+func (r *reader) isMembers(n *xml.Element, ps []particle) bool {
+	if _, maxOccurs := occurs(n); n.GetName() != "choice" || maxOccurs != -1 {
+		return false
+	}
+	names := make(map[string]bool, len(ps))
+	for _, p := range ps {
+		if names[p.name] || r.dialect.IsListTag(p.name) {
+			return false
+		}
+		names[p.name] = true
+	}
+	return true
+}
+
+// isList reports whether ps are the items of a list: list items, or a
+// single element which may be repeated.
+func (r *reader) isList(ps []particle) bool {
+	if len(ps) == 1 && ps[0].maxOccurs != 1 {
+		return true
+	}
+	for _, p := range ps {
+		if !r.dialect.IsListTag(p.name) {
+			return false
+		}
+	}
+	return true
+}
+
+// itemType returns the type of the items of the list t, t if it's not a
+// list.
+func itemType(t any) any {
+	switch va := t.(type) {
+	case *generator.FixedArray:
+		return va.PrimaryType
+	case *generator.CustomType:
+		if generator.IsSliceType(va) {
+			return va.Type1
+		}
+	}
+	return t
+}
+
+// structure returns the structure of the complex type n, for the element at
+// path, whose particles are ps and attribute declarations attrs.
+func (r *reader) structure(n *xml.Element, path []string, ps []particle, attrs []*xml.Element) (*generator.StructInfo, error) {
+	name := r.structName(path)
+	key := structKey{name: name, t: n}
+	if s, ok := r.structs[key]; ok {
+		return s, nil
+	}
+	s := &generator.StructInfo{
+		Name:      name,
+		Members:   make(map[string]*generator.Member),
+		Instances: 1,
+	}
+	r.structs[key] = s
+	r.registered[name] = append(r.registered[name], s)
+	r.expanding[n] = s
+	defer delete(r.expanding, n)
+	for _, p := range ps {
+		if _, ok := s.Members[p.name]; ok {
+			continue
+		}
+		t, err := r.elementType(p.decl, append(path[:len(path):len(path)], p.name))
+		if err != nil {
+			return nil, err
+		}
+		// The generator types the repeated leaves as slices
+		if k, ok := t.(reflect.Kind); ok && p.maxOccurs != 1 {
+			t = &generator.CustomType{
+				Name:       "Slice",
+				Pkg:        "*types",
+				Type1:      k,
+				ImportPath: paths.CustomTypesPath,
+			}
+		}
+		addMember(s, p.name, t, p.optional)
+	}
+	if r.attributes {
+		s.Attributes = r.attributesOf(attrs)
+	}
+	return s, nil
+}
+
+// attributesOf returns the typed attributes declared by attrs, sorted by
+// name.
+func (r *reader) attributesOf(attrs []*xml.Element) []*generator.Attribute {
+	var typed []*generator.Attribute
+	seen := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		name := a.Attr.Get("name")
+		if name == "" {
+			name = local(a.Attr.Get("ref"))
+		}
+		use := a.Attr.Get("use")
+		if seen[name] || use == "prohibited" {
+			continue
+		}
+		seen[name] = true
+		kind := reflect.String
+		if t := a.Attr.Get("type"); t != "" {
+			kind = r.baseKind(t)
+		}
+		for c := a.Child; c != nil; c = c.Next {
+			if c.GetName() == "simpleType" {
+				kind, _ = r.simpleKind(c)
+			}
+		}
+		switch kind {
+		case reflect.Bool, reflect.Int64, reflect.Float64:
+		default:
+			kind = reflect.String
+		}
+		attr := &generator.Attribute{Name: name, T: kind}
+		if use == "required" {
+			attr.Occurrences = 1
+		}
+		typed = append(typed, attr)
+	}
+	sort.Slice(typed, func(i, j int) bool {
+		return typed[i].Name < typed[j].Name
+	})
+	return typed
+}
+
+// nameEnums suffixes the names of the enumerations taken by a structure or
+// another enumeration.
+func (r *reader) nameEnums() {
+	taken := make(map[string]bool, len(r.registered)+len(r.enumList))
+	for name := range r.registered {
+		taken[name] = true
+	}
+	for _, e := range r.enumList {
+		name := e.Name
+		for i := 0; taken[name]; i++ {
+			name = e.Name + strconv.Itoa(i)
+		}
+		e.Name = name
+		taken[name] = true
+	}
+}
@@ -0,0 +1,232 @@
+package xsd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/xml"
+)
+
+func TestRead(t *testing.T) {
+	const schema = `<?xml version="1.0" encoding="UTF-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:element name="savegame">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="game" type="Game"/>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+	<xs:complexType name="Game">
+		<xs:sequence>
+			<xs:element name="tickCount" type="xs:int"/>
+			<xs:element name="paused" type="boolean" minOccurs="0"/>
+			<xs:element name="gender" type="Gender"/>
+			<xs:element name="label">
+				<xs:complexType>
+					<xs:simpleContent>
+						<xs:extension base="xs:string">
+							<xs:attribute name="lang" type="xs:string"/>
+						</xs:extension>
+					</xs:simpleContent>
+				</xs:complexType>
+			</xs:element>
+			<xs:element name="empty">
+				<xs:complexType/>
+			</xs:element>
+			<xs:element name="weights">
+				<xs:complexType>
+					<xs:sequence>
+						<xs:element name="keys" type="Strings"/>
+						<xs:element name="values" type="Longs"/>
+					</xs:sequence>
+				</xs:complexType>
+			</xs:element>
+			<xs:element name="pawns">
+				<xs:complexType>
+					<xs:sequence>
+						<xs:element name="li" minOccurs="0" maxOccurs="unbounded">
+							<xs:complexType>
+								<xs:sequence>
+									<xs:element name="name" type="xs:string"/>
+									<xs:element name="age" type="xs:long" minOccurs="0"/>
+								</xs:sequence>
+								<xs:attribute name="id" type="xs:long" use="required"/>
+							</xs:complexType>
+						</xs:element>
+					</xs:sequence>
+				</xs:complexType>
+			</xs:element>
+			<xs:element name="game">
+				<xs:complexType>
+					<xs:sequence>
+						<xs:element name="seed" type="xs:long"/>
+					</xs:sequence>
+				</xs:complexType>
+			</xs:element>
+		</xs:sequence>
+	</xs:complexType>
+	<xs:complexType name="Strings">
+		<xs:sequence>
+			<xs:element name="li" type="xs:string" maxOccurs="unbounded"/>
+		</xs:sequence>
+	</xs:complexType>
+	<xs:complexType name="Longs">
+		<xs:sequence>
+			<xs:element name="li" type="xs:long" maxOccurs="unbounded"/>
+		</xs:sequence>
+	</xs:complexType>
+	<xs:simpleType name="boolean">
+		<xs:restriction base="xs:string">
+			<xs:enumeration value="True"/>
+			<xs:enumeration value="False"/>
+		</xs:restriction>
+	</xs:simpleType>
+	<xs:simpleType name="Gender">
+		<xs:restriction base="xs:string">
+			<xs:enumeration value="Male"/>
+			<xs:enumeration value="Female"/>
+		</xs:restriction>
+	</xs:simpleType>
+</xs:schema>`
+
+	root, registered, err := Read(schema, WithAttributes(true))
+	require.NoError(t, err)
+	assert.Equal(t, []*generator.StructInfo{root}, registered[root.Name])
+	savegame := root.Members["savegame"].T.(*generator.StructInfo)
+	game := savegame.Members["game"].T.(*generator.StructInfo)
+	assert.Equal(t, "game", game.Name)
+
+	assert.Equal(t, reflect.Int64, game.Members["tickCount"].T)
+	assert.Equal(t, reflect.Bool, game.Members["paused"].T)
+	assert.Equal(t, 0, game.Members["paused"].Occurrences)
+	assert.Equal(t, &generator.Enum{Name: "Gender", Values: []string{"Female", "Male"}}, game.Members["gender"].T)
+	assert.Equal(t, "Type", game.Members["label"].T.(*generator.CustomType).Name)
+	assert.Equal(t, "Empty", game.Members["empty"].T.(*generator.CustomType).Name)
+
+	weights := game.Members["weights"].T.(*generator.CustomType)
+	assert.Equal(t, "Map", weights.Name)
+	assert.Equal(t, reflect.String, weights.Type1)
+	assert.Equal(t, reflect.Int64, weights.Type2)
+
+	pawns := game.Members["pawns"].T.(*generator.CustomType)
+	require.True(t, generator.IsSliceType(pawns))
+	pawn := pawns.Type1.(*generator.StructInfo)
+	assert.Equal(t, "pawns", pawn.Name)
+	assert.Equal(t, 0, pawn.Members["age"].Occurrences)
+	assert.Equal(t, []*generator.Attribute{{Name: "id", T: reflect.Int64, Occurrences: 1}}, pawn.Attributes)
+
+	// Named like the generator names a child named after its parent
+	inner := game.Members["game"].T.(*generator.StructInfo)
+	assert.Equal(t, "savegame_game_game_game_savegame_Inner", inner.Name)
+	assert.Contains(t, registered, inner.Name)
+
+	t.Run("round trip", func(t *testing.T) {
+		document := `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<grid><li></li><li>1</li><li></li></grid>
+	<factions>
+		<values><li><name>A</name></li></values>
+	</factions>
+	<cells><cell>1</cell><cell>2</cell></cells>
+</savegame>`
+		root, registered, err := Read(export(t, []string{document}, nil))
+		require.NoError(t, err)
+		savegame := root.Members["savegame"].T.(*generator.StructInfo)
+		assert.Equal(t, &generator.FixedArray{Size: 3, PrimaryType: reflect.Int64}, savegame.Members["grid"].T)
+		factions := savegame.Members["factions"].T.(*generator.StructInfo)
+		values := factions.Members["values"].T.(*generator.CustomType)
+		assert.Equal(t, "savegame_factions_values", values.Type1.(*generator.StructInfo).Name)
+		assert.Contains(t, registered, "savegame_factions_values")
+		cells := savegame.Members["cells"].T.(*generator.CustomType)
+		require.True(t, generator.IsSliceType(cells))
+		assert.Equal(t, reflect.Int64, cells.Type1)
+		assert.Equal(t, "cell", cells.ItemTag)
+	})
+
+	t.Run("polymorphic round trip", func(t *testing.T) {
+		documents := []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawns>
+		<li Class="Human"><name>Tynan</name><age>3</age></li>
+		<li Class="Animal"><name>Rex</name><species>dog</species></li>
+	</pawns>
+	<things>
+		<li Class="Hat"><def>Cap</def></li>
+		<li Class="Hat"><def>Hood</def></li>
+	</things>
+</savegame>`}
+		opts := []generator.Option{generator.WithClassPolymorphism(true)}
+		root, registered, err := Read(export(t, documents, opts))
+		require.NoError(t, err)
+		// The members of the classes are optional members, not lists
+		savegame := root.Members["savegame"].T.(*generator.StructInfo)
+		pawns := savegame.Members["pawns"].T.(*generator.CustomType)
+		require.True(t, generator.IsSliceType(pawns))
+		pawn := pawns.Type1.(*generator.StructInfo)
+		for name, kind := range map[string]reflect.Kind{"name": reflect.String, "age": reflect.Int64, "species": reflect.String} {
+			assert.Equal(t, kind, pawn.Members[name].T, name)
+			assert.Equal(t, 0, pawn.Members[name].Occurrences, name)
+		}
+		things := savegame.Members["things"].T.(*generator.CustomType)
+		require.True(t, generator.IsSliceType(things))
+		assert.Equal(t, reflect.String, things.Type1.(*generator.StructInfo).Members["def"].T)
+
+		// The schema seeds the generation refined by the samples
+		for _, opts := range [][]generator.Option{nil, opts} {
+			g := generator.New(append(opts, generator.WithSeed(root, registered))...)
+			_, err = g.GenerateGoFilesFromCorpus(readRoots(t, documents))
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("union round trip", func(t *testing.T) {
+		documents := []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawns>
+		<li><name>A</name><value>12</value></li>
+		<li><name>B</name><value><amount>3</amount><unit>kg</unit></value></li>
+		<li><name>C</name><value><li>1</li><li>2</li></value></li>
+	</pawns>
+</savegame>`}
+		opts := []generator.Option{generator.WithUnions(true)}
+		schema := export(t, documents, opts)
+		root, _, err := Read(schema)
+		require.NoError(t, err)
+		pawn := root.Members["savegame"].T.(*generator.StructInfo).Members["pawns"].T.(*generator.CustomType).Type1.(*generator.StructInfo)
+		assert.Equal(t, &xml.Element{}, pawn.Members["value"].T)
+
+		root, registered, err := Read(schema, WithUnions(true))
+		require.NoError(t, err)
+		pawn = root.Members["savegame"].T.(*generator.StructInfo).Members["pawns"].T.(*generator.CustomType).Type1.(*generator.StructInfo)
+		u, ok := pawn.Members["value"].T.(*generator.Union)
+		require.True(t, ok)
+		shapes := make([]generator.Shape, 0, len(u.Variants))
+		for _, v := range u.Variants {
+			shapes = append(shapes, v.Shape)
+		}
+		assert.Equal(t, []generator.Shape{generator.ShapeText, generator.ShapeList, generator.ShapeStructure}, shapes)
+		value := u.Variants[2].T.(*generator.StructInfo)
+		assert.Equal(t, reflect.Int64, value.Members["amount"].T)
+		assert.Equal(t, reflect.String, value.Members["unit"].T)
+
+		g := generator.New(append(opts, generator.WithSeed(root, registered))...)
+		s, err := g.GenerateGoFilesFromCorpus(readRoots(t, documents))
+		require.NoError(t, err)
+		pawn = s.Members["savegame"].T.(*generator.StructInfo).Members["pawns"].T.(*generator.CustomType).Type1.(*generator.StructInfo)
+		assert.IsType(t, &generator.Union{}, pawn.Members["value"].T)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := Read(`<?xml version="1.0"?><savegame/>`)
+		assert.ErrorIs(t, err, ErrNotSchema)
+		_, _, err = Read(`<?xml version="1.0"?><xs:schema xmlns:xs="` + Namespace + `"><xs:include schemaLocation="a.xsd"/><xs:element name="a"/></xs:schema>`)
+		assert.ErrorIs(t, err, ErrUnsupported)
+		_, _, err = Read(`<?xml version="1.0"?><xs:schema xmlns:xs="` + Namespace + `"><xs:complexType name="A"/></xs:schema>`)
+		assert.ErrorIs(t, err, ErrNoElement)
+	})
+}
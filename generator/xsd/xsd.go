@@ -0,0 +1,482 @@
+// Package xsd exports the structures inferred by the generator as an XML
+// Schema, for the tools validating the documents with XSD, and reads the
+// structures described by a schema.
+package xsd
+
+import (
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/xml"
+	"github.com/cruffinoni/rimworld-editor/xml/dialect"
+)
+
+// Namespace is the namespace of the XML Schema definitions.
+const Namespace = "http://www.w3.org/2001/XMLSchema"
+
+const (
+	header  = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+	anyType = "xs:anyType"
+	// boolType is the type of the booleans, which are written "True" and
+	// "False" while xs:boolean only accepts lowercase values. The generated
+	// type names are camel-cased, so it can't collide with them.
+	boolType = "boolean"
+	// emptyType is the type of the empty items of the fixed arrays.
+	emptyType = "empty"
+	unbounded = "unbounded"
+)
+
+type options struct {
+	dialect    *dialect.Dialect
+	attributes bool
+	unions     bool
+}
+
+// Option configures the export or the reading of a schema.
+type Option func(o *options)
+
+// WithDialect sets the dialect of the documents, which gives the tags of the
+// list items and of the keys and values of the maps. It's dialect.Default
+// by default.
+func WithDialect(d *dialect.Dialect) Option {
+	return func(o *options) {
+		o.dialect = dialect.Or(d)
+	}
+}
+
+// WithAttributes types the attributes declared by the schema read as fields
+// of the structures, see generator.WithAttributes. It's disabled by default
+// and doesn't change the export, which writes the typed attributes found.
+func WithAttributes(enabled bool) Option {
+	return func(o *options) {
+		o.attributes = enabled
+	}
+}
+
+// WithUnions reads the unions of the exported schemas as a Union instead of
+// an *xml.Element, see generator.WithUnions which the generation they seed
+// needs. It's disabled by default and doesn't change the export.
+func WithUnions(enabled bool) Option {
+	return func(o *options) {
+		o.unions = enabled
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{dialect: dialect.Default}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+type exporter struct {
+	options
+	registeredMember generator.MemberVersioning
+	// defined are the names of the global types already defined.
+	defined map[string]bool
+	// simple are the names of the simple types.
+	simple    map[string]bool
+	types     []*node
+	usesBool  bool
+	usesEmpty bool
+}
+
+// Write writes to w the XML Schema of the documents whose structures are
+// inferred as root, the structure returned by the generator. Its members
+// are the root elements of the documents. registeredMember are the
+// structures registered by the generator, the first version of each one is
+// exported like the Go code.
+//
+// Every structure, enumeration, union and polymorphic type is a global type
+// named like its Go type. The attributes which aren't typed are allowed by
+// an xs:anyAttribute, like they're kept in the Attr map of the structures.
+func Write(w io.Writer, root *generator.StructInfo, registeredMember generator.MemberVersioning, opts ...Option) error {
+	e := &exporter{
+		options:          newOptions(opts),
+		registeredMember: registeredMember,
+		defined:          make(map[string]bool),
+		simple: map[string]bool{
+			"xs:string": true,
+			"xs:long":   true,
+			"xs:double": true,
+			boolType:    true,
+		},
+	}
+	schema := xs("schema").set("xmlns:xs", Namespace)
+	for _, m := range root.ElementMembers() {
+		schema.add(e.element(m.Element, m.T))
+	}
+	if e.usesBool {
+		schema.add(xs("simpleType", "name", boolType).add(
+			xs("restriction", "base", "xs:string").add(
+				xs("enumeration", "value", "True"),
+				xs("enumeration", "value", "False"),
+				xs("enumeration", "value", "true"),
+				xs("enumeration", "value", "false"),
+			),
+		))
+	}
+	if e.usesEmpty {
+		schema.add(xs("simpleType", "name", emptyType).add(
+			xs("restriction", "base", "xs:string").add(xs("length", "value", "0")),
+		))
+	}
+	schema.add(e.types...)
+	var sb strings.Builder
+	sb.WriteString(header)
+	schema.write(&sb, 0)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// element returns the declaration of the element name of type t.
+func (e *exporter) element(name string, t any) *node {
+	n := xs("element", "name", name)
+	ref, anonymous := e.typeOf(t)
+	if anonymous != nil {
+		return n.add(anonymous)
+	}
+	return n.set("type", ref)
+}
+
+// typeOf returns the name of the type t, defining it if needed, or the
+// anonymous type of t if it has no name.
+func (e *exporter) typeOf(t any) (string, *node) {
+	switch va := t.(type) {
+	case reflect.Kind:
+		return e.simpleType(va), nil
+	case *generator.StructInfo:
+		return e.structType(e.registeredMember.Best(va)), nil
+	case *generator.Enum:
+		return e.enumType(va), nil
+	case *generator.Union:
+		return e.unionType(va), nil
+	case *generator.Polymorphic:
+		return e.polymorphicType(va), nil
+	case *generator.FixedArray:
+		return "", xs("complexType").add(xs("sequence").add(e.arrayItems(va)), anyAttribute())
+	case *generator.CustomType:
+		switch {
+		case va.External || generator.IsMultipleType(va):
+			return anyType, nil
+		case generator.IsEmptyType(va):
+			return "", xs("complexType").add(anyAttribute())
+		case generator.IsEmbeddedType(va):
+			base, _ := e.typeOf(va.Type1)
+			return "", xs("complexType").add(
+				xs("simpleContent").add(
+					xs("extension", "base", base).add(anyAttribute()),
+				),
+			)
+		case va.Name == "Map":
+			return "", xs("complexType").add(e.mapContent(va), anyAttribute())
+		case va.Name == "Slice":
+			return "", e.listType(va.Type1, va.ItemTag)
+		}
+	case *xml.Element:
+		return anyType, nil
+	}
+	return anyType, nil
+}
+
+func (e *exporter) simpleType(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return "xs:string"
+	case reflect.Int64:
+		return "xs:long"
+	case reflect.Float64:
+		return "xs:double"
+	case reflect.Bool:
+		e.usesBool = true
+		return boolType
+	}
+	return anyType
+}
+
+// define reserves the global type name. It returns false if the type is
+// already defined, which also stops the recursive structures.
+func (e *exporter) define(name string) bool {
+	if e.defined[name] {
+		return false
+	}
+	e.defined[name] = true
+	return true
+}
+
+func anyAttribute() *node {
+	return xs("anyAttribute", "processContents", "lax")
+}
+
+// structType defines the complex type of s and returns its name.
+func (e *exporter) structType(s *generator.StructInfo) string {
+	name := strcase.ToCamel(s.Name)
+	if !e.define(name) {
+		return name
+	}
+	t := xs("complexType", "name", name)
+	e.types = append(e.types, t)
+	t.add(e.structContent(s))
+	for _, a := range s.Attributes {
+		use := "required"
+		if s.IsOptionalAttribute(a) {
+			use = "optional"
+		}
+		t.add(xs("attribute", "name", a.Name, "type", e.simpleType(a.T), "use", use))
+	}
+	t.add(anyAttribute())
+	return name
+}
+
+// memberElements returns the declarations of the members of s.
+func (e *exporter) memberElements(s *generator.StructInfo) []*node {
+	ms := s.ElementMembers()
+	els := make([]*node, 0, len(ms))
+	for _, m := range ms {
+		el := e.element(m.Element, m.T)
+		if s.IsOptional(m.Member) {
+			el.set("minOccurs", "0")
+		}
+		els = append(els, el)
+	}
+	return els
+}
+
+// structContent returns the members of s in any order, the documents
+// inferred together may write them in different orders. It's nil if s has
+// no members.
+func (e *exporter) structContent(s *generator.StructInfo) *node {
+	els := e.memberElements(s)
+	if len(els) == 0 {
+		return nil
+	}
+	return xs("all").add(els...)
+}
+
+// items returns the particle of at most maxOccurs list items, declared by
+// item for each list tag, or for tag if the items are written with it.
+func (e *exporter) items(maxOccurs, tag string, item func(tag string) *node) *node {
+	tags := e.dialect.ListTags
+	if tag != "" {
+		tags = []string{tag}
+	}
+	if len(tags) == 1 {
+		return item(tags[0]).set("minOccurs", "0").set("maxOccurs", maxOccurs)
+	}
+	// Every list tag is accepted, the items are written with the first one
+	choice := xs("choice", "minOccurs", "0", "maxOccurs", maxOccurs)
+	for _, tag := range tags {
+		choice.add(item(tag))
+	}
+	return choice
+}
+
+// listItems returns the particle of the items of a list of type t, see
+// items for tag.
+func (e *exporter) listItems(t any, tag string) *node {
+	return e.items(unbounded, tag, func(tag string) *node {
+		return e.element(tag, t)
+	})
+}
+
+// listType returns the anonymous type of a list of type t.
+func (e *exporter) listType(t any, tag string) *node {
+	return xs("complexType").add(xs("sequence").add(e.listItems(t, tag)), anyAttribute())
+}
+
+// arrayItems returns the particle of the items of a. The items are
+// positional and the missing ones are written empty, so an item of a simple
+// type may also be empty.
+func (e *exporter) arrayItems(a *generator.FixedArray) *node {
+	return e.items(strconv.Itoa(a.Size), a.ItemTag, func(tag string) *node {
+		el := e.element(tag, a.PrimaryType)
+		ref := typeAttr(el)
+		if !e.simple[ref] || ref == "xs:string" {
+			return el
+		}
+		e.usesEmpty = true
+		return xs("element", "name", tag).add(
+			xs("simpleType").add(xs("union", "memberTypes", ref+" "+emptyType)),
+		)
+	})
+}
+
+// mapContent returns the sequence of the keys and values of the map c.
+func (e *exporter) mapContent(c *generator.CustomType) *node {
+	return xs("sequence").add(
+		xs("element", "name", e.dialect.MapKeys).add(e.listType(c.Type1, "")),
+		xs("element", "name", e.dialect.MapValues).add(e.listType(c.Type2, "")),
+	)
+}
+
+// enumType defines the simple type of en and returns its name.
+func (e *exporter) enumType(en *generator.Enum) string {
+	name := strcase.ToCamel(en.Name)
+	if !e.define(name) {
+		return name
+	}
+	restriction := xs("restriction", "base", "xs:string")
+	for _, v := range en.Values {
+		restriction.add(xs("enumeration", "value", v))
+	}
+	e.simple[name] = true
+	e.types = append(e.types, xs("simpleType", "name", name).add(restriction))
+	return name
+}
+
+// unionType defines the type of u and returns its name. A union of text
+// variants is a union of simple types. Otherwise, it's a mixed type whose
+// children are the ones of one of its variants, its text isn't typed.
+func (e *exporter) unionType(u *generator.Union) string {
+	name := strcase.ToCamel(u.Name)
+	if !e.define(name) {
+		return name
+	}
+	simple := true
+	for _, v := range u.Variants {
+		switch v.T.(type) {
+		case reflect.Kind, *generator.Enum:
+		default:
+			simple = false
+		}
+	}
+	if simple {
+		memberTypes := make([]string, 0, len(u.Variants))
+		for _, v := range u.Variants {
+			ref, _ := e.typeOf(v.T)
+			memberTypes = append(memberTypes, ref)
+		}
+		e.simple[name] = true
+		e.types = append(e.types, xs("simpleType", "name", name).add(
+			xs("union", "memberTypes", strings.Join(memberTypes, " ")),
+		))
+		return name
+	}
+	t := xs("complexType", "name", name, "mixed", "true")
+	e.types = append(e.types, t)
+	choice := xs("choice", "minOccurs", "0")
+	for _, v := range u.Variants {
+		switch va := v.T.(type) {
+		case *generator.StructInfo:
+			// xs:all can't be an alternative
+			if els := e.memberElements(e.registeredMember.Best(va)); len(els) > 0 {
+				choice.add(xs("choice", "maxOccurs", unbounded).add(els...))
+			}
+		case *generator.FixedArray:
+			choice.add(e.arrayItems(va))
+		case *generator.CustomType:
+			switch va.Name {
+			case "Slice":
+				choice.add(e.listItems(va.Type1, va.ItemTag))
+			case "Map":
+				choice.add(e.mapContent(va))
+			}
+		}
+	}
+	switch len(choice.children) {
+	case 0:
+	case 1:
+		// The text variants have no children
+		t.add(choice.children[0].set("minOccurs", "0"))
+	default:
+		t.add(choice)
+	}
+	t.add(anyAttribute())
+	return name
+}
+
+// polymorphicType defines the type of p and returns its name. XSD 1.0 can't
+// pick the children after the Class attribute, so the type accepts the
+// children of every class in any order, each of them being optional. Its
+// Class attribute takes one of the classes of p.
+func (e *exporter) polymorphicType(p *generator.Polymorphic) string {
+	name := strcase.ToCamel(p.Name)
+	if !e.define(name) {
+		return name
+	}
+	t := xs("complexType", "name", name)
+	e.types = append(e.types, t)
+	variants := make([]*generator.StructInfo, 0, len(p.Variants)+1)
+	for _, class := range p.Classes() {
+		variants = append(variants, e.registeredMember.Best(p.Variants[class]))
+	}
+	if p.Default != nil {
+		variants = append(variants, e.registeredMember.Best(p.Default))
+	}
+	var (
+		children   []*node
+		childIndex = make(map[string]int)
+		types      []string
+		attributes = make(map[string]reflect.Kind)
+		attrNames  []string
+	)
+	for _, v := range variants {
+		for _, m := range v.ElementMembers() {
+			el := e.element(m.Element, m.T)
+			ref := typeAttr(el)
+			i, ok := childIndex[m.Element]
+			if !ok {
+				childIndex[m.Element] = len(children)
+				children = append(children, el)
+				types = append(types, ref)
+				continue
+			}
+			// An element has the same type in every class of the content
+			// model, it's left untyped when the classes disagree.
+			if ref == "" || ref != types[i] {
+				children[i] = xs("element", "name", m.Element, "type", anyType)
+				types[i] = anyType
+			}
+		}
+		for _, a := range v.Attributes {
+			if k, ok := attributes[a.Name]; !ok {
+				attributes[a.Name] = a.T
+				attrNames = append(attrNames, a.Name)
+			} else if k != a.T {
+				attributes[a.Name] = reflect.String
+			}
+		}
+	}
+	if len(children) > 0 {
+		for _, c := range children {
+			c.set("minOccurs", "0")
+		}
+		t.add(xs("all").add(children...))
+	}
+	class := xs("restriction", "base", "xs:string")
+	for _, c := range p.Classes() {
+		class.add(xs("enumeration", "value", c))
+	}
+	use := "required"
+	if p.Default != nil {
+		use = "optional"
+	}
+	t.add(xs("attribute", "name", generator.ClassAttribute, "use", use).add(xs("simpleType").add(class)))
+	sort.Strings(attrNames)
+	for _, a := range attrNames {
+		if a == generator.ClassAttribute {
+			continue
+		}
+		t.add(xs("attribute", "name", a, "type", e.simpleType(attributes[a]), "use", "optional"))
+	}
+	t.add(anyAttribute())
+	return name
+}
+
+// typeAttr returns the type attribute of the element declaration n, empty
+// if its type is anonymous.
+func typeAttr(n *node) string {
+	for _, a := range n.attr {
+		if a.name == "type" {
+			return a.value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package xsd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cruffinoni/rimworld-editor/file"
+	"github.com/cruffinoni/rimworld-editor/generator"
+	"github.com/cruffinoni/rimworld-editor/xml"
+	"github.com/cruffinoni/rimworld-editor/xml/dialect"
+)
+
+// readRoots returns the root elements of documents.
+func readRoots(t *testing.T, documents []string) []*xml.Element {
+	t.Helper()
+	roots := make([]*xml.Element, 0, len(documents))
+	for _, d := range documents {
+		o, err := file.ReadFromBuffer(d)
+		require.NoError(t, err)
+		roots = append(roots, o.XML.Root)
+	}
+	return roots
+}
+
+func export(t *testing.T, documents []string, opts []generator.Option, xsdOpts ...Option) string {
+	t.Helper()
+	g := generator.New(opts...)
+	s, err := g.GenerateGoFilesFromCorpus(readRoots(t, documents))
+	require.NoError(t, err)
+	var sb strings.Builder
+	require.NoError(t, Write(&sb, s, g.RegisteredMembers, xsdOpts...))
+	return sb.String()
+}
+
+func TestWrite(t *testing.T) {
+	got := export(t, []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<game>
+		<paused>True</paused>
+		<label lang="en">hello</label>
+		<empty />
+		<weights>
+			<keys><li>a</li></keys>
+			<values><li>1</li></values>
+		</weights>
+		<pawns>
+			<li id="1"><name>Tynan</name><age>30</age></li>
+			<li id="2"><name>Randy</name></li>
+		</pawns>
+	</game>
+</savegame>`}, []generator.Option{generator.WithAttributes(true)})
+
+	assert.True(t, strings.HasPrefix(got, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<xs:schema xmlns:xs=\""+Namespace+"\">\n"))
+	assert.Contains(t, got, "\t<xs:element name=\"savegame\" type=\"Savegame\"/>\n")
+	assert.Contains(t, got, "<xs:simpleType name=\"boolean\">")
+	assert.Contains(t, got, "<xs:element name=\"paused\" type=\"boolean\"/>")
+	assert.Contains(t, got, "<xs:extension base=\"xs:string\">")
+	assert.Regexp(t, `<xs:element name="keys">\s*<xs:complexType>\s*<xs:sequence>\s*<xs:choice minOccurs="0" maxOccurs="unbounded">\s*<xs:element name="li" type="xs:string"/>`, got)
+	assert.Regexp(t, `<xs:element name="values">\s*<xs:complexType>\s*<xs:sequence>\s*<xs:choice minOccurs="0" maxOccurs="unbounded">\s*<xs:element name="li" type="xs:long"/>`, got)
+	assert.Contains(t, got, "<xs:element name=\"li\" type=\"Pawns\"/>")
+	assert.Regexp(t, `<xs:complexType name="Pawns">\s*<xs:all>\s*<xs:element name="name" type="xs:string"/>\s*<xs:element name="age" type="xs:long" minOccurs="0"/>\s*</xs:all>\s*<xs:attribute name="id" type="xs:long" use="required"/>\s*<xs:anyAttribute processContents="lax"/>`, got)
+
+	t.Run("dialect", func(t *testing.T) {
+		d := *dialect.Default
+		d.ListTags = []string{"item"}
+		got := export(t, []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<grid><item></item><item>1</item><item></item></grid>
+</savegame>`}, []generator.Option{generator.WithDialect(&d)}, WithDialect(&d))
+		assert.Regexp(t, `<xs:sequence>\s*<xs:element name="item" minOccurs="0" maxOccurs="3">\s*<xs:simpleType>\s*<xs:union memberTypes="xs:long empty"/>`, got)
+		assert.Contains(t, got, "<xs:simpleType name=\"empty\">")
+	})
+
+	t.Run("polymorphic", func(t *testing.T) {
+		got := export(t, []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<things>
+		<li Class="Apparel"><def>Hat</def><hp>10</hp></li>
+		<li Class="Weapon"><def>Gun</def><range>20.5</range></li>
+	</things>
+</savegame>`}, []generator.Option{generator.WithClassPolymorphism(true)})
+		assert.Regexp(t, `<xs:all>\s*<xs:element name="def" type="xs:string" minOccurs="0"/>\s*<xs:element name="hp" type="xs:long" minOccurs="0"/>\s*<xs:element name="range" type="xs:double" minOccurs="0"/>\s*</xs:all>\s*<xs:attribute name="Class" use="required">`, got)
+		assert.Contains(t, got, "<xs:enumeration value=\"Weapon\"/>")
+	})
+}
+
+func TestWrite_validatesSamples(t *testing.T) {
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint isn't installed")
+	}
+	// The members are in different orders and the items aren't list items
+	documents := []string{`<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<meta><version>1.4</version></meta>
+	<pawn><name>Tynan</name></pawn>
+	<things>
+		<thing Class="Apparel"><def>Hat</def></thing>
+		<thing Class="Weapon"><def>Gun</def></thing>
+	</things>
+	<grid><cell>1</cell><cell>2</cell></grid>
+</savegame>`, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn><name>Randy</name></pawn>
+	<meta><version>1.5</version></meta>
+	<things>
+		<thing><def>Rock</def></thing>
+		<thing><def>Wood</def></thing>
+	</things>
+	<grid><cell>3</cell><cell>4</cell></grid>
+</savegame>`}
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "savegame.xsd")
+	require.NoError(t, os.WriteFile(schema, []byte(export(t, documents, nil)), 0644))
+	args := []string{"--noout", "--schema", schema}
+	for i, d := range documents {
+		name := filepath.Join(dir, "sample"+strconv.Itoa(i)+".xml")
+		require.NoError(t, os.WriteFile(name, []byte(d), 0644))
+		args = append(args, name)
+	}
+	out, err := exec.Command(xmllint, args...).CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
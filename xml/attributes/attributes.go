@@ -1,6 +1,7 @@
 package attributes
 
 import (
+	"reflect"
 	"strings"
 )
 
@@ -34,3 +35,13 @@ func (m *Attributes) Get(key string) string {
 	}
 	return ""
 }
+
+// TagName returns the name of the attribute held by a field tagged
+// `xml:"name,attr"`. ok is false if the field doesn't hold an attribute.
+func TagName(tag reflect.StructTag) (name string, ok bool) {
+	name, options, found := strings.Cut(tag.Get("xml"), ",")
+	if !found || options != "attr" {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,125 @@
+package dialect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Dialect describes how an XML source encodes its collections: which tags
+// are the items of a list, which tags hold the keys and the values of a map
+// and which tags are transversal, meaning they only wrap their parent's
+// content and don't name a structure by themselves.
+type Dialect struct {
+	// ListTags are the tags of the items of a list. The first one is used
+	// when a list is saved.
+	ListTags []string `json:"list_tags"`
+	// MapKeys is the tag of the container of the keys of a map.
+	MapKeys string `json:"map_keys"`
+	// MapValues is the tag of the container of the values of a map.
+	MapValues string `json:"map_values"`
+	// TransversalTags are the tags whose structure is named after their
+	// parents.
+	TransversalTags []string `json:"transversal_tags"`
+	// TransversalSubstrings work like TransversalTags but match any tag
+	// containing one of them, case-insensitively.
+	TransversalSubstrings []string `json:"transversal_substrings"`
+}
+
+// Default is the dialect of the RimWorld files: <li> items, <keys>/<values>
+// maps and <vals> transversal tags.
+var Default = &Dialect{
+	ListTags:              []string{"li", "list"},
+	MapKeys:               "keys",
+	MapValues:             "values",
+	TransversalTags:       []string{"vals", "values"},
+	TransversalSubstrings: []string{"inner"},
+}
+
+// Or returns d or Default if d is nil.
+func Or(d *Dialect) *Dialect {
+	if d == nil {
+		return Default
+	}
+	return d
+}
+
+// IsListTag reports whether tag is the tag of a list item.
+func (d *Dialect) IsListTag(tag string) bool {
+	for _, t := range d.ListTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ListTag returns the tag used to write the items of a list.
+func (d *Dialect) ListTag() string {
+	return d.ListTags[0]
+}
+
+// IsMapKeysTag reports whether tag is the container of the keys of a map.
+func (d *Dialect) IsMapKeysTag(tag string) bool {
+	return tag == d.MapKeys
+}
+
+// IsMapValuesTag reports whether tag is the container of the values of a map.
+func (d *Dialect) IsMapValuesTag(tag string) bool {
+	return tag == d.MapValues
+}
+
+// IsTransversalTag reports whether tag is a transversal tag.
+func (d *Dialect) IsTransversalTag(tag string) bool {
+	for _, t := range d.TransversalTags {
+		if t == tag {
+			return true
+		}
+	}
+	lower := strings.ToLower(tag)
+	for _, s := range d.TransversalSubstrings {
+		if strings.Contains(lower, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrNoListTag  = errors.New("dialect: at least one list tag is required")
+	ErrNoMapTags  = errors.New("dialect: the map keys and values tags are required")
+	ErrSameMapTag = errors.New("dialect: the map keys and values tags must differ")
+)
+
+// Validate checks that d can be used to parse and save files.
+func (d *Dialect) Validate() error {
+	if len(d.ListTags) == 0 || d.ListTags[0] == "" {
+		return ErrNoListTag
+	}
+	if d.MapKeys == "" || d.MapValues == "" {
+		return ErrNoMapTags
+	}
+	if d.MapKeys == d.MapValues {
+		return ErrSameMapTag
+	}
+	return nil
+}
+
+// Load reads a dialect from a JSON file. The fields missing from the file
+// keep the value of Default.
+func Load(fileName string) (*Dialect, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	d := *Default
+	if err = json.Unmarshal(content, &d); err != nil {
+		return nil, fmt.Errorf("dialect.Load: %s: %w", fileName, err)
+	}
+	if err = d.Validate(); err != nil {
+		return nil, fmt.Errorf("dialect.Load: %s: %w", fileName, err)
+	}
+	return &d, nil
+}
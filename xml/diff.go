@@ -0,0 +1,132 @@
+package xml
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Divergence is a place where two trees differ.
+type Divergence struct {
+	// Path is the XML path of the element of the first tree, or of the
+	// second one if the first tree has no element there.
+	Path   string
+	Reason string
+}
+
+func (d Divergence) String() string {
+	return d.Path + ": " + d.Reason
+}
+
+// Diff compares t to o structurally and returns where they diverge: the
+// names, attributes, data and children of their elements. The formatting is
+// ignored and so are the differences in how a value is written when it's the
+// same value, e.g. "1.50" and "1.5" or "true" and "True".
+func (t *Tree) Diff(o *Tree) []Divergence {
+	return Diff(t.Root, o.Root)
+}
+
+// Diff compares the elements a and b, their next siblings and their
+// descendants, see Tree.Diff.
+func Diff(a, b *Element) []Divergence {
+	var d []Divergence
+	for a != nil || b != nil {
+		switch {
+		case b == nil:
+			d = append(d, Divergence{Path: a.XMLPath(), Reason: "missing"})
+			a = a.Next
+			continue
+		case a == nil:
+			d = append(d, Divergence{Path: b.XMLPath(), Reason: "unexpected"})
+			b = b.Next
+			continue
+		case a.GetName() != b.GetName():
+			// A single missing or unexpected sibling doesn't shift the
+			// comparison of the next ones
+			switch {
+			case sameName(a.Next, b):
+				d = append(d, Divergence{Path: a.XMLPath(), Reason: "missing"})
+				a = a.Next
+			case sameName(a, b.Next):
+				d = append(d, Divergence{Path: b.XMLPath(), Reason: "unexpected"})
+				b = b.Next
+			default:
+				d = append(d, Divergence{Path: a.XMLPath(), Reason: "found <" + b.GetName() + "> instead"})
+				a, b = a.Next, b.Next
+			}
+			continue
+		}
+		d = append(d, diffAttributes(a, b)...)
+		if !sameData(a.Data, b.Data) {
+			d = append(d, Divergence{
+				Path:   a.XMLPath(),
+				Reason: "data " + strconv.Quote(dataString(a.Data)) + " became " + strconv.Quote(dataString(b.Data)),
+			})
+		}
+		d = append(d, Diff(a.Child, b.Child)...)
+		a, b = a.Next, b.Next
+	}
+	return d
+}
+
+func sameName(a, b *Element) bool {
+	return a != nil && b != nil && a.GetName() == b.GetName()
+}
+
+func diffAttributes(a, b *Element) []Divergence {
+	names := make([]string, 0, len(a.Attr)+len(b.Attr))
+	for name := range a.Attr {
+		names = append(names, name)
+	}
+	for name := range b.Attr {
+		if _, ok := a.Attr[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var d []Divergence
+	for _, name := range names {
+		av, inA := a.Attr[name]
+		bv, inB := b.Attr[name]
+		switch {
+		case !inB:
+			d = append(d, Divergence{Path: a.XMLPath(), Reason: "attribute " + name + " missing"})
+		case !inA:
+			d = append(d, Divergence{Path: a.XMLPath(), Reason: "unexpected attribute " + name})
+		case !sameData(CreateDataType(av), CreateDataType(bv)):
+			d = append(d, Divergence{Path: a.XMLPath(), Reason: "attribute " + name + " " + strconv.Quote(av) + " became " + strconv.Quote(bv)})
+		}
+	}
+	return d
+}
+
+func dataString(d *Data) string {
+	if d == nil {
+		return ""
+	}
+	return strings.TrimSpace(d.data)
+}
+
+// sameData reports whether a and b hold the same value.
+func sameData(a, b *Data) bool {
+	as, bs := dataString(a), dataString(b)
+	if as == bs {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	isNumber := func(k reflect.Kind) bool {
+		return k == reflect.Int64 || k == reflect.Float64
+	}
+	switch {
+	case isNumber(a.t) && isNumber(b.t):
+		af, errA := strconv.ParseFloat(as, 64)
+		bf, errB := strconv.ParseFloat(bs, 64)
+		return errA == nil && errB == nil && af == bf
+	case a.t == reflect.Bool && b.t == reflect.Bool:
+		return strings.EqualFold(as, bs)
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package xml
+
+import (
+	_xml "encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTree(t *testing.T, document string) *Tree {
+	t.Helper()
+	tree := &Tree{}
+	require.NoError(t, _xml.NewDecoder(strings.NewReader(document)).Decode(tree))
+	return tree
+}
+
+func TestTree_Diff(t *testing.T) {
+	original := readTree(t, `<?xml version="1.0" encoding="utf-8"?>
+<savegame>
+	<pawn>
+		<name>Tynan</name>
+		<weight>1.50</weight>
+	</pawn>
+</savegame>`)
+	assert.Empty(t, original.Diff(readTree(t, `<?xml version="1.0" encoding="utf-8"?>
+<savegame><pawn><name>Tynan</name><weight>1.5</weight></pawn></savegame>`)))
+
+	assert.Equal(t, []Divergence{
+		{Path: "savegame>pawn>name", Reason: `data "Tynan" became "Randy"`},
+		{Path: "savegame>pawn>weight", Reason: "missing"},
+	}, original.Diff(readTree(t, `<?xml version="1.0" encoding="utf-8"?>
+<savegame><pawn><name>Randy</name></pawn></savegame>`)))
+
+	// An unexpected element doesn't shift the comparison of its siblings
+	assert.Equal(t, []Divergence{
+		{Path: "savegame>pawn>extra", Reason: "unexpected"},
+	}, original.Diff(readTree(t, `<?xml version="1.0" encoding="utf-8"?>
+<savegame><pawn><name>Tynan</name><extra/><weight>1.5</weight></pawn></savegame>`)))
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/cruffinoni/xml-generator/xml/attributes"
+	"github.com/cruffinoni/xml-generator/xml/dialect"
 	"github.com/cruffinoni/xml-generator/xml/saver"
 )
 
@@ -16,6 +17,7 @@ type Element struct {
 	Attr         attributes.Attributes
 	Data         *Data
 	index        int
+	dialect      *dialect.Dialect
 
 	Next   *Element
 	Prev   *Element
@@ -85,6 +87,12 @@ func (e *Element) DisplayDebug() string {
 	return sb.String()
 }
 
+// Dialect returns the dialect of the tree the element comes from, or
+// dialect.Default if the element hasn't been parsed.
+func (e *Element) Dialect() *dialect.Dialect {
+	return dialect.Or(e.dialect)
+}
+
 // GetIndex returns the index of the element in the list of elements or 0 if the element is not in a list
 func (e *Element) GetIndex() int {
 	return e.index
@@ -21,3 +21,13 @@ type Assigner interface {
 	// AttributeAssigner is an interface for types that can set and get XML attributes.
 	AttributeAssigner
 }
+
+// Union is implemented by the types holding a value whose shape varies from
+// an element to another, see the unions of the generator.
+type Union interface {
+	// AssignElement assigns e itself, and not its children, to the variant
+	// matching its shape.
+	AssignElement(e *xml.Element) error
+	// Value returns the value of the variant assigned.
+	Value() any
+}
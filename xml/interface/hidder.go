@@ -5,3 +5,27 @@ type FieldValidator interface {
 	IsValidField(field string) bool
 	CountValidatedField() int
 }
+
+// Enum is implemented by the string types generated from the values of a
+// field, see the enumerations of the generator.
+type Enum interface {
+	// Valid reports whether the value is one of the known values.
+	Valid() bool
+}
+
+// UnknownValueField returns the name validated through the FieldValidator
+// when the enumeration field holds an unknown value. The value is still
+// assigned to the field.
+func UnknownValueField(field string) string {
+	return field + ":unknown"
+}
+
+// ClassAttribute is the attribute giving the class of polymorphic elements.
+const ClassAttribute = "Class"
+
+// Classifier is implemented by the structures of polymorphic elements, whose
+// type depends on their Class attribute.
+type Classifier interface {
+	// Class returns the value of the Class attribute of the structure.
+	Class() string
+}
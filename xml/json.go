@@ -0,0 +1,244 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Names of the JSON properties which don't hold a child element.
+const (
+	// JSONAttributePrefix prefixes the name of the attributes.
+	JSONAttributePrefix = "@"
+	// JSONText is the property of the data of the elements which also have
+	// attributes or children.
+	JSONText = "#text"
+	// JSONMapKey and JSONMapValue are the properties of the pairs of the
+	// maps whose keys aren't texts.
+	JSONMapKey   = "key"
+	JSONMapValue = "value"
+)
+
+// JSONValue returns the JSON value of d: a number, a boolean or a string,
+// after its kind. The hexadecimal numbers stay strings.
+func (d *Data) JSONValue() any {
+	switch d.t {
+	case reflect.Int64:
+		if i, err := strconv.ParseInt(d.data, 10, 64); err == nil {
+			return i
+		}
+	case reflect.Float64:
+		if f, err := strconv.ParseFloat(d.data, 64); err == nil {
+			return f
+		}
+	case reflect.Bool:
+		return strings.EqualFold(d.data, "true")
+	}
+	return d.data
+}
+
+// MarshalJSON converts the tree to JSON, an object whose only property is
+// the root element. An element is converted after its shape, like the
+// generated types read it:
+//   - the list items are an array;
+//   - a map is an object if its keys are texts, an array of key and value
+//     pairs otherwise;
+//   - the other elements with children are objects, with one property per
+//     child. The children repeated with the same name are an array;
+//   - the data is a number, a boolean or a string, see Data.JSONValue;
+//   - an empty element is null.
+//
+// The attributes are properties prefixed by JSONAttributePrefix. An element
+// with attributes is an object, its data is the property JSONText. The
+// attributes of the lists and of the maps are left out.
+func (t *Tree) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	if t.Root == nil {
+		return []byte("null"), nil
+	}
+	b.WriteByte('{')
+	writeJSONString(&b, t.Root.GetName())
+	b.WriteByte(':')
+	t.Root.writeJSON(&b)
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func writeJSONString(b *bytes.Buffer, s string) {
+	// Marshalling a string can't fail
+	v, _ := json.Marshal(s)
+	b.Write(v)
+}
+
+func writeJSONData(b *bytes.Buffer, d *Data) {
+	v, _ := json.Marshal(d.JSONValue())
+	b.Write(v)
+}
+
+// writeJSON writes the JSON value of e, without its siblings.
+func (e *Element) writeJSON(b *bytes.Buffer) {
+	d := e.Dialect()
+	switch {
+	case e.Child == nil && e.Attr.Empty():
+		if e.Data == nil {
+			b.WriteString("null")
+			return
+		}
+		writeJSONData(b, e.Data)
+	case e.Child == nil:
+		b.WriteByte('{')
+		e.writeJSONAttributes(b)
+		if e.Data != nil {
+			b.WriteByte(',')
+			writeJSONString(b, JSONText)
+			b.WriteByte(':')
+			writeJSONData(b, e.Data)
+		}
+		b.WriteByte('}')
+	case d.IsListTag(e.Child.GetName()):
+		b.WriteByte('[')
+		for n := e.Child; n != nil; n = n.Next {
+			if n != e.Child {
+				b.WriteByte(',')
+			}
+			n.writeJSON(b)
+		}
+		b.WriteByte(']')
+	case d.IsMapKeysTag(e.Child.GetName()):
+		e.writeJSONMap(b)
+	default:
+		b.WriteByte('{')
+		comma := e.writeJSONAttributes(b)
+		if e.Data != nil {
+			if comma {
+				b.WriteByte(',')
+			}
+			writeJSONString(b, JSONText)
+			b.WriteByte(':')
+			writeJSONData(b, e.Data)
+			comma = true
+		}
+		e.writeJSONChildren(b, comma)
+		b.WriteByte('}')
+	}
+}
+
+// writeJSONAttributes writes the attributes of e as properties, sorted by
+// name. It reports whether it wrote any.
+func (e *Element) writeJSONAttributes(b *bytes.Buffer) bool {
+	names := make([]string, 0, len(e.Attr))
+	for name := range e.Attr {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writeJSONString(b, JSONAttributePrefix+name)
+		b.WriteByte(':')
+		writeJSONData(b, CreateDataType(e.Attr[name]))
+	}
+	return len(names) > 0
+}
+
+// writeJSONChildren writes the children of e as properties, in the order of
+// their first occurrence. comma tells if a property is already written.
+func (e *Element) writeJSONChildren(b *bytes.Buffer, comma bool) {
+	var (
+		names    []string
+		children = make(map[string][]*Element)
+	)
+	for n := e.Child; n != nil; n = n.Next {
+		name := n.GetName()
+		if _, ok := children[name]; !ok {
+			names = append(names, name)
+		}
+		children[name] = append(children[name], n)
+	}
+	for _, name := range names {
+		if comma {
+			b.WriteByte(',')
+		}
+		comma = true
+		writeJSONString(b, name)
+		b.WriteByte(':')
+		same := children[name]
+		if len(same) == 1 {
+			same[0].writeJSON(b)
+			continue
+		}
+		b.WriteByte('[')
+		for i, n := range same {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			n.writeJSON(b)
+		}
+		b.WriteByte(']')
+	}
+}
+
+// writeJSONMap writes the map e. The keys and the values are paired by
+// position, a value missing is null.
+func (e *Element) writeJSONMap(b *bytes.Buffer) {
+	var keys, values []*Element
+	for n := e.Child.Child; n != nil; n = n.Next {
+		keys = append(keys, n)
+	}
+	if v := e.Child.Next; v != nil && e.Dialect().IsMapValuesTag(v.GetName()) {
+		for n := v.Child; n != nil; n = n.Next {
+			values = append(values, n)
+		}
+	}
+	texts := true
+	for _, k := range keys {
+		if k.Child != nil || !k.Attr.Empty() {
+			texts = false
+			break
+		}
+	}
+	writeValue := func(i int) {
+		if i < len(values) {
+			values[i].writeJSON(b)
+		} else {
+			b.WriteString("null")
+		}
+	}
+	if texts {
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key := ""
+			if k.Data != nil {
+				key = k.Data.data
+			}
+			writeJSONString(b, key)
+			b.WriteByte(':')
+			writeValue(i)
+		}
+		b.WriteByte('}')
+		return
+	}
+	b.WriteByte('[')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('{')
+		writeJSONString(b, JSONMapKey)
+		b.WriteByte(':')
+		k.writeJSON(b)
+		b.WriteByte(',')
+		writeJSONString(b, JSONMapValue)
+		b.WriteByte(':')
+		writeValue(i)
+		b.WriteByte('}')
+	}
+	b.WriteByte(']')
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/cruffinoni/xml-generator/xml/attributes"
+	"github.com/cruffinoni/xml-generator/xml/dialect"
 )
 
 type Flag uint
@@ -18,6 +19,8 @@ type Buffer struct {
 
 	lastPoint int
 	lastDepth int
+
+	dialect *dialect.Dialect
 }
 
 func NewBuffer() *Buffer {
@@ -31,6 +34,17 @@ func NewBuffer() *Buffer {
 	return b
 }
 
+// SetDialect sets the dialect used to write the collections.
+func (b *Buffer) SetDialect(d *dialect.Dialect) {
+	b.dialect = d
+}
+
+// Dialect returns the dialect used to write the collections, dialect.Default
+// if none has been set.
+func (b *Buffer) Dialect() *dialect.Dialect {
+	return dialect.Or(b.dialect)
+}
+
 func (b *Buffer) Write(p []byte) (int, error) {
 	if len(p) > 1 {
 		b.lastPoint = b.bufferLen
@@ -10,6 +10,7 @@ import (
 
 	"github.com/cruffinoni/xml-generator/xml"
 	"github.com/cruffinoni/xml-generator/xml/attributes"
+	"github.com/cruffinoni/xml-generator/xml/dialect"
 	"github.com/cruffinoni/xml-generator/xml/interface"
 	"github.com/cruffinoni/xml-generator/xml/saver"
 	"github.com/cruffinoni/xml-generator/xml/types/primary"
@@ -17,7 +18,14 @@ import (
 
 // SaveWithBuffer takes in a value of multiple type, and returns a saver.Buffer and multiple error that occurs during the saving process.
 func SaveWithBuffer(val any) (*saver.Buffer, error) {
+	return SaveWithDialect(val, nil)
+}
+
+// SaveWithDialect works like SaveWithBuffer but writes the collections with
+// the given dialect. A nil dialect means dialect.Default.
+func SaveWithDialect(val any, d *dialect.Dialect) (*saver.Buffer, error) {
 	b := saver.NewBuffer()
+	b.SetDialect(d)
 	valType := reflect.TypeOf(val)
 	if valType.Kind() == reflect.Ptr {
 		valType = valType.Elem()
@@ -29,6 +37,76 @@ func SaveWithBuffer(val any) (*saver.Buffer, error) {
 	return b, err
 }
 
+// WithClass returns attr with the Class attribute of val when val is the
+// structure of a polymorphic element. attr is updated in place if it's not
+// nil.
+func WithClass(val any, attr attributes.Attributes) attributes.Attributes {
+	c, ok := castTo[_interface.Classifier](val)
+	if !ok || c.Class() == "" {
+		return attr
+	}
+	if attr == nil {
+		attr = make(attributes.Attributes)
+	}
+	attr[_interface.ClassAttribute] = c.Class()
+	return attr
+}
+
+// WithAttributes returns attr with the attributes held by the fields of val
+// tagged `xml:"name,attr"`. The nil pointers are left out, and so are the
+// attributes already in attr: they couldn't be assigned to their field when
+// unmarshalled. attr isn't changed, a copy is returned if there is any such
+// field.
+func WithAttributes(val any, attr attributes.Attributes) attributes.Attributes {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return attr
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return attr
+	}
+	t := v.Type()
+	copied := false
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := attributes.TagName(t.Field(i).Tag)
+		if !ok {
+			continue
+		}
+		if _, ok = attr[name]; ok {
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Pointer {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		if !copied {
+			copied = true
+			c := make(attributes.Attributes, len(attr)+1)
+			for k, value := range attr {
+				c[k] = value
+			}
+			attr = c
+		}
+		switch f.Kind() {
+		case reflect.Bool:
+			attr[name] = capitalize(strconv.FormatBool(f.Bool()))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			attr[name] = strconv.FormatInt(f.Int(), 10)
+		case reflect.Float32, reflect.Float64:
+			attr[name] = strconv.FormatFloat(f.Float(), 'f', -1, 64)
+		default:
+			attr[name] = f.String()
+		}
+	}
+	return attr
+}
+
 // castTo attempts to cast the given value to the specified type and returns the result and a boolean indicating whether the cast was successful.
 func castTo[T any](val any) (T, bool) {
 	if v, ok := val.(T); ok {
@@ -56,12 +134,18 @@ func Save(val any, b *saver.Buffer, tag string) error {
 	if v.IsZero() && v.Kind() == reflect.Ptr {
 		return nil
 	}
+	// A union is saved as the value of its variant
+	if union, ok := castTo[_interface.Union](val); ok {
+		return Save(union.Value(), b, tag)
+	}
 	validator, implValidator := castTo[_interface.FieldValidator](v.Interface())
 	transformer, implTransformer := castTo[saver.Transformer](v.Interface())
 	var attr attributes.Attributes
 	if attributeAssigner, ok := castTo[_interface.AttributeAssigner](v.Interface()); ok {
 		attr = attributeAssigner.GetAttributes()
 	}
+	attr = WithClass(v.Interface(), attr)
+	attr = WithAttributes(v.Interface(), attr)
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
@@ -120,13 +204,13 @@ func Save(val any, b *saver.Buffer, tag string) error {
 			idxInterface := v.Index(i).Interface()
 			if fieldValidator, ok := idxInterface.(_interface.FieldValidator); ok && fieldValidator != nil && fieldValidator.CountValidatedField() == 0 {
 				if attributeAssigner, ok := castTo[_interface.AttributeAssigner](idxInterface); ok {
-					b.WriteEmptyTag("li", attributeAssigner.GetAttributes())
+					b.WriteEmptyTag(b.Dialect().ListTag(), WithAttributes(idxInterface, attributeAssigner.GetAttributes()))
 					continue
 				} else {
 					log.Fatal("can't cast child to attribute assigner")
 				}
 			}
-			if err := Save(idxInterface, b, "li"); err != nil {
+			if err := Save(idxInterface, b, b.Dialect().ListTag()); err != nil {
 				return err
 			}
 		}
@@ -187,6 +271,10 @@ func Save(val any, b *saver.Buffer, tag string) error {
 			if !ok {
 				continue
 			}
+			// The attributes are written in the tag of the structure
+			if _, isAttr := attributes.TagName(f.Tag); isAttr {
+				continue
+			}
 			//if !implValidator {
 			//	log.Printf("Validator implemented for %T (field %v): invalid field", v.Interface(), f.Name)
 			//} else {
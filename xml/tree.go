@@ -4,11 +4,16 @@ import (
 	"bytes"
 	_xml "encoding/xml"
 	"log"
+
+	"github.com/cruffinoni/xml-generator/xml/dialect"
 )
 
 type Tree struct {
 	_xml.Unmarshaler
 	Root *Element
+	// Dialect tells how the collections are encoded in the file. It must be
+	// set before decoding, dialect.Default is used when it's nil.
+	Dialect *dialect.Dialect
 }
 
 func (t *Tree) Debug() string {
@@ -44,12 +49,13 @@ func (t *Tree) FindElementFromData(data string) []*Element {
 
 func (t *Tree) UnmarshalXML(decoder *_xml.Decoder, s _xml.StartElement) error {
 	var (
-		lastNode = &Element{StartElement: s}
+		d        = dialect.Or(t.Dialect)
+		lastNode = &Element{StartElement: s, dialect: d}
 		depth    = 0
 	)
 	t.Root = lastNode
 
-	return unmarshalEmbed(decoder,
+	return unmarshalEmbed(decoder, d,
 		func(e *_xml.StartElement, ctx *Context) {
 			if ctx.depth > depth {
 				if lastNode.Child != nil {
@@ -70,6 +76,7 @@ func (t *Tree) UnmarshalXML(decoder *_xml.Decoder, s _xml.StartElement) error {
 						Parent:       lastNode,
 						index:        idx,
 						Attr:         ctx.attr,
+						dialect:      d,
 					}
 					lastNode.Child = n
 					lastNode = n
@@ -101,6 +108,7 @@ func (t *Tree) UnmarshalXML(decoder *_xml.Decoder, s _xml.StartElement) error {
 						StartElement: *e,
 						EndElement:   _xml.EndElement{Name: e.Name},
 						Attr:         ctx.attr,
+						dialect:      d,
 					}
 					lastNode.Next = n
 					lastNode = n
@@ -122,6 +130,7 @@ func (t *Tree) UnmarshalXML(decoder *_xml.Decoder, s _xml.StartElement) error {
 					index:        idx,
 					EndElement:   _xml.EndElement{Name: e.Name},
 					Attr:         ctx.attr,
+					dialect:      d,
 				}
 				// All children must have the same parent because
 				// they are siblings
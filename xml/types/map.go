@@ -64,42 +64,44 @@ func (m *Map[K, V]) TransformToXML(b *saver.Buffer) error {
 		b.CloseTagWithIndent(m.tag)
 		b.WriteString("\n")
 	}()
+	d := b.Dialect()
 	if m.m == nil || m.Capacity() == 0 {
-		b.WriteEmptyTag("keys", nil)
-		b.WriteEmptyTag("values", nil)
+		b.WriteEmptyTag(d.MapKeys, nil)
+		b.WriteEmptyTag(d.MapValues, nil)
 		return nil
 	}
+	li := d.ListTag()
 	b.IncreaseDepth()
-	b.WriteStringWithIndent("<keys>\n")
+	b.WriteStringWithIndent("<" + d.MapKeys + ">\n")
 	b.IncreaseDepth()
 	for k := range m.m {
-		b.WriteStringWithIndent("<li>")
+		b.WriteStringWithIndent("<" + li + ">")
 		if err := xmlFile.Save(k, b, ""); err != nil {
 			return err
 		}
 		if unicode.IsSpace(rune(b.Bytes()[b.Len()-1])) {
-			b.WriteStringWithIndent("</li>\n")
+			b.WriteStringWithIndent("</" + li + ">\n")
 		} else {
-			b.WriteString("</li>\n")
+			b.WriteString("</" + li + ">\n")
 		}
 	}
 	b.DecreaseDepth()
-	b.WriteStringWithIndent("</keys>\n")
-	b.WriteStringWithIndent("<values>\n")
+	b.WriteStringWithIndent("</" + d.MapKeys + ">\n")
+	b.WriteStringWithIndent("<" + d.MapValues + ">\n")
 	b.IncreaseDepth()
 	for _, v := range m.m {
-		b.WriteStringWithIndent("<li>")
+		b.WriteStringWithIndent("<" + li + ">")
 		if err := xmlFile.Save(v, b, ""); err != nil {
 			return err
 		}
 		if unicode.IsSpace(rune(b.Bytes()[b.Len()-1])) {
-			b.WriteStringWithIndent("</li>\n")
+			b.WriteStringWithIndent("</" + li + ">\n")
 		} else {
-			b.WriteString("</li>\n")
+			b.WriteString("</" + li + ">\n")
 		}
 	}
 	b.DecreaseDepth()
-	b.WriteStringWithIndent("</values>")
+	b.WriteStringWithIndent("</" + d.MapValues + ">")
 	b.DecreaseDepth()
 	return nil
 }
@@ -151,12 +153,13 @@ func (m *Map[K, V]) Assign(e *xml.Element) error {
 		return nil
 	}
 	//log.Printf("Tag: %v", m.tag)
-	keys := path.FindWithPath("keys>[...]", e)
+	d := e.Dialect()
+	keys := path.FindWithPath(d.MapKeys+">[...]", e)
 	if len(keys) == 0 {
 		return errors.New("Map/Assign: no key")
 	}
 	//log.Printf("e=%v", e.GetName())
-	values := path.FindWithPath("values>[...]", e)
+	values := path.FindWithPath(d.MapValues+">[...]", e)
 	if len(values) == 0 {
 		return errors.New("Map/Assign: no value")
 	}
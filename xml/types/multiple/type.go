@@ -53,7 +53,7 @@ func (t *Type) TransformToXML(buffer *saver.Buffer) error {
 		return nil
 	}
 	// We are in a list, so don't write twice the same tag
-	if t.first.Element.GetName() == "li" {
+	if t.first.Element.Dialect().IsListTag(t.first.Element.GetName()) {
 		buffer.WriteString(t.first.Element.Data.GetString())
 		t.first = t.first.Next
 		return nil
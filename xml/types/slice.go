@@ -92,6 +92,25 @@ func (s *sliceData[T]) String() string {
 	return s.str
 }
 
+// transformUnionToXML writes the item tag holding v, the value of a union.
+func transformUnionToXML(b *saver.Buffer, v any, tag string, attr attributes.Attributes) error {
+	switch va := v.(type) {
+	case nil:
+		b.WriteEmptyTag(tag, attr)
+		return nil
+	case *xml.Element:
+		// The element kept as is has its own tag
+		return va.TransformToXML(b)
+	}
+	item := &sliceData[any]{
+		data: v,
+		tag:  tag,
+		attr: attr,
+		kind: reflect.TypeOf(v).Kind(),
+	}
+	return item.TransformToXML(b)
+}
+
 func (s *sliceData[T]) GetXMLTag() []byte {
 	return nil
 }
@@ -100,12 +119,17 @@ func (s *sliceData[T]) TransformToXML(b *saver.Buffer) error {
 	//log.Printf("sliceData.TransformToXML => %v (? %T) [Hidden: %v | %v]", s.tag, s.data, s.hidden, s.attr)
 	//log.Printf("Is hidden: %v (%T)", s.hidden, s.data)
 
+	if union, ok := any(s.data).(_interface.Union); ok {
+		return transformUnionToXML(b, union.Value(), s.tag, s.attr)
+	}
 	_, okEmpty := any(s.data).(*primary.Empty)
 	elem, okElem := any(s.data).(*xml.Element)
+	s.attr = xmlFile.WithAttributes(s.data, s.attr)
 	if implFieldValidating, ok := castToInterface[_interface.FieldValidator](s.data); ok && implFieldValidating.CountValidatedField() == 0 || okEmpty || okElem && elem.IsEmpty() {
 		b.WriteEmptyTag(s.tag, s.attr)
 		return nil
 	}
+	s.attr = xmlFile.WithClass(s.data, s.attr)
 	b.OpenTag(s.tag, s.attr)
 	if err := xmlFile.Save(s.data, b, ""); err != nil {
 		return err
@@ -249,15 +273,29 @@ func (s *Slice[T]) Assign(e *xml.Element) error {
 		// Set sd.data to zero depending on the type of T. Either a pointer or a
 		// value.
 		//log.Printf("Type: %v / %v", reflect.TypeOf(sd.data).Kind(), n.Child)
-		switch tType := reflect.TypeOf(sd.data).Kind(); tType {
-		case reflect.Ptr, reflect.Interface, reflect.Struct, reflect.Map, reflect.Slice:
-			sd.data = reflect.New(reflect.TypeOf(*new(T)).Elem()).Interface().(T)
-		case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
-			sd.data = zero[T]()
+		// The concrete type of the interfaces is given by the Class attribute
+		if it := reflect.TypeOf((*T)(nil)).Elem(); it.Kind() == reflect.Interface {
+			v, ok := unmarshal.NewFromClass(it, n)
+			if !ok {
+				return fmt.Errorf("Slice.Assign: no class registered for %v", it)
+			}
+			sd.data = v.(T)
+		} else {
+			switch tType := reflect.TypeOf(sd.data).Kind(); tType {
+			case reflect.Ptr, reflect.Interface, reflect.Struct, reflect.Map, reflect.Slice:
+				sd.data = reflect.New(reflect.TypeOf(*new(T)).Elem()).Interface().(T)
+			case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+				sd.data = zero[T]()
+			}
 		}
 
 		//log.Printf("Child ? %v", n.Child != nil)
-		if n.Child != nil {
+		if union, ok := any(sd.data).(_interface.Union); ok {
+			// The variant is chosen from the shape of the item
+			if err := union.AssignElement(n); err != nil {
+				return err
+			}
+		} else if n.Child != nil {
 			if err := unmarshal.Element(n.Child, &sd); err != nil {
 				return err
 			}
@@ -267,7 +305,8 @@ func (s *Slice[T]) Assign(e *xml.Element) error {
 			}
 		}
 		//log.Printf("Slice.Assign: %+v", sd.data)
-		sd.SetAttributes(n.Attr)
+		// The attributes held by the fields of the item are left to them
+		sd.SetAttributes(unmarshal.AssignAttributes(sd.data, n.Attr))
 		s.data = append(s.data, sd)
 		n = n.Next
 	}
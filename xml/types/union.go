@@ -0,0 +1,30 @@
+package types
+
+import (
+	"github.com/cruffinoni/xml-generator/xml"
+)
+
+// Union holds the value of an element whose shape varies. The generated
+// unions embed it and choose the type of the value from the shape of the
+// element, see unmarshal.SelectShape.
+type Union struct {
+	value any
+}
+
+// Value returns the value held. It's the element itself when its shape
+// matches none of the variants.
+func (u *Union) Value() any {
+	return u.value
+}
+
+// Set sets the value held.
+func (u *Union) Set(v any) {
+	u.value = v
+}
+
+// Keep holds e as is, without its siblings.
+func (u *Union) Keep(e *xml.Element) {
+	single := *e
+	single.Next = nil
+	u.value = &single
+}
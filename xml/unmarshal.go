@@ -2,10 +2,10 @@ package xml
 
 import (
 	_xml "encoding/xml"
-	"github.com/cruffinoni/xml-generator/xml/utils"
 	"io"
 
 	"github.com/cruffinoni/xml-generator/xml/attributes"
+	"github.com/cruffinoni/xml-generator/xml/dialect"
 )
 
 // event is a type that represents a function to
@@ -32,7 +32,10 @@ func transformAttrToMap(attr *[]_xml.Attr) attributes.Attributes {
 
 const InvalidIdx = -1
 
+// unmarshalEmbed decodes the tokens of decoder. d tells which tags are list
+// items to maintain their indices.
 func unmarshalEmbed(decoder *_xml.Decoder,
+	d *dialect.Dialect,
 	onStartElement event[*_xml.StartElement],
 	onCharByte event[[]byte]) error {
 	ctx := &Context{
@@ -50,7 +53,7 @@ func unmarshalEmbed(decoder *_xml.Decoder,
 		case _xml.StartElement:
 			ctx.depth++
 			ctx.attr = transformAttrToMap(&t.Attr)
-			if utils.IsListTag(t.Name.Local) {
+			if d.IsListTag(t.Name.Local) {
 				ctx.index[ctx.depth]++
 			}
 			if onStartElement != nil {
@@ -63,7 +66,7 @@ func unmarshalEmbed(decoder *_xml.Decoder,
 			ctx.attr = nil
 
 			previousIdx := ctx.depth + 1
-			if !utils.IsListTag(t.Name.Local) && ctx.index[previousIdx] > 0 {
+			if !d.IsListTag(t.Name.Local) && ctx.index[previousIdx] > 0 {
 				delete(ctx.index, previousIdx)
 			}
 			ctx.depth--
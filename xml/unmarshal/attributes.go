@@ -0,0 +1,92 @@
+package unmarshal
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cruffinoni/xml-generator/xml/attributes"
+	"github.com/cruffinoni/xml-generator/xml/interface"
+)
+
+// SetAttributes assigns the attributes attr to dest. Those held by a field
+// of dest tagged `xml:"name,attr"` are set to the field, the others are given
+// to dest.SetAttributes.
+func SetAttributes(dest _interface.AttributeAssigner, attr attributes.Attributes) {
+	dest.SetAttributes(AssignAttributes(dest, attr))
+}
+
+// AssignAttributes sets the attributes of attr held by a field of dest tagged
+// `xml:"name,attr"` to their field and returns the other ones. An attribute
+// whose value doesn't fit its field is returned too, so it's saved back as
+// is. attr isn't changed.
+func AssignAttributes(dest any, attr attributes.Attributes) attributes.Attributes {
+	v := reflect.ValueOf(dest)
+	if len(attr) == 0 || v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return attr
+	}
+	v = v.Elem()
+	t := v.Type()
+	var unknown attributes.Attributes
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := attributes.TagName(t.Field(i).Tag)
+		if !ok {
+			continue
+		}
+		value, ok := attr[name]
+		if !ok || !setAttributeField(v.Field(i), value) {
+			continue
+		}
+		if unknown == nil {
+			unknown = make(attributes.Attributes, len(attr))
+			for k, value := range attr {
+				unknown[k] = value
+			}
+		}
+		delete(unknown, name)
+	}
+	if unknown == nil {
+		return attr
+	}
+	return unknown
+}
+
+// setAttributeField sets the field f to value, allocating it if it's a
+// pointer. It returns false if value can't be parsed as the type of f.
+func setAttributeField(f reflect.Value, value string) bool {
+	t := f.Type()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		v.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		fl, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		v.SetFloat(fl)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.ToLower(value))
+		if err != nil {
+			return false
+		}
+		v.SetBool(b)
+	default:
+		return false
+	}
+	if f.Kind() == reflect.Pointer {
+		f.Set(v.Addr())
+	} else {
+		f.Set(v)
+	}
+	return true
+}
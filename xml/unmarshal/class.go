@@ -0,0 +1,84 @@
+package unmarshal
+
+import (
+	"reflect"
+
+	"github.com/cruffinoni/xml-generator/xml"
+	"github.com/cruffinoni/xml-generator/xml/attributes"
+	"github.com/cruffinoni/xml-generator/xml/interface"
+	"github.com/cruffinoni/xml-generator/xml/saver"
+)
+
+// ClassFactory creates the structure of the given class. It returns nil for
+// the unknown classes.
+type ClassFactory func(class string) _interface.Assigner
+
+var classFactories = make(map[reflect.Type]ClassFactory)
+
+// RegisterClasses registers the factory creating the structures implementing
+// the interface I. The generated code registers its polymorphic types when
+// its package is initialized.
+func RegisterClasses[I any](f ClassFactory) {
+	classFactories[reflect.TypeOf((*I)(nil)).Elem()] = f
+}
+
+// NewFromClass creates the value of the interface type t matching the Class
+// attribute of e. The elements of an unknown class are kept as an
+// *UnknownClass. ok is false when t has no registered factory.
+func NewFromClass(t reflect.Type, e *xml.Element) (v _interface.Assigner, ok bool) {
+	f, ok := classFactories[t]
+	if !ok {
+		return nil, false
+	}
+	class := e.Attr.Get(_interface.ClassAttribute)
+	if v = f(class); v == nil || !reflect.TypeOf(v).Implements(t) {
+		v = &UnknownClass{class: class}
+	}
+	return v, true
+}
+
+// UnknownClass holds a polymorphic element whose class has no generated
+// structure. Its children are kept as is, so they are saved unchanged.
+type UnknownClass struct {
+	class string
+	attr  attributes.Attributes
+	// Element is the first child of the element, nil if it has none.
+	Element *xml.Element
+}
+
+func (u *UnknownClass) Assign(e *xml.Element) error {
+	// An element without children is assigned itself
+	if e.Child == nil && e.Data == nil && e.Attr.Get(_interface.ClassAttribute) != "" {
+		return nil
+	}
+	u.Element = e
+	return nil
+}
+
+func (u *UnknownClass) GetPath() string {
+	return ""
+}
+
+func (u *UnknownClass) SetAttributes(attr attributes.Attributes) {
+	u.attr = attr
+}
+
+func (u *UnknownClass) GetAttributes() attributes.Attributes {
+	return u.attr
+}
+
+func (u *UnknownClass) Class() string {
+	return u.class
+}
+
+func (u *UnknownClass) GetXMLTag() []byte {
+	return nil
+}
+
+func (u *UnknownClass) TransformToXML(b *saver.Buffer) error {
+	if u.Element == nil {
+		return nil
+	}
+	// The siblings of the element are written with it
+	return u.Element.TransformToXML(b)
+}
@@ -0,0 +1,100 @@
+package unmarshal
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/cruffinoni/xml-generator/xml"
+)
+
+// Shape is the layout of an element. The variants of a union are told apart
+// by their shape.
+type Shape int
+
+// The shapes are sorted from the most specific to the least specific one.
+const (
+	// ShapeEmpty is an element without data nor children.
+	ShapeEmpty Shape = iota
+	ShapeBool
+	ShapeInt
+	// ShapeFloat is an element holding a number, integers included.
+	ShapeFloat
+	// ShapeText is an element holding any data.
+	ShapeText
+	// ShapeList is an element whose children are list items.
+	ShapeList
+	// ShapeMap is an element holding the keys and the values of a map.
+	ShapeMap
+	// ShapeStructure is an element with children.
+	ShapeStructure
+)
+
+// Matches reports whether e has the shape s.
+func (s Shape) Matches(e *xml.Element) bool {
+	switch s {
+	case ShapeEmpty:
+		return e.Data == nil && e.Child == nil
+	case ShapeBool, ShapeInt, ShapeFloat, ShapeText:
+		if e.Data == nil || e.Child != nil {
+			return false
+		}
+		k := e.Data.Kind()
+		switch s {
+		case ShapeBool:
+			return k == reflect.Bool
+		case ShapeInt:
+			return k == reflect.Int64
+		case ShapeFloat:
+			return k == reflect.Int64 || k == reflect.Float64
+		}
+		return true
+	case ShapeList:
+		if e.Child == nil {
+			return false
+		}
+		for n := e.Child; n != nil; n = n.Next {
+			if !e.Dialect().IsListTag(n.GetName()) {
+				return false
+			}
+		}
+		return true
+	case ShapeMap:
+		return e.Child != nil && e.Dialect().IsMapKeysTag(e.Child.GetName())
+	case ShapeStructure:
+		return e.Child != nil
+	}
+	return false
+}
+
+// SelectShape returns the index of the first of shapes matching e, or -1 if
+// none does.
+func SelectShape(e *xml.Element, shapes ...Shape) int {
+	for i, s := range shapes {
+		if s.Matches(e) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Value assigns e itself, and not its children, to dest. dest is a pointer
+// to any type a field of a structure can have.
+func Value(e *xml.Element, dest any) error {
+	t := reflect.TypeOf(dest)
+	if t.Kind() != reflect.Pointer {
+		return errors.New("unmarshal.Value: dest must be a pointer")
+	}
+	// The value is unmarshalled as the only field of a structure
+	holder := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: t.Elem(),
+		Tag:  reflect.StructTag(`xml:"` + e.GetName() + `"`),
+	}}))
+	single := *e
+	single.Next = nil
+	if err := Element(&single, holder.Interface()); err != nil {
+		return err
+	}
+	reflect.ValueOf(dest).Elem().Set(holder.Elem().Field(0))
+	return nil
+}
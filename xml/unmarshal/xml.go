@@ -105,6 +105,10 @@ func Element(element *xml.Element, dest any) error {
 	if n == nil || n.GetName() == "history" {
 		return nil
 	}
+	// The unknown classes have no field to fill, they keep the elements as is
+	if u, ok := dest.(*UnknownClass); ok {
+		return u.Assign(element)
+	}
 
 	destAssigner, destIsAssigner := dest.(_interface.Assigner)
 	if destIsAssigner {
@@ -162,6 +166,9 @@ func Element(element *xml.Element, dest any) error {
 			case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Bool, reflect.Float32, reflect.Float64:
 				//log.Printf("Attributing data: %v / %s > '%v'", n.XMLPath(), fieldKind.String(), n.Data)
 				attributeDataToField(fieldValue, n)
+				if enum, ok := fieldValue.Interface().(_interface.Enum); ok && !enum.Valid() && canValidate {
+					validator.ValidateField(_interface.UnknownValueField(t.Field(f).Name))
+				}
 			case reflect.Array:
 				l := fieldValue.Len()
 				// Create a slice
@@ -191,7 +198,7 @@ func Element(element *xml.Element, dest any) error {
 						}
 						newEntry := reflect.New(ft.Elem())
 						if nChild.Child == nil {
-							newEntry.Interface().(_interface.Assigner).SetAttributes(nChild.Attr)
+							SetAttributes(newEntry.Interface().(_interface.Assigner), nChild.Attr)
 						} else {
 							if err := Element(nChild.Child, newEntry.Interface().(_interface.Assigner)); err != nil {
 								panic(err)
@@ -215,8 +222,13 @@ func Element(element *xml.Element, dest any) error {
 					cast := fieldValue.Addr().Interface().(_interface.Assigner)
 					_ = cast.Assign(n)
 					cast.SetAttributes(n.Attr)
+				} else if union, ok := fieldValue.Addr().Interface().(_interface.Union); ok {
+					// The variant is chosen from the shape of the element
+					if err := union.AssignElement(n); err != nil {
+						return err
+					}
 				} else if assigner, ok := fieldValue.Addr().Interface().(_interface.Assigner); ok {
-					assigner.SetAttributes(n.Attr)
+					SetAttributes(assigner, n.Attr)
 					// Otherwise, we need to call the unmarshal function recursively
 					if err := Element(n.Child, fieldValue.Addr().Interface().(_interface.Assigner)); err != nil {
 						panic(err)
@@ -231,9 +243,9 @@ func Element(element *xml.Element, dest any) error {
 		// but dest might be the parent because we go through all the fields
 		// of the struct.
 		if t.Kind() == reflect.Struct && element.Parent != nil {
-			destAssigner.SetAttributes(element.Parent.Attr)
+			SetAttributes(destAssigner, element.Parent.Attr)
 		} else {
-			destAssigner.SetAttributes(element.Attr)
+			SetAttributes(destAssigner, element.Attr)
 		}
 		return destAssigner.Assign(element)
 	}
@@ -1,5 +1,9 @@
 package utils
 
+import "github.com/cruffinoni/xml-generator/xml/dialect"
+
+// IsListTag reports whether tag is a list item in the default dialect.
+// Use dialect.Dialect.IsListTag for the other sources.
 func IsListTag(tag string) bool {
-	return tag == "li" || tag == "list"
+	return dialect.Default.IsListTag(tag)
 }